@@ -6,32 +6,74 @@ set -e
 # Designed to be run non-interactively via environment config.
 
 # Default Config / Environment Variables
+BOOT_MODE="${BOOT_MODE:-}"           # uefi, bios (empty = detect)
+UEFI_BITS="${UEFI_BITS:-}"           # 64, 32 (empty = detect)
 DISK="${DISK:-}"
 MANUAL_PARTITIONING="${MANUAL_PARTITIONING:-no}" # yes, no
 TARGET_ROOT="${TARGET_ROOT:-}"
 TARGET_EFI="${TARGET_EFI:-}"
 FORMAT_ROOT="${FORMAT_ROOT:-yes}"
 FORMAT_EFI="${FORMAT_EFI:-no}"
+SWAP_SIZE="${SWAP_SIZE:-0}"          # swapfile in GiB, 0 for none
 
+MIRRORLIST="${MIRRORLIST:-}"         # contents of /etc/pacman.d/mirrorlist (empty = keep the live one)
+CACHE_SERVER="${CACHE_SERVER:-}"     # caching proxy Server value, tried before the mirrors
+CACHE_DIR="${CACHE_DIR:-}"           # shared package cache directory (e.g. NFS), used by pacstrap
+OFFLINE_REPO="${OFFLINE_REPO:-}"     # local repository or package cache directory (empty = mirrors)
 HOSTNAME="${HOSTNAME:-archlinux}"
+WIFI_PROFILE_NAME="${WIFI_PROFILE_NAME:-}" # file name below /etc/NetworkManager/system-connections
+WIFI_PROFILE="${WIFI_PROFILE:-}"     # NetworkManager keyfile of the Wi-Fi joined in the GUI
+# Accounts: USER_COUNT plus USER_<i>_NAME, _FULL_NAME, _PASSWORD, _ADMIN (yes/no),
+# _GROUPS (comma separated), _SHELL (bash, zsh, zsh-ohmyzsh), _HOME (empty = /home/NAME)
+# and _SSH_KEYS (authorized_keys contents).
+# Without USER_COUNT, USERNAME/FULL_NAME/USER_PASSWORD/SHELL_CHOICE describe one admin.
+USER_COUNT="${USER_COUNT:-}"
 USERNAME="${USERNAME:-user}"
 FULL_NAME="${FULL_NAME:-Arch User}"
 ROOT_PASSWORD="${ROOT_PASSWORD:-}"
+ROOT_LOCKED="${ROOT_LOCKED:-no}"     # yes: no root password, account locked
+PRIVILEGE_TOOL="${PRIVILEGE_TOOL:-sudo}" # sudo, doas, both
+SUDOERS_DROPIN="${SUDOERS_DROPIN:-}" # contents of /etc/sudoers.d/10-wheel (empty: no sudo for wheel)
+DOAS_CONF="${DOAS_CONF:-}"           # contents of /etc/doas.conf (empty: no doas)
+ENABLE_SSHD="${ENABLE_SSHD:-no}"     # yes: install openssh and enable sshd
+SSH_DISABLE_PASSWORDS="${SSH_DISABLE_PASSWORDS:-no}" # yes: key-only SSH login
 USER_PASSWORD="${USER_PASSWORD:-}"
 FS_TYPE="${FS_TYPE:-ext4}"           # ext4, btrfs
 USE_LUKS="${USE_LUKS:-no}"           # yes, no
 LUKS_PASSWORD="${LUKS_PASSWORD:-}"
-DESKTOP_ENV="${DESKTOP_ENV:-none}"   # xfce, gnome, kde, i3, sway, hyprland, etc.
-SHELL_CHOICE="${SHELL_CHOICE:-bash}" # bash, zsh, zsh-ohmyzsh
-HAS_NVIDIA="${HAS_NVIDIA:-no}"       # yes, no
-LOCALE="${LOCALE:-en_US}"
-KEYMAP="${KEYMAP:-us}"
+DESKTOP_ENV="${DESKTOP_ENV:-none}"   # catalog id (xfce, gnome, kde, ...) or none
+DESKTOP_VARIANT="${DESKTOP_VARIANT:-}" # minimal, full (informational, already applied to DESKTOP_PACKAGES)
+DESKTOP_PACKAGES="${DESKTOP_PACKAGES:-}" # packages for DESKTOP_ENV, from gui/internal/data/desktops.json
+EXTRA_PACKAGES="${EXTRA_PACKAGES:-}" # bundles and custom packages from the Packages page
+EXTRA_SERVICES="${EXTRA_SERVICES:-}" # units the bundles need (cups, libvirtd, ...)
+DISPLAY_MANAGER="${DISPLAY_MANAGER:-}" # systemd unit to enable (gdm, sddm, lightdm, cosmic-greeter)
+SHELL_CHOICE="${SHELL_CHOICE:-bash}" # bash, zsh, zsh-ohmyzsh (single-user configs)
+KERNEL="${KERNEL:-linux}"            # linux, linux-lts, linux-zen, linux-hardened
+MULTILIB="${MULTILIB:-no}"           # yes, no
+GPU_PACKAGES="${GPU_PACKAGES:-}"     # driver packages chosen by the GUI
+GPU_AUR_PACKAGES="${GPU_AUR_PACKAGES:-}" # legacy NVIDIA branches (AUR only)
+AUR_HELPER="${AUR_HELPER:-none}"     # none, yay, paru (built as the first user)
+AUR_PACKAGES="${AUR_PACKAGES:-}"     # installed with AUR_HELPER after the first boot setup
+KERNEL_PARAMS="${KERNEL_PARAMS:-}"   # appended to GRUB_CMDLINE_LINUX_DEFAULT
+INITRAMFS_MODULES="${INITRAMFS_MODULES:-}" # added to mkinitcpio MODULES
+REMOVE_KMS_HOOK="${REMOVE_KMS_HOOK:-no}"   # yes when NVIDIA modules replace nouveau
+MICROCODE="${MICROCODE:-}"           # intel-ucode, amd-ucode, none (empty = detect)
+BLUETOOTH="${BLUETOOTH:-no}"         # yes, no
+POWER_PROFILE="${POWER_PROFILE:-none}" # none, tlp, power-profiles-daemon
+LOCALE="${LOCALE:-en_US.UTF-8}"      # LANG value
+LOCALE_GEN="${LOCALE_GEN:-}"         # contents of /etc/locale.gen (default: LOCALE only)
+LOCALE_CONF="${LOCALE_CONF:-}"       # contents of /etc/locale.conf (default: LANG=LOCALE)
+KEYMAP="${KEYMAP:-us}"               # console keymap
+X11_KEYBOARD_CONF="${X11_KEYBOARD_CONF:-}" # contents of /etc/X11/xorg.conf.d/00-keyboard.conf
 TIMEZONE="${TIMEZONE:-UTC}"
 
 # Internal Variables
 CONFIG_FILE=""
+PACMAN_CONF="/etc/pacman.conf"       # replaced by setup_package_source for offline installs
+PACSTRAP_FLAGS=""                    # -c when the shared cache is used
+TARGET_MIRRORLIST=""                 # mirrorlist for the installed system, set by apply_mirrorlist
 NONINTERACTIVE="no"
-DRY_RUN="${DRY_RUN:-no}"
+DRY_RUN="${DRY_RUN:-no}"             # yes: print the commands that change the system instead of running them
 
 # Formatting
 BOLD='\033[1m'
@@ -42,6 +84,56 @@ NC='\033[0m' # No Color
 log() { echo -e "${GREEN}[BACKEND]${NC} $1"; }
 error() { echo -e "${RED}[ERROR]${NC} $1" >&2; }
 
+# show_cmd prints a command line, quoting arguments like the GUI does
+show_cmd() {
+    local out="" a
+    for a in "$@"; do
+        if [[ -z "$a" || "$a" =~ [[:space:]\'\"\$\\] ]]; then
+            a="'${a//\'/\'\\\'\'}'"
+        fi
+        out+="${out:+ }$a"
+    done
+    printf '%s\n' "$out"
+}
+
+# run executes a command that changes the live system, the disks or the
+# target. A dry run prints it to fd 3 (stderr, opened in the main flow)
+# instead, so it shows even where output is captured.
+run() {
+    if [[ "$DRY_RUN" == "yes" ]]; then
+        printf '[DRY-RUN] %s\n' "$(show_cmd "$@")" >&3
+        return 0
+    fi
+    "$@"
+}
+
+# run_stdin is run for commands reading a secret from a pipe; a dry run
+# drains the pipe and marks the command instead of showing the input
+run_stdin() {
+    if [[ "$DRY_RUN" == "yes" ]]; then
+        cat > /dev/null
+        printf '[DRY-RUN] %s < stdin\n' "$(show_cmd "$@")" >&3
+        return 0
+    fi
+    "$@"
+}
+
+# write_file PATH and append_file PATH store stdin; a dry run discards it
+write_file() {
+    if [[ "$DRY_RUN" == "yes" ]]; then
+        cat > /dev/null
+        return 0
+    fi
+    cat > "$1"
+}
+append_file() {
+    if [[ "$DRY_RUN" == "yes" ]]; then
+        cat > /dev/null
+        return 0
+    fi
+    cat >> "$1"
+}
+
 usage() {
     echo "Usage: $0 [--config <file>]"
     echo "Environment variables can also be set directly."
@@ -70,6 +162,59 @@ if [[ -n "$CONFIG_FILE" ]]; then
     fi
 fi
 
+# Boot mode: detect from the live system unless the config overrides it
+detect_boot_mode() {
+    local DETECTED_MODE="bios"
+    [[ -d /sys/firmware/efi/efivars ]] && DETECTED_MODE="uefi"
+
+    [[ -z "$BOOT_MODE" ]] && BOOT_MODE="$DETECTED_MODE"
+    if [[ "$BOOT_MODE" != "uefi" && "$BOOT_MODE" != "bios" ]]; then
+        error "Invalid BOOT_MODE: $BOOT_MODE (expected uefi or bios)"
+        exit 1
+    fi
+
+    if [[ "$BOOT_MODE" == "uefi" && -z "$UEFI_BITS" ]]; then
+        UEFI_BITS=64
+        [[ "$(cat /sys/firmware/efi/fw_platform_size 2>/dev/null)" == "32" ]] && UEFI_BITS=32
+    fi
+
+    # Installing for another machine: NVRAM entries would land on this one,
+    # so GRUB goes to the removable fallback path instead.
+    GRUB_REMOVABLE="no"
+    [[ "$BOOT_MODE" != "$DETECTED_MODE" ]] && GRUB_REMOVABLE="yes"
+
+    log "Boot Mode: $BOOT_MODE${UEFI_BITS:+ (${UEFI_BITS}-bit)} (live system: $DETECTED_MODE)"
+}
+
+# user_field I FIELD prints USER_<I>_<FIELD>
+user_field() {
+    local var="USER_${1}_${2}"
+    printf '%s' "${!var:-}"
+}
+
+# Maps the single-user keys of older configs onto USER_0_*
+load_users() {
+    if [[ -n "$USER_COUNT" ]]; then return; fi
+    USER_COUNT=1
+    USER_0_NAME="$USERNAME"
+    USER_0_FULL_NAME="$FULL_NAME"
+    USER_0_PASSWORD="$USER_PASSWORD"
+    USER_0_ADMIN="yes"
+    USER_0_GROUPS="audio,video,storage"
+    USER_0_SHELL="$SHELL_CHOICE"
+    USER_0_HOME=""
+    USER_0_SSH_KEYS=""
+}
+
+# any_user_shell PATTERN succeeds if some account uses a matching shell choice
+any_user_shell() {
+    local i
+    for ((i = 0; i < USER_COUNT; i++)); do
+        [[ "$(user_field "$i" SHELL)" == $1 ]] && return 0
+    done
+    return 1
+}
+
 # Validation
 validate_config() {
     log "Validating configuration..."
@@ -81,13 +226,123 @@ validate_config() {
         [[ -z "$DISK" ]] && { error "DISK is not set"; MISSING_KEYS=1; }
     fi
 
-    [[ -z "$USERNAME" ]] && { error "USERNAME is not set"; MISSING_KEYS=1; }
-    [[ -z "$ROOT_PASSWORD" ]] && { error "ROOT_PASSWORD is not set"; MISSING_KEYS=1; }
-    [[ -z "$USER_PASSWORD" ]] && { error "USER_PASSWORD is not set"; MISSING_KEYS=1; }
+    load_users
+    if [[ ! "$USER_COUNT" =~ ^[0-9]+$ ]] || (( USER_COUNT < 1 )); then
+        error "USER_COUNT must be at least 1"; exit 1
+    fi
+    if [[ ! "$SWAP_SIZE" =~ ^[0-9]+$ ]]; then
+        error "SWAP_SIZE must be a number of GiB"; exit 1
+    fi
+    local i name
+    for ((i = 0; i < USER_COUNT; i++)); do
+        [[ -z "$(user_field "$i" NAME)" ]] && { error "USER_${i}_NAME is not set"; MISSING_KEYS=1; }
+        [[ -z "$(user_field "$i" PASSWORD)" ]] && { error "USER_${i}_PASSWORD is not set"; MISSING_KEYS=1; }
+    done
+    [[ -z "$ROOT_PASSWORD" ]] && [[ "$ROOT_LOCKED" != "yes" ]] && { error "ROOT_PASSWORD is not set"; MISSING_KEYS=1; }
     [[ -z "$LUKS_PASSWORD" ]] && [[ "$USE_LUKS" == "yes" ]] && { error "LUKS_PASSWORD is required for encryption"; MISSING_KEYS=1; }
+    if [[ "$MANUAL_PARTITIONING" == "yes" && "$BOOT_MODE" == "uefi" && -z "$TARGET_EFI" ]]; then
+        error "TARGET_EFI is required for manual partitioning in UEFI mode"; MISSING_KEYS=1
+    fi
     
     if [[ "$MISSING_KEYS" -eq 1 ]]; then exit 1; fi
 
+    # Same rules as the GUI; useradd would otherwise fail late inside the chroot
+    for ((i = 0; i < USER_COUNT; i++)); do
+        name="$(user_field "$i" NAME)"
+        if [[ ! "$name" =~ ^[a-z_][a-z0-9_-]*[$]?$ ]] || (( ${#name} > 32 )); then
+            error "Invalid username: $name"
+            exit 1
+        fi
+        if [[ ! "$(user_field "$i" GROUPS)" =~ ^[a-z0-9_,-]*$ ]]; then
+            error "Invalid groups for $name: $(user_field "$i" GROUPS)"
+            exit 1
+        fi
+        if [[ -n "$(user_field "$i" HOME)" && ! "$(user_field "$i" HOME)" =~ ^/[^:[:space:]]+$ ]]; then
+            error "Invalid home directory for $name: $(user_field "$i" HOME)"
+            exit 1
+        fi
+    done
+    if [[ ! "$HOSTNAME" =~ ^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$ ]]; then
+        error "Invalid hostname: $HOSTNAME"
+        exit 1
+    fi
+
+    # Key-only login without any key would lock everyone out remotely
+    if [[ "$ENABLE_SSHD" == "yes" && "$SSH_DISABLE_PASSWORDS" == "yes" ]]; then
+        local has_keys="no"
+        for ((i = 0; i < USER_COUNT; i++)); do
+            [[ -n "$(user_field "$i" SSH_KEYS)" ]] && has_keys="yes"
+        done
+        if [[ "$has_keys" != "yes" ]]; then
+            error "SSH_DISABLE_PASSWORDS needs USER_<i>_SSH_KEYS for at least one user"
+            exit 1
+        fi
+    fi
+
+    # Only Server lines and comments, pacman.conf Includes this file
+    local line
+    while IFS= read -r line; do
+        [[ -z "$line" || "$line" == \#* || "$line" =~ ^Server\ =\ https?://[^[:space:]]+$ ]] && continue
+        error "Invalid mirrorlist line: $line"
+        exit 1
+    done <<< "$MIRRORLIST"
+
+    if [[ -n "$CACHE_SERVER" && ! "$CACHE_SERVER" =~ ^https?://[^[:space:]]+$ ]]; then
+        error "Invalid cache server: $CACHE_SERVER"
+        exit 1
+    fi
+    if [[ -n "$CACHE_DIR" && ! "$CACHE_DIR" =~ ^/[^[:space:]]*$ ]]; then
+        error "Invalid cache directory: $CACHE_DIR"
+        exit 1
+    fi
+
+    if [[ -n "$WIFI_PROFILE" && ! "$WIFI_PROFILE_NAME" =~ ^[A-Za-z0-9_-][A-Za-z0-9._-]*\.nmconnection$ ]]; then
+        error "Invalid Wi-Fi profile name: $WIFI_PROFILE_NAME"
+        exit 1
+    fi
+
+    # Both end up in the chroot script
+    if [[ ! "$DISPLAY_MANAGER" =~ ^[A-Za-z0-9@._-]*$ ]]; then
+        error "Invalid display manager: $DISPLAY_MANAGER"
+        exit 1
+    fi
+    if [[ ! "$EXTRA_SERVICES" =~ ^[A-Za-z0-9@._\ -]*$ ]]; then
+        error "Invalid service list: $EXTRA_SERVICES"
+        exit 1
+    fi
+    # Passed to pacstrap unquoted, so only package name characters
+    if [[ ! "$EXTRA_PACKAGES" =~ ^[a-z0-9@._+\ -]*$ ]]; then
+        error "Invalid package list: $EXTRA_PACKAGES"
+        exit 1
+    fi
+    if [[ ! "$DESKTOP_PACKAGES" =~ ^[a-z0-9@._+\ -]*$ ]]; then
+        error "Invalid desktop package list: $DESKTOP_PACKAGES"
+        exit 1
+    fi
+    # The package sets live in the GUI's desktop catalog only
+    if [[ "$DESKTOP_ENV" != "none" && -z "$DESKTOP_PACKAGES" ]]; then
+        error "DESKTOP_ENV=$DESKTOP_ENV needs DESKTOP_PACKAGES (see gui/internal/data/desktops.json)"
+        exit 1
+    fi
+    if [[ "$AUR_HELPER" != "none" && "$AUR_HELPER" != "yay" && "$AUR_HELPER" != "paru" ]]; then
+        error "Unknown AUR helper: $AUR_HELPER"
+        exit 1
+    fi
+    if [[ ! "$AUR_PACKAGES" =~ ^[a-z0-9@._+\ -]*$ ]]; then
+        error "Invalid AUR package list: $AUR_PACKAGES"
+        exit 1
+    fi
+    if [[ ! "$GPU_AUR_PACKAGES" =~ ^[a-z0-9@._+\ -]*$ ]]; then
+        error "Invalid GPU AUR package list: $GPU_AUR_PACKAGES"
+        exit 1
+    fi
+
+    # A typo would leave /etc/localtime as a dangling symlink
+    if [[ "$TIMEZONE" == *..* ]] || [[ ! -f "/usr/share/zoneinfo/$TIMEZONE" ]]; then
+        error "Unknown timezone: $TIMEZONE"
+        exit 1
+    fi
+
     if [[ "$DRY_RUN" != "yes" ]]; then
          if [[ "$MANUAL_PARTITIONING" == "yes" ]]; then
             if [[ ! -b "$TARGET_ROOT" ]]; then
@@ -105,23 +360,15 @@ validate_config() {
 }
 
 setup_partitioning() {
-    local BOOT_MODE
-    if [[ -d /sys/firmware/efi/efivars ]]; then
-        BOOT_MODE="UEFI"
-    else
-        BOOT_MODE="BIOS"
-    fi
-    log "Boot Mode: $BOOT_MODE"
-
     if [[ "$MANUAL_PARTITIONING" == "yes" ]]; then
         log "Mode: Manual Partitioning"
         ROOT_PART="$TARGET_ROOT"
         EFI_PART="$TARGET_EFI"  # Can be empty if BIOS
 
         # Format EFI if requested (and UEFI)
-        if [[ "$BOOT_MODE" == "UEFI" ]] && [[ "$FORMAT_EFI" == "yes" ]] && [[ -n "$EFI_PART" ]]; then
+        if [[ "$BOOT_MODE" == "uefi" ]] && [[ "$FORMAT_EFI" == "yes" ]] && [[ -n "$EFI_PART" ]]; then
             log "Formatting EFI partition $EFI_PART..."
-            mkfs.fat -F32 "$EFI_PART"
+            run mkfs.fat -F32 "$EFI_PART"
         fi
 
         # We assume for ROOT that if FORMAT_ROOT=yes, we format.
@@ -142,34 +389,35 @@ setup_partitioning() {
         
         # Wiping disk
         log "Wiping $DISK..."
-        wipefs -af "$DISK"
+        run wipefs -af "$DISK"
 
         # Naming
         local PART_PREFIX="$DISK"
-        [[ "$DISK" == *"nvme"* || "$DISK" == *"mmcblk"* ]] && PART_PREFIX="${DISK}p"
+        # Names ending in a digit (nvme0n1, mmcblk0, loop0) get a "p" before the number
+        [[ "$DISK" =~ [0-9]$ ]] && PART_PREFIX="${DISK}p"
 
-        if [[ "$BOOT_MODE" == "UEFI" ]]; then
+        if [[ "$BOOT_MODE" == "uefi" ]]; then
             # UEFI: GPT, ESP, Root
-            parted -s "$DISK" mklabel gpt
-            parted -s "$DISK" mkpart "EFI" fat32 1MiB 513MiB
-            parted -s "$DISK" set 1 esp on
-            parted -s "$DISK" mkpart "root" "${FS_TYPE}" 513MiB 100%
+            run parted -s "$DISK" mklabel gpt
+            run parted -s "$DISK" mkpart "EFI" fat32 1MiB 513MiB
+            run parted -s "$DISK" set 1 esp on
+            run parted -s "$DISK" mkpart "root" "${FS_TYPE}" 513MiB 100%
             
             EFI_PART="${PART_PREFIX}1"
             ROOT_PART="${PART_PREFIX}2"
-            mkfs.fat -F32 "$EFI_PART"
+            run mkfs.fat -F32 "$EFI_PART"
         else
             # BIOS: MBR, Root
-            parted -s "$DISK" mklabel msdos
-            parted -s "$DISK" mkpart primary "${FS_TYPE}" 1MiB 100%
-            parted -s "$DISK" set 1 boot on
+            run parted -s "$DISK" mklabel msdos
+            run parted -s "$DISK" mkpart primary "${FS_TYPE}" 1MiB 100%
+            run parted -s "$DISK" set 1 boot on
             
             ROOT_PART="${PART_PREFIX}1"
         fi
         
         # Wait for nodes
-        sleep 2
-        partprobe "$DISK" || true
+        [[ "$DRY_RUN" == "yes" ]] || sleep 2
+        run partprobe "$DISK" || true
         
         # Auto mode always formats root
         FORMAT_ROOT="yes"
@@ -179,8 +427,8 @@ setup_partitioning() {
     local CRYPT_ROOT="$ROOT_PART"
     if [[ "$USE_LUKS" == "yes" ]] && [[ "$FORMAT_ROOT" == "yes" ]]; then
         log "Encrypting root partition $ROOT_PART..."
-        echo -n "$LUKS_PASSWORD" | cryptsetup luksFormat --type luks2 "$ROOT_PART" -
-        echo -n "$LUKS_PASSWORD" | cryptsetup open "$ROOT_PART" cryptroot -
+        echo -n "$LUKS_PASSWORD" | run_stdin cryptsetup luksFormat --type luks2 "$ROOT_PART" -
+        echo -n "$LUKS_PASSWORD" | run_stdin cryptsetup open "$ROOT_PART" cryptroot -
         CRYPT_ROOT="/dev/mapper/cryptroot"
     fi
 
@@ -188,148 +436,434 @@ setup_partitioning() {
     if [[ "$FORMAT_ROOT" == "yes" ]]; then
         if [[ "$FS_TYPE" == "btrfs" ]]; then
             log "Formatting BTRFS..."
-            mkfs.btrfs -f "$CRYPT_ROOT"
-            mount "$CRYPT_ROOT" /mnt
+            run mkfs.btrfs -f "$CRYPT_ROOT"
+            run mount "$CRYPT_ROOT" /mnt
             
             # Subvolumes
-            btrfs subvolume create /mnt/@
-            btrfs subvolume create /mnt/@home
-            btrfs subvolume create /mnt/@snapshots
-            btrfs subvolume create /mnt/@var_log
-            umount /mnt
+            run btrfs subvolume create /mnt/@
+            run btrfs subvolume create /mnt/@home
+            run btrfs subvolume create /mnt/@snapshots
+            run btrfs subvolume create /mnt/@var_log
+            run umount /mnt
             
             local BTRFS_OPTS="noatime,compress=zstd,space_cache=v2,discard=async"
-            mount -o "subvol=@,${BTRFS_OPTS}" "$CRYPT_ROOT" /mnt
-            mkdir -p /mnt/{home,.snapshots,var/log,boot}
-            mount -o "subvol=@home,${BTRFS_OPTS}" "$CRYPT_ROOT" /mnt/home
-            mount -o "subvol=@snapshots,${BTRFS_OPTS}" "$CRYPT_ROOT" /mnt/.snapshots
-            mount -o "subvol=@var_log,${BTRFS_OPTS}" "$CRYPT_ROOT" /mnt/var/log
+            run mount -o "subvol=@,${BTRFS_OPTS}" "$CRYPT_ROOT" /mnt
+            run mkdir -p /mnt/{home,.snapshots,var/log,boot}
+            run mount -o "subvol=@home,${BTRFS_OPTS}" "$CRYPT_ROOT" /mnt/home
+            run mount -o "subvol=@snapshots,${BTRFS_OPTS}" "$CRYPT_ROOT" /mnt/.snapshots
+            run mount -o "subvol=@var_log,${BTRFS_OPTS}" "$CRYPT_ROOT" /mnt/var/log
         else
             log "Formatting EXT4..."
-            mkfs.ext4 -F "$CRYPT_ROOT"
-            mount "$CRYPT_ROOT" /mnt
+            run mkfs.ext4 -F "$CRYPT_ROOT"
+            run mount "$CRYPT_ROOT" /mnt
         fi
     else
         log "Mounting existing root partition without formatting..."
         # If encrypted, we assume user already opened it? No, script is creating NEW system.
         # If manual + no-format, it implies we are installing over existing FS?
         # For now, let's just mount.
-        mount "$CRYPT_ROOT" /mnt
+        run mount "$CRYPT_ROOT" /mnt
         # Detect if it's btrfs and handle subvols? Too complex for blind script.
         # Assume standard mount.
     fi
 
-    if [[ "$BOOT_MODE" == "UEFI" ]]; then
-        mkdir -p /mnt/boot
+    if [[ "$BOOT_MODE" == "uefi" ]]; then
+        run mkdir -p /mnt/boot
         if [[ -n "$EFI_PART" ]]; then
-            mount "$EFI_PART" /mnt/boot
+            run mount "$EFI_PART" /mnt/boot
         fi
     fi
 }
 
+# Enable [multilib] in a pacman.conf (live system for pacstrap, then the target)
+enable_multilib() {
+    local CONF="$1"
+    if ! grep -qs "^\[multilib\]" "$CONF"; then
+        run sed -i '/^#\[multilib\]/,/^#Include/ s/^#//' "$CONF"
+    fi
+}
+
+# Lab installs: checks the caching proxy and the shared cache before any
+# disk is touched. Whatever is unreachable is dropped with a warning and
+# the installation continues with the mirrors.
+check_package_cache() {
+    if [[ -n "$CACHE_SERVER" ]]; then
+        local probe="${CACHE_SERVER//\$repo/core}"
+        probe="${probe//\$arch/x86_64}"
+        if curl -fsS -o /dev/null --max-time 10 "${probe%/}/core.db"; then
+            log "Caching proxy $CACHE_SERVER is reachable."
+        else
+            log "Warning: caching proxy $CACHE_SERVER is unreachable, using the mirrors"
+            CACHE_SERVER=""
+        fi
+    fi
+    if [[ -n "$CACHE_DIR" ]]; then
+        if [[ -d "$CACHE_DIR" && -w "$CACHE_DIR" ]]; then
+            log "Using shared package cache $CACHE_DIR"
+        else
+            log "Warning: shared cache $CACHE_DIR is missing or read-only, using the target's cache"
+            CACHE_DIR=""
+        fi
+    fi
+}
+
+# Writes the ranked mirrors, led by the caching proxy, to the live system,
+# which pacstrap downloads with. The original list is kept next to it. The
+# installed system gets the mirrors without the proxy.
+apply_mirrorlist() {
+    [[ -z "$MIRRORLIST" && -z "$CACHE_SERVER" ]] && return 0
+    log "Writing mirrorlist..."
+    local backup=/etc/pacman.d/mirrorlist.archgui-bak
+    [[ -f "$backup" ]] || run cp /etc/pacman.d/mirrorlist "$backup"
+    # A dry run made no backup, the live list is still the original
+    [[ -f "$backup" ]] || backup=/etc/pacman.d/mirrorlist
+    TARGET_MIRRORLIST="$MIRRORLIST"
+    [[ -z "$TARGET_MIRRORLIST" ]] && TARGET_MIRRORLIST="$(cat "$backup")"
+    # pacman falls back to the next Server when the proxy fails mid-install
+    if [[ -n "$CACHE_SERVER" ]]; then
+        printf 'Server = %s\n%s\n' "$CACHE_SERVER" "$TARGET_MIRRORLIST" | write_file /etc/pacman.d/mirrorlist
+    else
+        printf '%s\n' "$TARGET_MIRRORLIST" | write_file /etc/pacman.d/mirrorlist
+    fi
+
+    # Downloads land in the shared cache, so the next machine finds them
+    if [[ -n "$CACHE_DIR" ]]; then
+        [[ "$PACMAN_CONF" == /etc/pacman.conf ]] && run cp /etc/pacman.conf /tmp/archgui-pacman.conf
+        PACMAN_CONF=/tmp/archgui-pacman.conf
+        run sed -i "/^\[options\]/a CacheDir = ${CACHE_DIR%/}/\nCacheDir = /var/cache/pacman/pkg/" "$PACMAN_CONF"
+        PACSTRAP_FLAGS="-c"
+    fi
+}
+
+# run_pacstrap [-OPTION...] PACKAGE... installs into /mnt with the package
+# source set up above
+run_pacstrap() {
+    local opts=()
+    while [[ "$1" == -* ]]; do
+        opts+=("$1")
+        shift
+    done
+    run pacstrap -C "$PACMAN_CONF" $PACSTRAP_FLAGS "${opts[@]}" /mnt "$@"
+}
+
+# base_packages prints the packages of the first pacstrap
+base_packages() {
+    # Microcode check (the GUI normally decides, detect only when unset)
+    local microcode="$MICROCODE"
+    if [[ -z "$microcode" ]]; then
+        local CPU_VENDOR
+        CPU_VENDOR=$(grep -m1 vendor_id /proc/cpuinfo | awk '{print $3}' || true)
+        [[ "$CPU_VENDOR" == "GenuineIntel" ]] && microcode="intel-ucode"
+        [[ "$CPU_VENDOR" == "AuthenticAMD" ]] && microcode="amd-ucode"
+    fi
+
+    local PACKAGES="base base-devel $KERNEL linux-firmware networkmanager grub sudo nano vim git btop"
+    [[ -n "$microcode" && "$microcode" != "none" ]] && PACKAGES="$PACKAGES $microcode"
+    [[ "$BLUETOOTH" == "yes" ]] && PACKAGES="$PACKAGES bluez bluez-utils"
+    [[ "$POWER_PROFILE" != "none" ]] && PACKAGES="$PACKAGES $POWER_PROFILE"
+    [[ "$FS_TYPE" == "btrfs" ]] && PACKAGES="$PACKAGES btrfs-progs"
+    [[ "$BOOT_MODE" == "uefi" ]] && PACKAGES="$PACKAGES efibootmgr"
+    echo "$PACKAGES"
+}
+
+# all_packages prints everything install_packages installs from the repos
+all_packages() {
+    local PACKAGES
+    PACKAGES="$(base_packages) $GPU_PACKAGES $EXTRA_PACKAGES"
+    [[ "$DESKTOP_ENV" != "none" ]] && PACKAGES="$PACKAGES $DESKTOP_PACKAGES"
+    any_user_shell "zsh*" && PACKAGES="$PACKAGES zsh zsh-completions"
+    any_user_shell "zsh-ohmyzsh" && PACKAGES="$PACKAGES git curl"
+    [[ "$PRIVILEGE_TOOL" == "doas" || "$PRIVILEGE_TOOL" == "both" ]] && PACKAGES="$PACKAGES opendoas"
+    [[ "$ENABLE_SSHD" == "yes" ]] && PACKAGES="$PACKAGES openssh"
+    echo $PACKAGES
+}
+
+# Offline installs read every package from OFFLINE_REPO through a private
+# pacman.conf. A bare package cache is indexed with repo-add first. The full
+# package list is resolved before any disk is touched, so a missing package
+# fails here instead of halfway through pacstrap.
+setup_package_source() {
+    [[ -z "$OFFLINE_REPO" ]] && return 0
+    local repo_dir="$OFFLINE_REPO" db dbpath=/tmp/archgui-offline-db
+
+    if [[ ! -d "$OFFLINE_REPO" ]]; then
+        error "Offline repository $OFFLINE_REPO does not exist"
+        exit 1
+    fi
+    if ! compgen -G "$OFFLINE_REPO/*.db" > /dev/null; then
+        log "Indexing package cache $OFFLINE_REPO..."
+        repo_dir=/tmp/archgui-offline-repo
+        run rm -rf "$repo_dir"
+        run mkdir -p "$repo_dir"
+        run ln -s "$OFFLINE_REPO"/*.pkg.tar* "$repo_dir"/
+        # The links are listed from the source, they do not exist in a dry run
+        run repo-add -q "$repo_dir/offline.db.tar.gz" $(compgen -G "$OFFLINE_REPO/*.pkg.tar*" | grep -v '\.sig$' | sed "s|^$OFFLINE_REPO|$repo_dir|")
+    fi
+
+    # Keep [options] of the live config, replace all repositories
+    PACMAN_CONF=/tmp/archgui-pacman.conf
+    {
+        awk '/^\[/ && $0 != "[options]" { exit } { print }' /etc/pacman.conf
+        if [[ "$repo_dir" == "$OFFLINE_REPO" ]]; then
+            for db in "$repo_dir"/*.db; do
+                printf '\n[%s]\nSigLevel = Optional TrustedOnly\nServer = file://%s\n' "$(basename "$db" .db)" "$repo_dir"
+            done
+        else
+            printf '\n[offline]\nSigLevel = Optional TrustedOnly\nServer = file://%s\n' "$repo_dir"
+        fi
+    } | write_file "$PACMAN_CONF"
+
+    log "Checking packages against $OFFLINE_REPO..."
+    run rm -rf "$dbpath"
+    run mkdir -p "$dbpath"
+    run pacman --config "$PACMAN_CONF" --dbpath "$dbpath" -Sy > /dev/null
+    local out
+    if ! out=$(run pacman --config "$PACMAN_CONF" --dbpath "$dbpath" -Sp --print-format '%n' $(all_packages) 2>&1); then
+        error "Packages missing from $OFFLINE_REPO:"
+        grep -E 'error|warning' <<< "$out" >&2 || printf '%s\n' "$out" >&2
+        exit 1
+    fi
+    run rm -rf "$dbpath"
+    [[ "$DRY_RUN" == "yes" ]] || log "All $(wc -l <<< "$out") packages are available offline."
+}
+
 install_packages() {
+    if [[ "$MULTILIB" == "yes" && -z "$OFFLINE_REPO" ]]; then
+        log "Enabling multilib repository..."
+        enable_multilib "$PACMAN_CONF"
+        run pacman --config "$PACMAN_CONF" -Sy
+    fi
+
     log "Installing base system..."
-    # Microcode check
-    local MICROCODE=""
-    local CPU_VENDOR
-    CPU_VENDOR=$(grep -m1 vendor_id /proc/cpuinfo | awk '{print $3}' || true)
-    [[ "$CPU_VENDOR" == "GenuineIntel" ]] && MICROCODE="intel-ucode"
-    [[ "$CPU_VENDOR" == "AuthenticAMD" ]] && MICROCODE="amd-ucode"
-
-    local PACKAGES="base base-devel linux linux-firmware networkmanager grub sudo nano vim git btop"
-    [[ -n "$MICROCODE" ]] && PACKAGES="$PACKAGES $MICROCODE"
-    [[ "$FS_TYPE" == "btrfs" ]] && PACKAGES="$PACKAGES btrfs-progs"
-    [[ -d /sys/firmware/efi/efivars ]] && PACKAGES="$PACKAGES efibootmgr"
-    
-    pacstrap -K /mnt $PACKAGES
+    run_pacstrap -K $(base_packages)
+    [[ -n "$TARGET_MIRRORLIST" ]] && printf '%s\n' "$TARGET_MIRRORLIST" | write_file /mnt/etc/pacman.d/mirrorlist
+    [[ "$MULTILIB" == "yes" ]] && enable_multilib /mnt/etc/pacman.conf
+
+    # Graphics drivers (independent of the desktop, also used for compute)
+    if [[ -n "$GPU_PACKAGES" ]]; then
+        log "Installing graphics drivers: $GPU_PACKAGES"
+        run_pacstrap $GPU_PACKAGES
+    fi
+    if [[ -n "$GPU_AUR_PACKAGES" && "$AUR_HELPER" == "none" ]]; then
+        log "Warning: $GPU_AUR_PACKAGES are only available from the AUR and were not installed."
+    fi
 
-    # Desktop Environment Logic
+    # Desktop Environment (packages resolved by the GUI from its desktop catalog)
     if [[ "$DESKTOP_ENV" != "none" ]]; then
-        log "Installing Desktop: $DESKTOP_ENV"
-        local DESKTOP_PKGS="xorg-server xorg-xinit pipewire pipewire-alsa pipewire-pulse wireplumber pavucontrol"
-        local DM=""
-        
-        case "$DESKTOP_ENV" in
-            xfce)
-                DESKTOP_PKGS="$DESKTOP_PKGS xfce4 xfce4-goodies lightdm lightdm-gtk-greeter"
-                DM="lightdm"
-                ;;
-            gnome)
-                DESKTOP_PKGS="$DESKTOP_PKGS gnome gnome-tweaks gdm"
-                DM="gdm"
-                ;;
-            kde)
-                DESKTOP_PKGS="$DESKTOP_PKGS plasma-meta kde-applications-meta sddm packagekit-qt6"
-                DM="sddm"
-                ;;
-            i3)
-                DESKTOP_PKGS="$DESKTOP_PKGS i3-wm i3status dmenu xterm feh picom lightdm lightdm-gtk-greeter"
-                DM="lightdm"
-                ;;
-            hyprland)
-                DESKTOP_PKGS="$DESKTOP_PKGS hyprland xdg-desktop-portal-hyprland waybar wofi foot sddm"
-                DM="sddm"
-                ;;
-            *)
-                log "Unknown desktop environment: $DESKTOP_ENV"
-                ;;
-        esac
-
-        # Nvidia
-        if [[ "$HAS_NVIDIA" == "yes" ]]; then
-            DESKTOP_PKGS="$DESKTOP_PKGS nvidia nvidia-utils nvidia-settings"
-        fi
+        log "Installing Desktop: $DESKTOP_ENV${DESKTOP_VARIANT:+ ($DESKTOP_VARIANT)}"
+        run_pacstrap $DESKTOP_PACKAGES
+    fi
 
-        pacstrap /mnt $DESKTOP_PKGS
-        
-        # Save DM for chroot script
-        echo "DISPLAY_MANAGER=$DM" > /mnt/dm_info
+    # Additional software
+    if [[ -n "$EXTRA_PACKAGES" ]]; then
+        log "Installing additional packages: $EXTRA_PACKAGES"
+        run_pacstrap $EXTRA_PACKAGES
     fi
 
     # Shell
-    if [[ "$SHELL_CHOICE" == *"zsh"* ]]; then
-        pacstrap /mnt zsh zsh-completions
+    if any_user_shell "zsh*"; then
+        run_pacstrap zsh zsh-completions
+    fi
+    if any_user_shell "zsh-ohmyzsh"; then
+        run_pacstrap git curl
+    fi
+
+    # doas
+    if [[ "$PRIVILEGE_TOOL" == "doas" || "$PRIVILEGE_TOOL" == "both" ]]; then
+        run_pacstrap opendoas
+    fi
+
+    # SSH server
+    if [[ "$ENABLE_SSHD" == "yes" ]]; then
+        run_pacstrap openssh
+    fi
+}
+
+# Adds every account via arch-chroot. Values are passed as arguments and
+# passwords on stdin, so they never become part of a generated script.
+create_users() {
+    local i name groups home shell
+    for ((i = 0; i < USER_COUNT; i++)); do
+        name="$(user_field "$i" NAME)"
+        groups="$(user_field "$i" GROUPS)"
+        if [[ "$(user_field "$i" ADMIN)" == "yes" ]]; then
+            groups="wheel${groups:+,$groups}"
+        fi
+        shell="/bin/bash"
+        [[ "$(user_field "$i" SHELL)" == zsh* ]] && shell="/bin/zsh"
+
+        local args=(-m -c "$(user_field "$i" FULL_NAME)" -s "$shell")
+        [[ -n "$groups" ]] && args+=(-G "$groups")
+        home="$(user_field "$i" HOME)"
+        if [[ -n "$home" ]]; then
+            run arch-chroot /mnt mkdir -p "$(dirname "$home")"
+            args+=(-d "$home")
+        fi
+
+        log "Creating user $name (groups: ${groups:-none})..."
+        run arch-chroot /mnt useradd "${args[@]}" "$name"
+        printf '%s:%s\n' "$name" "$(user_field "$i" PASSWORD)" | run_stdin arch-chroot /mnt chpasswd
+
+        install_ssh_keys "$name" "${home:-/home/$name}" "$(user_field "$i" SSH_KEYS)"
+
+        if [[ "$(user_field "$i" SHELL)" == "zsh-ohmyzsh" && -n "$OFFLINE_REPO" ]]; then
+            log "Warning: offline install, Oh-My-Zsh not installed for $name"
+        elif [[ "$(user_field "$i" SHELL)" == "zsh-ohmyzsh" ]]; then
+            log "Installing Oh-My-Zsh for user $name..."
+            run arch-chroot /mnt su - "$name" -c 'sh -c "$(curl -fsSL https://raw.githubusercontent.com/ohmyzsh/ohmyzsh/master/tools/install.sh)" "" --unattended'
+        fi
+    done
+}
+
+# install_ssh_keys NAME HOME KEYS writes ~/.ssh/authorized_keys with the
+# permissions sshd's StrictModes requires
+install_ssh_keys() {
+    local name="$1" home="$2" keys="$3"
+    [[ -z "$keys" ]] && return 0
+    log "Installing SSH keys for $name..."
+    run install -d -m 700 "/mnt$home/.ssh"
+    printf '%s\n' "$keys" | write_file "/mnt$home/.ssh/authorized_keys"
+    run chmod 600 "/mnt$home/.ssh/authorized_keys"
+    # Owner is resolved inside the target, its UIDs differ from the live system
+    run arch-chroot /mnt chown -R "$name:" "$home/.ssh"
+}
+
+# Grants wheel root access through a sudoers drop-in and/or doas.conf. Both
+# files are checked with the target's own tools before they take effect, a
+# broken sudoers file would otherwise lock administrators out.
+configure_privileges() {
+    # Older configs only knew sudo with a password
+    if [[ -z "$SUDOERS_DROPIN" && -z "$DOAS_CONF" && "$PRIVILEGE_TOOL" == "sudo" ]]; then
+        SUDOERS_DROPIN="%wheel ALL=(ALL:ALL) ALL"
+    fi
+
+    if [[ -n "$SUDOERS_DROPIN" ]]; then
+        log "Writing sudoers drop-in..."
+        # Staged outside sudoers.d; sudo ignores names with a dot, not broken content
+        printf '%s\n' "$SUDOERS_DROPIN" | write_file /mnt/root/10-wheel.sudoers
+        run chmod 440 /mnt/root/10-wheel.sudoers
+        if ! run arch-chroot /mnt visudo -cf /root/10-wheel.sudoers; then
+            error "Generated sudoers drop-in is invalid, aborting"
+            exit 1
+        fi
+        run install -d -m 750 /mnt/etc/sudoers.d
+        run mv /mnt/root/10-wheel.sudoers /mnt/etc/sudoers.d/10-wheel
+    fi
+
+    if [[ -n "$DOAS_CONF" ]]; then
+        log "Writing doas.conf..."
+        printf '%s\n' "$DOAS_CONF" | write_file /mnt/etc/doas.conf
+        run chmod 400 /mnt/etc/doas.conf
+        if ! run arch-chroot /mnt doas -C /etc/doas.conf; then
+            error "Generated doas.conf is invalid, aborting"
+            exit 1
+        fi
+    fi
+}
+
+# Enables sshd and, if asked, turns off password logins with a drop-in
+configure_sshd() {
+    [[ "$ENABLE_SSHD" != "yes" ]] && return 0
+    log "Enabling SSH server..."
+    if [[ "$SSH_DISABLE_PASSWORDS" == "yes" ]]; then
+        run mkdir -p /mnt/etc/ssh/sshd_config.d
+        write_file /mnt/etc/ssh/sshd_config.d/20-archgui.conf <<EOF
+# Written by the Arch Linux GUI installer: key-only logins
+PasswordAuthentication no
+KbdInteractiveAuthentication no
+EOF
+    fi
+    run arch-chroot /mnt systemctl enable sshd
+}
+
+# Copies the Wi-Fi joined during the installation, so NetworkManager connects
+# on first boot. The file holds the passphrase and must stay root-only.
+install_wifi_profile() {
+    [[ -z "$WIFI_PROFILE" ]] && return 0
+    log "Copying Wi-Fi profile..."
+    run install -d -m 700 /mnt/etc/NetworkManager/system-connections
+    run install -m 600 /dev/null "/mnt/etc/NetworkManager/system-connections/$WIFI_PROFILE_NAME"
+    printf '%s' "$WIFI_PROFILE" | write_file "/mnt/etc/NetworkManager/system-connections/$WIFI_PROFILE_NAME"
+}
+
+# Swapfile on the root filesystem. On btrfs it lives in a nested subvolume,
+# which snapshots of @ skip, and mkswapfile sets NOCOW for it.
+create_swapfile() {
+    (( SWAP_SIZE == 0 )) && return 0
+    log "Creating ${SWAP_SIZE} GiB swapfile..."
+    # Nothing is mounted in a dry run, the selection is what would be
+    local fstype="$FS_TYPE"
+    [[ "$DRY_RUN" == "yes" ]] || fstype="$(findmnt -n -o FSTYPE /mnt)"
+    if [[ "$fstype" == "btrfs" ]]; then
+        run btrfs subvolume create /mnt/swap
+        run btrfs filesystem mkswapfile --size "${SWAP_SIZE}g" --uuid clear /mnt/swap/swapfile
+        echo "/swap/swapfile none swap defaults 0 0" | append_file /mnt/etc/fstab
+    else
+        run fallocate -l "${SWAP_SIZE}G" /mnt/swapfile
+        run chmod 600 /mnt/swapfile
+        run mkswap /mnt/swapfile
+        echo "/swapfile none swap defaults 0 0" | append_file /mnt/etc/fstab
     fi
 }
 
 configure_system() {
     log "Configuring system..."
-    genfstab -U /mnt >> /mnt/etc/fstab
+    run genfstab -U /mnt | append_file /mnt/etc/fstab
+    create_swapfile
+
+    # Locales: written from the host, the values may contain anything
+    if [[ -z "$LOCALE_GEN" ]]; then
+        # Older configs passed "en_US" and relied on .UTF-8 being appended
+        [[ "$LOCALE" != *.* && "$LOCALE" != *@* ]] && LOCALE="${LOCALE}.UTF-8"
+        LOCALE_GEN="$LOCALE ${LOCALE##*.}"
+    fi
+    [[ -z "$LOCALE_CONF" ]] && LOCALE_CONF="LANG=$LOCALE"
+    printf '%s\n' "$LOCALE_GEN" | write_file /mnt/etc/locale.gen
+    printf '%s\n' "$LOCALE_CONF" | write_file /mnt/etc/locale.conf
+
+    # Keyboard: console and X11 (the desktop would otherwise come up with US)
+    echo "KEYMAP=${KEYMAP}" | write_file /mnt/etc/vconsole.conf
+    if [[ -n "$X11_KEYBOARD_CONF" ]]; then
+        run mkdir -p /mnt/etc/X11/xorg.conf.d
+        printf '%s' "$X11_KEYBOARD_CONF" | write_file /mnt/etc/X11/xorg.conf.d/00-keyboard.conf
+    fi
+
+    configure_privileges
 
     # Create Chroot Script
-    cat > /mnt/setup_chroot.sh <<EOF
+    write_file /mnt/setup_chroot.sh <<EOF
 #!/bin/bash
 set -e
 
 # Timezone & Locale
 ln -sf /usr/share/zoneinfo/${TIMEZONE} /etc/localtime
 hwclock --systohc
-sed -i "s/^#${LOCALE}.UTF-8/${LOCALE}.UTF-8/" /etc/locale.gen
 locale-gen
-echo "LANG=${LOCALE}.UTF-8" > /etc/locale.conf
-echo "KEYMAP=${KEYMAP}" > /etc/vconsole.conf
 echo "${HOSTNAME}" > /etc/hostname
 
-# Root Password
-echo "root:${ROOT_PASSWORD}" | chpasswd
-
-# User Setup
-USER_SHELL="/bin/bash"
-if command -v zsh &>/dev/null; then USER_SHELL="/bin/zsh"; fi
-useradd -m -c "${FULL_NAME}" -G wheel,audio,video,storage -s "\$USER_SHELL" "${USERNAME}"
-echo "${USERNAME}:${USER_PASSWORD}" | chpasswd
-sed -i 's/^# %wheel ALL=(ALL:ALL) ALL/%wheel ALL=(ALL:ALL) ALL/' /etc/sudoers
-
 # NetworkManager
 systemctl enable NetworkManager
 
+# Services of the selected software bundles
+for unit in ${EXTRA_SERVICES}; do
+    systemctl enable "\$unit"
+done
+
+# Hardware services
+if [[ "${BLUETOOTH}" == "yes" ]]; then
+    systemctl enable bluetooth
+fi
+if [[ "${POWER_PROFILE}" == "tlp" ]]; then
+    systemctl enable tlp
+    # TLP manages radios itself, see the TLP installation notes
+    systemctl mask systemd-rfkill.service systemd-rfkill.socket
+elif [[ "${POWER_PROFILE}" == "power-profiles-daemon" ]]; then
+    systemctl enable power-profiles-daemon
+fi
+
 # Display Manager
-if [[ -f /dm_info ]]; then
-    source /dm_info
-    if [[ -n "\$DISPLAY_MANAGER" ]]; then
-        systemctl enable "\$DISPLAY_MANAGER"
-    fi
-    rm /dm_info
+if [[ -n "${DISPLAY_MANAGER}" ]]; then
+    systemctl enable "${DISPLAY_MANAGER}"
 fi
 
 # Bootloader (GRUB)
@@ -342,10 +876,28 @@ if [[ "${USE_LUKS}" == "yes" ]]; then
     mkinitcpio -P
 fi
 
-if [[ -d /sys/firmware/efi/efivars ]]; then
+# Graphics: early KMS modules and kernel parameters
+if [[ -n "${INITRAMFS_MODULES}" ]]; then
+    sed -i "s/^MODULES=(\(.*\))/MODULES=(\1 ${INITRAMFS_MODULES})/" /etc/mkinitcpio.conf
+fi
+if [[ "${REMOVE_KMS_HOOK}" == "yes" ]]; then
+    sed -i '/^HOOKS=/ s/ kms//' /etc/mkinitcpio.conf
+fi
+if [[ -n "${INITRAMFS_MODULES}" ]] || [[ "${REMOVE_KMS_HOOK}" == "yes" ]]; then
+    mkinitcpio -P
+fi
+if [[ -n "${KERNEL_PARAMS}" ]]; then
+    sed -i "s|^GRUB_CMDLINE_LINUX_DEFAULT=\"\(.*\)\"|GRUB_CMDLINE_LINUX_DEFAULT=\"\1 ${KERNEL_PARAMS}\"|" /etc/default/grub
+fi
+
+if [[ "${BOOT_MODE}" == "uefi" ]]; then
     # For manual partitioning, we don't always wipe the disk, but we installed grub to ESP. 
     # grub-install sets up the efi binary.
-    grub-install --target=x86_64-efi --efi-directory=/boot --bootloader-id=ARCH
+    GRUB_TARGET="x86_64-efi"
+    [[ "${UEFI_BITS}" == "32" ]] && GRUB_TARGET="i386-efi"
+    GRUB_EXTRA=""
+    [[ "${GRUB_REMOVABLE}" == "yes" ]] && GRUB_EXTRA="--removable"
+    grub-install --target=\$GRUB_TARGET --efi-directory=/boot --bootloader-id=ARCH \$GRUB_EXTRA
 else
     # For BIOS, we install to the disk MBR usually. 
     # In manual mode, we need the DISK variable or we assume ROOT_PART's disk?
@@ -364,20 +916,9 @@ else
 fi
 grub-mkconfig -o /boot/grub/grub.cfg
 
-# Oh-My-Zsh
-if [[ "${SHELL_CHOICE}" == "zsh-ohmyzsh" ]]; then
-    echo "Installing Oh-My-Zsh for user ${USERNAME}..."
-    # Install git and curl just in case (though base/base-devel usually has them, or we added them)
-    # We should ensure they are present.
-    pacman -S --noconfirm git curl
-    
-    # Run installer as the user
-    su - ${USERNAME} -c 'sh -c "\$(curl -fsSL https://raw.githubusercontent.com/ohmyzsh/ohmyzsh/master/tools/install.sh)" "" --unattended'
-fi
-
 EOF
     
-    chmod +x /mnt/setup_chroot.sh
+    run chmod +x /mnt/setup_chroot.sh
     # Pass external variables that are needed inside if not templated
     # Actually, we templated ${TIMEZONE} etc into the heredoc, so we are good.
     # But wait! ROOT_PART is needed inside for LUKS UUID, but we defined it in the host scope.
@@ -388,23 +929,88 @@ EOF
     # Variables like \$USER_SHELL (escaped) will be literal in the file.
     # We need ROOT_PART to be expanded.
     
-    arch-chroot /mnt /setup_chroot.sh
-    rm /mnt/setup_chroot.sh
+    run arch-chroot /mnt /setup_chroot.sh
+    run rm /mnt/setup_chroot.sh
+
+    create_users
+    configure_sshd
+    install_wifi_profile
+
+    # Passwords are piped in from the host so quotes or $ in them cannot
+    # break the generated chroot script
+    log "Setting root password..."
+    if [[ "$ROOT_LOCKED" == "yes" ]]; then
+        run arch-chroot /mnt passwd -l root
+    else
+        printf 'root:%s\n' "$ROOT_PASSWORD" | run_stdin arch-chroot /mnt chpasswd
+    fi
+}
+
+# Builds yay or paru as the first user (makepkg refuses to run as root) and
+# installs AUR packages with it. Nothing here aborts the install, failures
+# are collected and reported as warnings at the end.
+install_aur() {
+    [[ "$AUR_HELPER" == "none" ]] && return 0
+    if [[ -n "$OFFLINE_REPO" ]]; then
+        log "Warning: offline install, AUR packages not installed: $AUR_HELPER $GPU_AUR_PACKAGES $AUR_PACKAGES"
+        return 0
+    fi
+    local user="$USER_0_NAME"
+    local sudoers="/mnt/etc/sudoers.d/90-archgui-aur"
+    local failed=()
+
+    log "Building $AUR_HELPER as $user..."
+    if ! run arch-chroot /mnt pacman -S --noconfirm --needed git base-devel; then
+        log "Warning: could not install git and base-devel, skipping the AUR"
+        return 0
+    fi
+
+    # makepkg -si and the helper call sudo pacman; allowed without a password
+    # only while this function runs
+    printf '%s ALL=(ALL) NOPASSWD: /usr/bin/pacman\n' "$user" | write_file "$sudoers"
+    run chmod 440 "$sudoers"
+
+    # The -bin packages avoid compiling Go/Rust in the chroot
+    if run arch-chroot /mnt su - "$user" -c "rm -rf /tmp/aur-helper && git clone https://aur.archlinux.org/${AUR_HELPER}-bin.git /tmp/aur-helper && cd /tmp/aur-helper && makepkg -si --noconfirm"; then
+        local pkg
+        for pkg in $GPU_AUR_PACKAGES $AUR_PACKAGES; do
+            log "Installing $pkg from the AUR..."
+            if ! run arch-chroot /mnt su - "$user" -c "$AUR_HELPER -S --noconfirm --needed $pkg"; then
+                failed+=("$pkg")
+            fi
+        done
+    else
+        failed+=("$AUR_HELPER" $GPU_AUR_PACKAGES $AUR_PACKAGES)
+    fi
+
+    run rm -f "$sudoers"
+    run rm -rf /mnt/tmp/aur-helper
+
+    if (( ${#failed[@]} > 0 )); then
+        log "Warning: AUR packages not installed: ${failed[*]}"
+        log "Warning: install them after the first boot with $AUR_HELPER -S"
+    fi
 }
 
 # Main Execution Flow
 if [[ "$NONINTERACTIVE" == "yes" ]]; then
+    [[ "$DRY_RUN" == "yes" ]] && exec 3>&2
+    detect_boot_mode
     validate_config
-    if [[ "$DRY_RUN" == "yes" ]]; then
-        log "Dry run complete. No changes made."
-        exit 0
-    fi
+    setup_package_source
+    check_package_cache
     
     setup_partitioning
+    apply_mirrorlist
     install_packages
     configure_system
+    install_aur
     
-    log "Installation Complete!"
+    if [[ "$DRY_RUN" == "yes" ]]; then
+        log "Dry run complete. No changes made."
+    else
+        log "Installation Complete!"
+    fi
 else
     usage
 fi
@@ -57,7 +57,8 @@ pacman -Sy
 # glibc: usually present, but ensure base deps
 # Install minimal X environment
 # Added 'feh' for wallpaper support
-pacman -S --noconfirm --needed xorg-server xorg-xinit fluxbox xterm ttf-dejavu feh
+# Added 'xorg-setxkbmap' for the live keyboard layout preview
+pacman -S --noconfirm --needed xorg-server xorg-xinit xorg-setxkbmap fluxbox xterm ttf-dejavu feh
 
 # 4. Download Components
 WORK_DIR="/opt/arch-installer" # Use /opt or /root
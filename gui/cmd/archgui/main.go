@@ -1,18 +1,46 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+
+	"archgui/gui/internal/command"
+	"archgui/gui/internal/state"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/dialog"
 )
 
 func main() {
+	profile := flag.String("profile", state.DefaultProfilePath, "JSON file with preset settings")
+	engine := flag.String("engine", "", "install engine, native or script (default: the profile's, else native)")
+	flag.Parse()
+	if *engine != "" && *engine != state.EngineNative && *engine != state.EngineScript {
+		fmt.Fprintf(os.Stderr, "unknown engine %q\n", *engine)
+		os.Exit(2)
+	}
+
 	a := app.New()
 	w := a.NewWindow("Arch Linux Installer")
 	w.Resize(fyne.NewSize(1024, 768))
 	w.CenterOnScreen()
 
-	wizard := NewWizard(w)
+	// Only an explicitly named profile has to exist
+	config, err := state.LoadProfile(*profile, *profile == state.DefaultProfilePath)
+	if err != nil {
+		config = state.NewInstallConfig()
+	}
+	if *engine != "" {
+		config.Engine = *engine
+	}
+
+	wizard := NewWizard(w, config, &command.Exec{})
 	w.SetContent(wizard.Layout())
+	if err != nil {
+		dialog.ShowError(err, w)
+	}
 
 	w.ShowAndRun()
 }
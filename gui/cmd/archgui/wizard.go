@@ -1,6 +1,7 @@
 package main
 
 import (
+	"archgui/gui/internal/command"
 	"archgui/gui/internal/pages"
 	"archgui/gui/internal/state"
 
@@ -20,21 +21,28 @@ type Wizard struct {
 	backBtn *widget.Button
 
 	config *state.InstallConfig
+	runner command.Runner
 }
 
-func NewWizard(w fyne.Window) *Wizard {
+func NewWizard(w fyne.Window, config *state.InstallConfig, runner command.Runner) *Wizard {
 	wiz := &Wizard{
 		window: w,
-		config: state.NewInstallConfig(),
+		config: config,
+		runner: runner,
 	}
 
 	// Initialize pages
 	wiz.pages = []pages.Page{
 		pages.NewWelcomePage(),
+		pages.NewHardwarePage(),
+		pages.NewNetworkPage(),
+		pages.NewMirrorsPage(),
 		pages.NewStoragePage(),
 		pages.NewLocalizationPage(),
 		pages.NewAccountPage(),
 		pages.NewDesktopPage(),
+		pages.NewPackagesPage(),
+		pages.NewPreflightPage(),
 		pages.NewSummaryPage(),
 		pages.NewInstallPage(),
 	}
@@ -107,6 +115,14 @@ func (w *Wizard) SetNextButtonEnabled(enabled bool) {
 	}
 }
 
+func (w *Wizard) Window() fyne.Window {
+	return w.window
+}
+
+func (w *Wizard) Runner() command.Runner {
+	return w.runner
+}
+
 func (w *Wizard) updateView() {
 	p := w.pages[w.current]
 
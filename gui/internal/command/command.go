@@ -0,0 +1,108 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Cmd is one program invocation
+type Cmd struct {
+	Name   string
+	Args   []string
+	Stdin  string       // passwords and other input that must stay out of argv and logs
+	Output func(string) // receives stdout and stderr line by line while it runs, may be nil
+}
+
+// String is the command line for logs, arguments quoted where needed
+func (c Cmd) String() string {
+	parts := []string{c.Name}
+	for _, a := range c.Args {
+		if a == "" || strings.ContainsAny(a, " \t\n'\"$\\") {
+			a = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+		parts = append(parts, a)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Runner runs programs; stdout is returned for commands whose output is used
+type Runner interface {
+	Run(ctx context.Context, c Cmd) ([]byte, error)
+}
+
+// Exec runs commands on the host
+type Exec struct{}
+
+func (e *Exec) Run(ctx context.Context, c Cmd) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	if c.Stdin != "" {
+		cmd.Stdin = strings.NewReader(c.Stdin)
+	}
+	var stdout bytes.Buffer
+	// os/exec copies both streams concurrently: each gets its own partial
+	// line, and the shared callback is serialized
+	output := c.Output
+	if output != nil {
+		var mu sync.Mutex
+		output = func(line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			c.Output(line)
+		}
+	}
+	outLog := &lineWriter{log: output}
+	errLog := &lineWriter{log: output}
+	cmd.Stdout = &teeWriter{&stdout, outLog}
+	cmd.Stderr = errLog
+	err := cmd.Run()
+	outLog.flush()
+	errLog.flush()
+	if err != nil {
+		return stdout.Bytes(), fmt.Errorf("%s: %w", c.Name, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// lineWriter hands complete lines to log, keeping a partial last line
+type lineWriter struct {
+	log     func(string)
+	pending []byte
+}
+
+func (w *lineWriter) Write(b []byte) (int, error) {
+	if w.log == nil {
+		return len(b), nil
+	}
+	w.pending = append(w.pending, b...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		w.log(string(w.pending[:i]))
+		w.pending = w.pending[i+1:]
+	}
+	return len(b), nil
+}
+
+func (w *lineWriter) flush() {
+	if w.log != nil && len(w.pending) > 0 {
+		w.log(string(w.pending))
+		w.pending = nil
+	}
+}
+
+// teeWriter copies stdout to the buffer and the log
+type teeWriter struct {
+	buf *bytes.Buffer
+	log *lineWriter
+}
+
+func (t *teeWriter) Write(b []byte) (int, error) {
+	t.buf.Write(b)
+	return t.log.Write(b)
+}
@@ -0,0 +1,62 @@
+package command
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExec(t *testing.T) {
+	var lines []string
+	r := &Exec{}
+
+	out, err := r.Run(context.Background(), Cmd{
+		Name:   "sh",
+		Args:   []string{"-c", "cat; echo err >&2; printf last"},
+		Stdin:  "in\n",
+		Output: func(l string) { lines = append(lines, l) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "in\nlast" {
+		t.Errorf("stdout %q", out)
+	}
+	for _, want := range []string{"in", "err", "last"} {
+		if !slices.Contains(lines, want) {
+			t.Errorf("%q not logged: %q", want, lines)
+		}
+	}
+
+	if _, err := r.Run(context.Background(), Cmd{Name: "sh", Args: []string{"-c", "exit 3"}}); err == nil {
+		t.Error("expected an error for a failing command")
+	}
+}
+
+func TestExecInterleavedStreams(t *testing.T) {
+	var lines []string
+	_, err := (&Exec{}).Run(context.Background(), Cmd{
+		Name:   "sh",
+		Args:   []string{"-c", "for i in $(seq 200); do echo out$i; echo err$i >&2; done"},
+		Output: func(l string) { lines = append(lines, l) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 400 {
+		t.Fatalf("got %d lines, want 400", len(lines))
+	}
+	for _, l := range lines {
+		if !strings.HasPrefix(l, "out") && !strings.HasPrefix(l, "err") || strings.Count(l, "out")+strings.Count(l, "err") != 1 {
+			t.Errorf("streams mixed in one line: %q", l)
+		}
+	}
+}
+
+func TestCmdString(t *testing.T) {
+	c := Cmd{Name: "arch-chroot", Args: []string{"/mnt", "useradd", "-c", "Jo O'Neil", ""}}
+	if got, want := c.String(), `arch-chroot /mnt useradd -c 'Jo O'\''Neil' ''`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
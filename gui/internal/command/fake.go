@@ -0,0 +1,75 @@
+package command
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// Recorder runs nothing: it keeps every command in order for a dry-run
+// preview and answers with canned stdout where a caller needs some
+type Recorder struct {
+	Outputs map[string]string // stdout by program name, e.g. a placeholder UUID for blkid
+
+	mu   sync.Mutex
+	cmds []Cmd
+}
+
+func (r *Recorder) Run(ctx context.Context, c Cmd) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cmds = append(r.cmds, c)
+	return []byte(r.Outputs[c.Name]), nil
+}
+
+// Commands returns the recorded commands in order
+func (r *Recorder) Commands() []Cmd {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Cmd(nil), r.cmds...)
+}
+
+// Reply is a scripted answer for commands whose line starts with Prefix
+type Reply struct {
+	Prefix string // matched against Cmd.String()
+	Stdout string
+	Err    error
+}
+
+// Fake answers commands from a script of replies for tests; the first
+// matching reply wins and unmatched commands succeed with no output
+type Fake struct {
+	Replies []Reply
+
+	mu    sync.Mutex
+	calls []Cmd
+}
+
+func (f *Fake) Run(ctx context.Context, c Cmd) ([]byte, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, c)
+	f.mu.Unlock()
+	line := c.String()
+	for _, r := range f.Replies {
+		if strings.HasPrefix(line, r.Prefix) {
+			return []byte(r.Stdout), r.Err
+		}
+	}
+	return nil, nil
+}
+
+// Calls returns the commands run so far
+func (f *Fake) Calls() []Cmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Cmd(nil), f.calls...)
+}
+
+// Lines returns the command lines run so far
+func (f *Fake) Lines() []string {
+	var lines []string
+	for _, c := range f.Calls() {
+		lines = append(lines, c.String())
+	}
+	return lines
+}
@@ -0,0 +1,43 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestFake(t *testing.T) {
+	f := &Fake{Replies: []Reply{
+		{Prefix: "lsblk -d", Stdout: "disks"},
+		{Prefix: "lsblk", Stdout: "all"},
+		{Prefix: "parted", Err: errors.New("busy")},
+	}}
+	ctx := context.Background()
+	if out, _ := f.Run(ctx, Cmd{Name: "lsblk", Args: []string{"-d", "--json"}}); string(out) != "disks" {
+		t.Errorf("first match: %q", out)
+	}
+	if out, _ := f.Run(ctx, Cmd{Name: "lsblk", Args: []string{"-l"}}); string(out) != "all" {
+		t.Errorf("fallthrough: %q", out)
+	}
+	if _, err := f.Run(ctx, Cmd{Name: "parted", Args: []string{"-s", "/dev/sda"}}); err == nil {
+		t.Error("expected the scripted error")
+	}
+	if out, err := f.Run(ctx, Cmd{Name: "true"}); out != nil || err != nil {
+		t.Errorf("unmatched: %q, %v", out, err)
+	}
+	if got := f.Lines(); !slices.Equal(got, []string{"lsblk -d --json", "lsblk -l", "parted -s /dev/sda", "true"}) {
+		t.Errorf("calls %q", got)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	r := &Recorder{Outputs: map[string]string{"blkid": "UUID"}}
+	if out, _ := r.Run(context.Background(), Cmd{Name: "blkid"}); string(out) != "UUID" {
+		t.Errorf("canned output %q", out)
+	}
+	r.Run(context.Background(), Cmd{Name: "wipefs", Args: []string{"-af", "/dev/sda"}})
+	if got := r.Commands(); len(got) != 2 || got[1].String() != "wipefs -af /dev/sda" {
+		t.Errorf("recorded %v", got)
+	}
+}
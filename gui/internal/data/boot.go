@@ -0,0 +1,76 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Boot modes as understood by the backend (BOOT_MODE)
+const (
+	BootUEFI = "uefi"
+	BootBIOS = "bios"
+)
+
+// Global EFI variable GUID used by SecureBoot and SetupMode
+const efiGlobalGUID = "8be4df61-93ca-11d2-aa0d-e98c0344fc5a"
+
+// BootInfo describes the firmware the live system was booted with
+type BootInfo struct {
+	Mode         string // uefi, bios
+	FirmwareBits int    // 64 or 32 for UEFI, 0 for BIOS
+	SecureBoot   bool
+	SetupMode    bool
+}
+
+// GetBootInfo probes the running firmware via /sys/firmware/efi
+func GetBootInfo() BootInfo {
+	return readBootInfo("/sys/firmware/efi")
+}
+
+func readBootInfo(efiDir string) BootInfo {
+	// Same test as the backend: no efivars means legacy BIOS boot
+	if st, err := os.Stat(filepath.Join(efiDir, "efivars")); err != nil || !st.IsDir() {
+		return BootInfo{Mode: BootBIOS}
+	}
+
+	info := BootInfo{Mode: BootUEFI, FirmwareBits: 64}
+	if raw, err := os.ReadFile(filepath.Join(efiDir, "fw_platform_size")); err == nil {
+		if strings.TrimSpace(string(raw)) == "32" {
+			info.FirmwareBits = 32
+		}
+	}
+	info.SecureBoot = readEFIFlag(efiDir, "SecureBoot")
+	info.SetupMode = readEFIFlag(efiDir, "SetupMode")
+	return info
+}
+
+// readEFIFlag reads a one byte boolean EFI variable.
+// efivarfs prefixes the value with 4 bytes of attributes.
+func readEFIFlag(efiDir, name string) bool {
+	raw, err := os.ReadFile(filepath.Join(efiDir, "efivars", name+"-"+efiGlobalGUID))
+	if err != nil || len(raw) < 5 {
+		return false
+	}
+	return raw[4] == 1
+}
+
+// String returns a short human readable description, e.g. "UEFI (64-bit), Secure Boot off"
+func (b BootInfo) String() string {
+	if b.Mode != BootUEFI {
+		return "BIOS (Legacy)"
+	}
+	s := "UEFI (64-bit)"
+	if b.FirmwareBits == 32 {
+		s = "UEFI (32-bit)"
+	}
+	if b.SecureBoot {
+		s += ", Secure Boot on"
+	} else {
+		s += ", Secure Boot off"
+	}
+	if b.SetupMode {
+		s += ", Setup Mode"
+	}
+	return s
+}
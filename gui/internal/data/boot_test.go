@@ -0,0 +1,51 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeEFIVar(t *testing.T, efiDir, name string, value byte) {
+	t.Helper()
+	path := filepath.Join(efiDir, "efivars", name+"-"+efiGlobalGUID)
+	if err := os.WriteFile(path, []byte{0x06, 0x00, 0x00, 0x00, value}, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadBootInfo(t *testing.T) {
+	// No efivars directory -> BIOS
+	bios := readBootInfo(filepath.Join(t.TempDir(), "efi"))
+	if bios.Mode != BootBIOS || bios.FirmwareBits != 0 {
+		t.Errorf("expected BIOS, got %+v", bios)
+	}
+
+	efiDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(efiDir, "efivars"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// efivars without any extra data -> plain 64-bit UEFI
+	info := readBootInfo(efiDir)
+	if info.Mode != BootUEFI || info.FirmwareBits != 64 || info.SecureBoot || info.SetupMode {
+		t.Errorf("expected plain 64-bit UEFI, got %+v", info)
+	}
+
+	if err := os.WriteFile(filepath.Join(efiDir, "fw_platform_size"), []byte("32\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeEFIVar(t, efiDir, "SecureBoot", 1)
+	writeEFIVar(t, efiDir, "SetupMode", 0)
+
+	info = readBootInfo(efiDir)
+	if info.FirmwareBits != 32 {
+		t.Errorf("expected 32-bit firmware, got %d", info.FirmwareBits)
+	}
+	if !info.SecureBoot || info.SetupMode {
+		t.Errorf("expected Secure Boot on and Setup Mode off, got %+v", info)
+	}
+	if got := info.String(); got != "UEFI (32-bit), Secure Boot on" {
+		t.Errorf("unexpected description: %q", got)
+	}
+}
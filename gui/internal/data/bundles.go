@@ -0,0 +1,96 @@
+package data
+
+import (
+	_ "embed"
+	"encoding/json"
+	"slices"
+	"sync"
+)
+
+// Curated package sets offered on the Packages page
+//
+//go:embed bundles.json
+var bundlesJSON []byte
+
+// Bundle is one curated package set
+type Bundle struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Packages    []string `json:"packages"`
+	Multilib    bool     `json:"multilib"` // needs [multilib] (lib32 packages)
+	Services    []string `json:"services"` // systemd units enabled in the target
+}
+
+var (
+	bundlesOnce sync.Once
+	bundles     []Bundle
+)
+
+// GetBundles returns the embedded bundle list. Like the desktop catalog it
+// is checked by the tests, so a parse error panics.
+func GetBundles() []Bundle {
+	bundlesOnce.Do(func() {
+		var f struct {
+			Bundles []Bundle `json:"bundles"`
+		}
+		if err := json.Unmarshal(bundlesJSON, &f); err != nil {
+			panic("bundles.json: " + err.Error())
+		}
+		bundles = f.Bundles
+	})
+	return bundles
+}
+
+// SelectedBundles returns the bundles whose ids are listed, in catalog order
+func SelectedBundles(ids []string) []Bundle {
+	var sel []Bundle
+	for _, b := range GetBundles() {
+		if slices.Contains(ids, b.ID) {
+			sel = append(sel, b)
+		}
+	}
+	return sel
+}
+
+// BundlePackages merges the packages of the given bundles and the custom
+// list, without duplicates
+func BundlePackages(ids, custom []string) []string {
+	var pkgs []string
+	for _, b := range SelectedBundles(ids) {
+		for _, p := range b.Packages {
+			if !slices.Contains(pkgs, p) {
+				pkgs = append(pkgs, p)
+			}
+		}
+	}
+	for _, p := range custom {
+		if !slices.Contains(pkgs, p) {
+			pkgs = append(pkgs, p)
+		}
+	}
+	return pkgs
+}
+
+// BundleServices lists the units to enable for the given bundles
+func BundleServices(ids []string) []string {
+	var units []string
+	for _, b := range SelectedBundles(ids) {
+		for _, u := range b.Services {
+			if !slices.Contains(units, u) {
+				units = append(units, u)
+			}
+		}
+	}
+	return units
+}
+
+// BundlesNeedMultilib reports whether any selected bundle needs [multilib]
+func BundlesNeedMultilib(ids []string) bool {
+	for _, b := range SelectedBundles(ids) {
+		if b.Multilib {
+			return true
+		}
+	}
+	return false
+}
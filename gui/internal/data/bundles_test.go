@@ -0,0 +1,40 @@
+package data
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBundles(t *testing.T) {
+	seen := map[string]bool{}
+	for _, b := range GetBundles() {
+		if b.ID == "" || seen[b.ID] {
+			t.Errorf("empty or duplicate bundle id %q", b.ID)
+		}
+		seen[b.ID] = true
+		for _, p := range b.Packages {
+			if strings.HasPrefix(p, "lib32-") && !b.Multilib {
+				t.Errorf("%s: %s needs multilib", b.ID, p)
+			}
+		}
+	}
+	for _, id := range []string{"development", "office", "multimedia", "gaming", "virtualization", "printing"} {
+		if !seen[id] {
+			t.Errorf("bundle %s missing", id)
+		}
+	}
+}
+
+func TestBundlePackages(t *testing.T) {
+	pkgs := BundlePackages([]string{"printing", "unknown"}, []string{"cups", "htop"})
+	if pkgs[0] != "cups" || !slices.Contains(pkgs, "htop") || strings.Count(strings.Join(pkgs, " "), "cups ") != 1 {
+		t.Errorf("unexpected package list %v", pkgs)
+	}
+	if !slices.Equal(BundleServices([]string{"printing", "virtualization"}), []string{"libvirtd", "cups", "avahi-daemon"}) {
+		t.Errorf("unexpected services %v", BundleServices([]string{"printing", "virtualization"}))
+	}
+	if !BundlesNeedMultilib([]string{"office", "gaming"}) || BundlesNeedMultilib([]string{"office"}) {
+		t.Errorf("only gaming needs multilib")
+	}
+}
@@ -0,0 +1,165 @@
+package data
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// Single source for desktop packages: the Desktop page lists these entries and
+// the installer passes the resolved package list to the backend.
+//
+//go:embed desktops.json
+var desktopsJSON []byte
+
+// Session types of a desktop
+const (
+	SessionX11     = "x11"
+	SessionWayland = "wayland"
+)
+
+// Variant ids shared by all desktops that offer a choice
+const (
+	VariantMinimal = "minimal" // the session and a terminal/file manager
+	VariantFull    = "full"    // the desktop's complete application set
+)
+
+// DesktopVariant is a package set on top of a desktop's own packages
+type DesktopVariant struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Packages []string `json:"packages"`
+}
+
+// DesktopExtra is an optional package set offered for one desktop
+type DesktopExtra struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Packages []string `json:"packages"`
+}
+
+// Desktop is one entry of the catalog
+type Desktop struct {
+	ID             string           `json:"id"` // DESKTOP_ENV value
+	Name           string           `json:"name"`
+	Description    string           `json:"description"`
+	Screenshot     string           `json:"screenshot"`      // image relative to the installer directory, optional
+	Session        string           `json:"session"`         // x11, wayland
+	Packages       []string         `json:"packages"`        // installed with every variant
+	DisplayManager string           `json:"display_manager"` // systemd unit enabled in the target
+	Variants       []DesktopVariant `json:"variants"`
+	Extras         []DesktopExtra   `json:"extras"`
+}
+
+// Variant looks up a variant, falling back to the first one when the desktop
+// does not offer the requested id
+func (d Desktop) Variant(id string) (DesktopVariant, bool) {
+	for _, v := range d.Variants {
+		if v.ID == id {
+			return v, true
+		}
+	}
+	if len(d.Variants) > 0 {
+		return d.Variants[0], true
+	}
+	return DesktopVariant{}, false
+}
+
+// DesktopCatalog is the parsed desktops.json
+type DesktopCatalog struct {
+	Common          []string            `json:"common"`           // every desktop (audio)
+	SessionPackages map[string][]string `json:"session_packages"` // display server per session type
+	Desktops        []Desktop           `json:"desktops"`
+}
+
+var (
+	catalogOnce sync.Once
+	catalog     *DesktopCatalog
+)
+
+// GetDesktopCatalog returns the embedded catalog. Its content is checked by
+// the tests, so a parse error is a build mistake and panics.
+func GetDesktopCatalog() *DesktopCatalog {
+	catalogOnce.Do(func() {
+		c, err := ParseDesktopCatalog(desktopsJSON)
+		if err != nil {
+			panic(err)
+		}
+		catalog = c
+	})
+	return catalog
+}
+
+// ParseDesktopCatalog decodes and sanity checks a catalog
+func ParseDesktopCatalog(raw []byte) (*DesktopCatalog, error) {
+	var c DesktopCatalog
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, fmt.Errorf("desktop catalog: %w", err)
+	}
+	seen := map[string]bool{}
+	for _, d := range c.Desktops {
+		switch {
+		case d.ID == "" || d.ID == "none":
+			return nil, fmt.Errorf("desktop catalog: invalid id %q", d.ID)
+		case seen[d.ID]:
+			return nil, fmt.Errorf("desktop catalog: duplicate id %q", d.ID)
+		case c.SessionPackages[d.Session] == nil:
+			return nil, fmt.Errorf("desktop catalog: %s has unknown session type %q", d.ID, d.Session)
+		case len(d.Packages) == 0:
+			return nil, fmt.Errorf("desktop catalog: %s has no packages", d.ID)
+		case d.Screenshot != "" && !filepath.IsLocal(d.Screenshot):
+			return nil, fmt.Errorf("desktop catalog: %s has screenshot %q outside the installer directory", d.ID, d.Screenshot)
+		}
+		seen[d.ID] = true
+		variants := map[string]bool{}
+		for _, v := range d.Variants {
+			if v.ID == "" || variants[v.ID] {
+				return nil, fmt.Errorf("desktop catalog: %s has an empty or duplicate variant %q", d.ID, v.ID)
+			}
+			variants[v.ID] = true
+		}
+	}
+	return &c, nil
+}
+
+// Find looks up a desktop by id
+func (c *DesktopCatalog) Find(id string) (Desktop, bool) {
+	for _, d := range c.Desktops {
+		if d.ID == id {
+			return d, true
+		}
+	}
+	return Desktop{}, false
+}
+
+// Packages resolves everything to install for a desktop, its variant and the
+// chosen extras, without duplicates. Unknown ids (including "none") install nothing.
+func (c *DesktopCatalog) Packages(id, variant string, extras []string) []string {
+	d, ok := c.Find(id)
+	if !ok {
+		return nil
+	}
+	var pkgs []string
+	add := func(list []string) {
+		for _, p := range list {
+			if !slices.Contains(pkgs, p) {
+				pkgs = append(pkgs, p)
+			}
+		}
+	}
+	add(c.SessionPackages[d.Session])
+	add(c.Common)
+	add(d.Packages)
+	if v, ok := d.Variant(variant); ok {
+		add(v.Packages)
+	}
+	for _, e := range d.Extras {
+		if slices.Contains(extras, e.ID) {
+			add(e.Packages)
+		}
+	}
+	return pkgs
+}
@@ -0,0 +1,96 @@
+package data
+
+import (
+	"slices"
+	"testing"
+)
+
+// Display manager units and the package shipping each one
+var displayManagerPackages = map[string]string{
+	"gdm":            "gdm",
+	"lightdm":        "lightdm",
+	"sddm":           "sddm",
+	"cosmic-greeter": "cosmic-greeter",
+}
+
+func TestDesktopCatalogDisplayManagers(t *testing.T) {
+	c := GetDesktopCatalog()
+	if len(c.Desktops) == 0 {
+		t.Fatal("embedded catalog is empty")
+	}
+	for _, d := range c.Desktops {
+		pkg, ok := displayManagerPackages[d.DisplayManager]
+		if !ok {
+			t.Errorf("%s: unknown display manager %q", d.ID, d.DisplayManager)
+			continue
+		}
+		for _, v := range d.Variants {
+			if !slices.Contains(c.Packages(d.ID, v.ID, nil), pkg) {
+				t.Errorf("%s/%s: display manager %s is enabled but %s is not installed", d.ID, v.ID, d.DisplayManager, pkg)
+			}
+		}
+	}
+}
+
+func TestDesktopCatalogPackages(t *testing.T) {
+	c := GetDesktopCatalog()
+
+	got := c.Packages("xfce", VariantFull, []string{"nm-applet", "unknown"})
+	want := []string{
+		"xorg-server", "xorg-xinit",
+		"pipewire", "pipewire-alsa", "pipewire-pulse", "wireplumber", "pavucontrol",
+		"xfce4", "lightdm", "lightdm-gtk-greeter", "xfce4-goodies",
+		"network-manager-applet",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if pkgs := c.Packages("xfce", VariantMinimal, nil); slices.Contains(pkgs, "xfce4-goodies") {
+		t.Errorf("minimal variant should not pull the goodies: %v", pkgs)
+	}
+
+	// Unknown variants fall back to the first one
+	if got, want := c.Packages("kde", "bogus", nil), c.Packages("kde", VariantMinimal, nil); !slices.Equal(got, want) {
+		t.Errorf("fallback variant: got %v, want %v", got, want)
+	}
+
+	if pkgs := c.Packages("sway", VariantFull, nil); !slices.Contains(pkgs, "xorg-xwayland") || slices.Contains(pkgs, "xorg-server") {
+		t.Errorf("wayland session packages not applied: %v", pkgs)
+	}
+	if pkgs := c.Packages("none", VariantFull, nil); pkgs != nil {
+		t.Errorf("none should install nothing, got %v", pkgs)
+	}
+}
+
+func TestDesktopCatalogVariants(t *testing.T) {
+	// Each of these offers a minimal and a full variant
+	for _, id := range []string{"cinnamon", "mate", "lxqt", "budgie", "sway", "cosmic"} {
+		d, ok := GetDesktopCatalog().Find(id)
+		if !ok {
+			t.Errorf("%s missing from the catalog", id)
+			continue
+		}
+		for _, v := range []string{VariantMinimal, VariantFull} {
+			if got, _ := d.Variant(v); got.ID != v {
+				t.Errorf("%s: no %s variant", id, v)
+			}
+		}
+	}
+}
+
+func TestParseDesktopCatalogErrors(t *testing.T) {
+	tests := map[string]string{
+		"bad json":    `{`,
+		"duplicate":   `{"session_packages":{"x11":[]},"desktops":[{"id":"a","session":"x11","packages":["p"]},{"id":"a","session":"x11","packages":["p"]}]}`,
+		"session":     `{"session_packages":{"x11":[]},"desktops":[{"id":"a","session":"mir","packages":["p"]}]}`,
+		"no packages": `{"session_packages":{"x11":[]},"desktops":[{"id":"a","session":"x11"}]}`,
+		"variant":     `{"session_packages":{"x11":[]},"desktops":[{"id":"a","session":"x11","packages":["p"],"variants":[{"id":"full"},{"id":"full"}]}]}`,
+		"none id":     `{"session_packages":{"x11":[]},"desktops":[{"id":"none","session":"x11","packages":["p"]}]}`,
+		"screenshot":  `{"session_packages":{"x11":[]},"desktops":[{"id":"a","session":"x11","packages":["p"],"screenshot":"../../etc/shadow"}]}`,
+	}
+	for name, raw := range tests {
+		if _, err := ParseDesktopCatalog([]byte(raw)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -1,9 +1,15 @@
 package data
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"os/exec"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"archgui/gui/internal/command"
 )
 
 // Disk structure for lsblk JSON parsing
@@ -19,9 +25,8 @@ type LsblkOutput struct {
 }
 
 // GetDisks returns a list of formatted disk strings for dropdowns
-func GetDisks() []string {
-	cmd := exec.Command("lsblk", "-d", "-n", "-o", "NAME,SIZE,TYPE", "--json")
-	output, err := cmd.Output()
+func GetDisks(r command.Runner) []string {
+	output, err := r.Run(context.Background(), command.Cmd{Name: "lsblk", Args: []string{"-d", "-n", "-o", "NAME,SIZE,TYPE", "--json"}})
 	if err != nil {
 		return []string{"/dev/sda (Test)", "/dev/nmve0n1 (Test)"}
 	}
@@ -44,10 +49,9 @@ func GetDisks() []string {
 }
 
 // GetPartitions returns partitions for a given disk (or all if empty)
-func GetPartitions() []string {
+func GetPartitions(r command.Runner) []string {
 	// We want all partitions to let user select Root/EFI
-	cmd := exec.Command("lsblk", "-l", "-n", "-o", "NAME,SIZE,TYPE", "--json")
-	output, err := cmd.Output()
+	output, err := r.Run(context.Background(), command.Cmd{Name: "lsblk", Args: []string{"-l", "-n", "-o", "NAME,SIZE,TYPE", "--json"}})
 	if err != nil {
 		return []string{"/dev/sda1 (Test)", "/dev/sda2 (Test)"}
 	}
@@ -69,3 +73,25 @@ func GetPartitions() []string {
 	}
 	return parts
 }
+
+// BlockDeviceSize returns the size of a disk or partition in bytes
+func BlockDeviceSize(dev string) (int64, error) {
+	return ReadBlockDeviceSize("/sys/class/block", dev)
+}
+
+// ReadBlockDeviceSize reads the size from a /sys/class/block style directory,
+// which counts 512 byte sectors regardless of the device's sector size
+func ReadBlockDeviceSize(sysBlock, dev string) (int64, error) {
+	if dev == "" {
+		return 0, fmt.Errorf("no device selected")
+	}
+	raw, err := os.ReadFile(filepath.Join(sysBlock, filepath.Base(dev), "size"))
+	if err != nil {
+		return 0, fmt.Errorf("%s not found", dev)
+	}
+	sectors, err := strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", dev, err)
+	}
+	return sectors * 512, nil
+}
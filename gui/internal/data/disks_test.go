@@ -0,0 +1,38 @@
+package data
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"archgui/gui/internal/command"
+)
+
+func TestGetDisks(t *testing.T) {
+	r := &command.Fake{Replies: []command.Reply{
+		{Prefix: "lsblk -d", Stdout: `{"blockdevices":[
+			{"name":"nvme0n1","size":"476.9G","type":"disk"},
+			{"name":"sr0","size":"1024M","type":"rom"},
+			{"name":"sda","size":"28.7G","type":"disk"}]}`},
+		{Prefix: "lsblk -l", Stdout: `{"blockdevices":[
+			{"name":"nvme0n1","size":"476.9G","type":"disk"},
+			{"name":"nvme0n1p1","size":"512M","type":"part"},
+			{"name":"nvme0n1p2","size":"476.4G","type":"part"},
+			{"name":"cryptroot","size":"476.4G","type":"crypt"}]}`},
+	}}
+	if got := GetDisks(r); !slices.Equal(got, []string{"/dev/nvme0n1 (476.9G)", "/dev/sda (28.7G)"}) {
+		t.Errorf("disks %q", got)
+	}
+	if got := GetPartitions(r); !slices.Equal(got, []string{"/dev/nvme0n1p1 (512M)", "/dev/nvme0n1p2 (476.4G)"}) {
+		t.Errorf("partitions %q", got)
+	}
+
+	empty := &command.Fake{Replies: []command.Reply{{Prefix: "lsblk", Stdout: `{"blockdevices":[]}`}}}
+	if got := GetDisks(empty); !slices.Equal(got, []string{"No disks found"}) {
+		t.Errorf("no disks: %q", got)
+	}
+	broken := &command.Fake{Replies: []command.Reply{{Prefix: "lsblk", Err: errors.New("not found")}}}
+	if got := GetPartitions(broken); len(got) != 2 {
+		t.Errorf("fallback %q", got)
+	}
+}
@@ -0,0 +1,214 @@
+package data
+
+// Kernels offered by the installer. Anything but the stock kernel needs
+// DKMS builds of out-of-tree GPU modules.
+var Kernels = []string{"linux", "linux-lts", "linux-zen", "linux-hardened"}
+
+// NVIDIA GPU generations, oldest first
+type NvidiaArch int
+
+const (
+	NvidiaUnknown NvidiaArch = iota
+	NvidiaTesla
+	NvidiaFermi
+	NvidiaKepler
+	NvidiaMaxwell
+	NvidiaPascal
+	NvidiaVolta
+	NvidiaTuring
+	NvidiaAmpere
+	NvidiaAda
+	NvidiaBlackwell
+)
+
+// nvidiaRanges maps PCI device ID ranges to generations. NVIDIA allocates
+// IDs roughly per chip family, so ranges are good enough to pick a branch.
+var nvidiaRanges = []struct {
+	from, to uint16
+	arch     NvidiaArch
+}{
+	{0x0040, 0x06bf, NvidiaTesla},
+	{0x06c0, 0x0fbf, NvidiaFermi},
+	{0x0fc0, 0x103f, NvidiaKepler},
+	{0x1040, 0x10ff, NvidiaFermi},
+	{0x1140, 0x117f, NvidiaFermi},
+	{0x1180, 0x12ff, NvidiaKepler},
+	{0x1340, 0x17ff, NvidiaMaxwell},
+	{0x15f0, 0x15ff, NvidiaPascal},
+	{0x1b00, 0x1d7f, NvidiaPascal},
+	{0x1d80, 0x1dff, NvidiaVolta},
+	{0x1e00, 0x1fff, NvidiaTuring},
+	{0x20b0, 0x20ff, NvidiaAmpere},
+	{0x2180, 0x21ff, NvidiaTuring},
+	{0x2200, 0x25ff, NvidiaAmpere},
+	{0x2600, 0x28ff, NvidiaAda},
+	{0x2900, 0x2fff, NvidiaBlackwell},
+}
+
+// NvidiaArchFor returns the generation of an NVIDIA device ID
+func NvidiaArchFor(deviceID uint16) NvidiaArch {
+	// Later entries are more specific (e.g. GP100 inside the Maxwell block)
+	arch := NvidiaUnknown
+	for _, r := range nvidiaRanges {
+		if deviceID >= r.from && deviceID <= r.to {
+			arch = r.arch
+		}
+	}
+	return arch
+}
+
+// NvidiaDriver describes one NVIDIA driver branch
+type NvidiaDriver struct {
+	Package  string // kernel module package
+	Utils    string // userspace, also used for the lib32 variant
+	Settings string
+	Label    string
+	DKMS     bool // works with any kernel, needs headers
+	AUR      bool // not in the official repos
+}
+
+// NvidiaDrivers lists the selectable branches. "nouveau" means no
+// proprietary driver, only mesa.
+var NvidiaDrivers = []NvidiaDriver{
+	{Package: "nvidia-open", Utils: "nvidia-utils", Settings: "nvidia-settings", Label: "Open kernel modules (Turing and newer)"},
+	{Package: "nvidia-open-dkms", Utils: "nvidia-utils", Settings: "nvidia-settings", Label: "Open kernel modules, DKMS", DKMS: true},
+	{Package: "nvidia", Utils: "nvidia-utils", Settings: "nvidia-settings", Label: "Proprietary"},
+	{Package: "nvidia-dkms", Utils: "nvidia-utils", Settings: "nvidia-settings", Label: "Proprietary, DKMS", DKMS: true},
+	{Package: "nvidia-580xx-dkms", Utils: "nvidia-580xx-utils", Settings: "nvidia-580xx-settings", Label: "Legacy 580xx (Maxwell, Pascal, Volta)", DKMS: true, AUR: true},
+	{Package: "nvidia-470xx-dkms", Utils: "nvidia-470xx-utils", Settings: "nvidia-470xx-settings", Label: "Legacy 470xx (Kepler)", DKMS: true, AUR: true},
+	{Package: "nvidia-390xx-dkms", Utils: "nvidia-390xx-utils", Settings: "nvidia-390xx-settings", Label: "Legacy 390xx (Fermi)", DKMS: true, AUR: true},
+	{Package: "nouveau", Label: "Open source nouveau (mesa)"},
+}
+
+// FindNvidiaDriver looks up a branch by package name
+func FindNvidiaDriver(pkg string) (NvidiaDriver, bool) {
+	for _, d := range NvidiaDrivers {
+		if d.Package == pkg {
+			return d, true
+		}
+	}
+	return NvidiaDriver{}, false
+}
+
+// NvidiaDriverOptions returns the package names usable with a kernel.
+// Prebuilt modules only exist for the stock kernel.
+func NvidiaDriverOptions(kernel string) []string {
+	var opts []string
+	for _, d := range NvidiaDrivers {
+		if kernel != "linux" && !d.DKMS && d.Package != "nouveau" {
+			continue
+		}
+		opts = append(opts, d.Package)
+	}
+	return opts
+}
+
+// NvidiaDriverForKernel maps a prebuilt branch to its DKMS variant when a
+// non-stock kernel is selected
+func NvidiaDriverForKernel(pkg, kernel string) string {
+	if kernel == "linux" {
+		return pkg
+	}
+	switch pkg {
+	case "nvidia-open":
+		return "nvidia-open-dkms"
+	case "nvidia":
+		return "nvidia-dkms"
+	}
+	return pkg
+}
+
+// RecommendNvidiaDriver picks the branch supporting the given device
+func RecommendNvidiaDriver(deviceID uint16, kernel string) string {
+	var pkg string
+	switch arch := NvidiaArchFor(deviceID); {
+	case arch >= NvidiaTuring:
+		pkg = "nvidia-open"
+	case arch >= NvidiaMaxwell:
+		pkg = "nvidia-580xx-dkms"
+	case arch == NvidiaKepler:
+		pkg = "nvidia-470xx-dkms"
+	case arch == NvidiaFermi:
+		pkg = "nvidia-390xx-dkms"
+	case arch == NvidiaUnknown:
+		// Newer than our table, the current branch is the best guess
+		if deviceID > 0x2fff {
+			pkg = "nvidia-open"
+		} else {
+			pkg = "nouveau"
+		}
+	default:
+		pkg = "nouveau"
+	}
+	return NvidiaDriverForKernel(pkg, kernel)
+}
+
+// GPUPlan is everything the backend needs for the selected graphics stack
+type GPUPlan struct {
+	Packages      []string // official repos, installed with pacstrap
+	AURPackages   []string // need an AUR helper
+	KernelParams  []string
+	Modules       []string // early loaded via mkinitcpio MODULES
+	RemoveKMSHook bool     // keep nouveau out of the initramfs
+}
+
+// PlanGPUDrivers builds the driver package set for the selected GPUs
+func PlanGPUDrivers(intel, amd bool, nvidia, kernel string, multilib bool) GPUPlan {
+	var plan GPUPlan
+	add := func(pkgs ...string) { plan.Packages = append(plan.Packages, pkgs...) }
+
+	if intel || amd || nvidia != "" {
+		add("mesa")
+		if multilib {
+			add("lib32-mesa")
+		}
+	}
+	if intel {
+		add("vulkan-intel", "intel-media-driver")
+		if multilib {
+			add("lib32-vulkan-intel")
+		}
+	}
+	if amd {
+		add("vulkan-radeon")
+		if multilib {
+			add("lib32-vulkan-radeon")
+		}
+	}
+
+	drv, ok := FindNvidiaDriver(nvidia)
+	if !ok || drv.Package == "nouveau" {
+		return plan
+	}
+
+	nvPkgs := []string{drv.Package, drv.Utils, drv.Settings}
+	if multilib {
+		nvPkgs = append(nvPkgs, "lib32-"+drv.Utils)
+	}
+	if drv.AUR {
+		plan.AURPackages = append(plan.AURPackages, nvPkgs...)
+	} else {
+		add(nvPkgs...)
+	}
+	if drv.DKMS {
+		add(kernel + "-headers")
+	}
+	if intel || amd {
+		// Hybrid graphics: render offload via prime-run
+		add("nvidia-prime")
+	}
+
+	// The initramfs is rebuilt before the AUR step, when AUR drivers are not
+	// installed yet (or never, without a helper): mkinitcpio would fail on
+	// the missing modules, so they keep nouveau until set up after boot
+	if drv.AUR {
+		return plan
+	}
+	plan.KernelParams = []string{"nvidia_drm.modeset=1"}
+	if drv.Package != "nvidia-470xx-dkms" && drv.Package != "nvidia-390xx-dkms" {
+		plan.KernelParams = append(plan.KernelParams, "nvidia_drm.fbdev=1")
+	}
+	plan.Modules = []string{"nvidia", "nvidia_modeset", "nvidia_uvm", "nvidia_drm"}
+	plan.RemoveKMSHook = true
+	return plan
+}
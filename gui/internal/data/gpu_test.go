@@ -0,0 +1,91 @@
+package data
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRecommendNvidiaDriver(t *testing.T) {
+	tests := []struct {
+		name     string
+		deviceID uint16
+		kernel   string
+		want     string
+	}{
+		{"RTX 3050 Mobile", 0x25a0, "linux", "nvidia-open"},
+		{"RTX 4090 on lts", 0x2684, "linux-lts", "nvidia-open-dkms"},
+		{"GTX 1650 (TU117)", 0x1f82, "linux", "nvidia-open"},
+		{"GTX 1080 (Pascal)", 0x1b80, "linux", "nvidia-580xx-dkms"},
+		{"GTX 970 (Maxwell)", 0x13c2, "linux-zen", "nvidia-580xx-dkms"},
+		{"Tesla P100 (GP100)", 0x15f8, "linux", "nvidia-580xx-dkms"},
+		{"GTX 780 (Kepler)", 0x1004, "linux", "nvidia-470xx-dkms"},
+		{"GTX 580 (Fermi)", 0x1080, "linux", "nvidia-390xx-dkms"},
+		{"GeForce 8800 (Tesla)", 0x0193, "linux", "nouveau"},
+		{"Unknown future card", 0x3100, "linux", "nvidia-open"},
+	}
+	for _, tt := range tests {
+		if got := RecommendNvidiaDriver(tt.deviceID, tt.kernel); got != tt.want {
+			t.Errorf("%s (%#04x, %s): got %s, want %s", tt.name, tt.deviceID, tt.kernel, got, tt.want)
+		}
+	}
+}
+
+func TestNvidiaDriverOptions(t *testing.T) {
+	stock := NvidiaDriverOptions("linux")
+	if !slices.Contains(stock, "nvidia-open") || !slices.Contains(stock, "nvidia-dkms") {
+		t.Errorf("stock kernel should offer prebuilt and DKMS drivers, got %v", stock)
+	}
+	for _, pkg := range NvidiaDriverOptions("linux-lts") {
+		drv, _ := FindNvidiaDriver(pkg)
+		if !drv.DKMS && pkg != "nouveau" {
+			t.Errorf("linux-lts must not offer prebuilt module %s", pkg)
+		}
+	}
+}
+
+func TestPlanGPUDrivers(t *testing.T) {
+	// Hybrid Intel + NVIDIA on linux-zen with multilib
+	plan := PlanGPUDrivers(true, false, "nvidia-open-dkms", "linux-zen", true)
+	for _, pkg := range []string{"mesa", "lib32-mesa", "vulkan-intel", "intel-media-driver", "lib32-vulkan-intel",
+		"nvidia-open-dkms", "nvidia-utils", "lib32-nvidia-utils", "linux-zen-headers", "nvidia-prime"} {
+		if !slices.Contains(plan.Packages, pkg) {
+			t.Errorf("hybrid plan missing %s: %v", pkg, plan.Packages)
+		}
+	}
+	if !slices.Contains(plan.KernelParams, "nvidia_drm.modeset=1") || !plan.RemoveKMSHook {
+		t.Errorf("NVIDIA plan should set modeset and drop the kms hook: %+v", plan)
+	}
+	if !slices.Equal(plan.Modules, []string{"nvidia", "nvidia_modeset", "nvidia_uvm", "nvidia_drm"}) {
+		t.Errorf("unexpected initramfs modules: %v", plan.Modules)
+	}
+
+	// AMD only, no multilib: no NVIDIA bits at all
+	plan = PlanGPUDrivers(false, true, "", "linux", false)
+	if !slices.Equal(plan.Packages, []string{"mesa", "vulkan-radeon"}) {
+		t.Errorf("unexpected AMD packages: %v", plan.Packages)
+	}
+	if len(plan.KernelParams) != 0 || len(plan.Modules) != 0 || plan.RemoveKMSHook {
+		t.Errorf("AMD plan should not touch kernel params or initramfs: %+v", plan)
+	}
+
+	// Legacy branches come from the AUR, only headers from the repos
+	plan = PlanGPUDrivers(false, false, "nvidia-470xx-dkms", "linux", false)
+	if !slices.Equal(plan.AURPackages, []string{"nvidia-470xx-dkms", "nvidia-470xx-utils", "nvidia-470xx-settings"}) {
+		t.Errorf("unexpected AUR packages: %v", plan.AURPackages)
+	}
+	if !slices.Contains(plan.Packages, "linux-headers") || slices.Contains(plan.KernelParams, "nvidia_drm.fbdev=1") {
+		t.Errorf("unexpected legacy plan: %+v", plan)
+	}
+
+	// GTX 1080 (Pascal) gets the AUR-only 580xx branch. With AUR helper
+	// "none" it is never installed, and with a helper only after the
+	// initramfs is built, so nothing may reference its modules.
+	drv := RecommendNvidiaDriver(0x1b80, "linux")
+	if drv != "nvidia-580xx-dkms" {
+		t.Fatalf("Pascal recommendation: %s", drv)
+	}
+	plan = PlanGPUDrivers(false, false, drv, "linux", false)
+	if len(plan.AURPackages) == 0 || len(plan.Modules) != 0 || len(plan.KernelParams) != 0 || plan.RemoveKMSHook {
+		t.Errorf("AUR driver plan must leave the initramfs and kernel params alone: %+v", plan)
+	}
+}
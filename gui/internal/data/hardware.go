@@ -0,0 +1,217 @@
+package data
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PCI vendor IDs we care about
+const (
+	VendorIntel  = 0x8086
+	VendorAMD    = 0x1002
+	VendorNvidia = 0x10de
+)
+
+// PCIDevice is a single entry from /sys/bus/pci/devices
+type PCIDevice struct {
+	Slot     string
+	VendorID uint16
+	DeviceID uint16
+	Class    uint32 // 24-bit class code, e.g. 0x030000 for VGA
+}
+
+// IsGPU reports whether the device is a display controller (class 0x03)
+func (d PCIDevice) IsGPU() bool {
+	return d.Class>>16 == 0x03
+}
+
+// IsWireless reports whether the device is a wireless network controller (class 0x0280)
+func (d PCIDevice) IsWireless() bool {
+	return d.Class>>8 == 0x0280
+}
+
+// VendorName returns a short vendor name for display
+func (d PCIDevice) VendorName() string {
+	switch d.VendorID {
+	case VendorIntel:
+		return "Intel"
+	case VendorAMD:
+		return "AMD"
+	case VendorNvidia:
+		return "NVIDIA"
+	}
+	return "Other"
+}
+
+// Hardware is the result of probing the live system
+type Hardware struct {
+	CPUVendor string // GenuineIntel, AuthenticAMD
+	CPUModel  string
+	GPUs      []PCIDevice
+	WiFi      bool
+	Bluetooth bool
+	Battery   bool
+	Chassis   int // SMBIOS chassis type, 0 if unknown
+}
+
+// GetHardware probes the running system
+func GetHardware() Hardware {
+	return ProbeHardware("/")
+}
+
+// ProbeHardware reads /sys and /proc below root (used with fixture trees in tests)
+func ProbeHardware(root string) Hardware {
+	var hw Hardware
+
+	hw.CPUVendor, hw.CPUModel = readCPUInfo(filepath.Join(root, "proc/cpuinfo"))
+
+	for _, dev := range readPCIDevices(filepath.Join(root, "sys/bus/pci/devices")) {
+		if dev.IsGPU() {
+			hw.GPUs = append(hw.GPUs, dev)
+		}
+		if dev.IsWireless() {
+			hw.WiFi = true
+		}
+	}
+
+	// rfkill also covers USB and SDIO radios that are not on the PCI bus
+	for _, t := range readClassAttr(filepath.Join(root, "sys/class/rfkill"), "type") {
+		switch t {
+		case "wlan":
+			hw.WiFi = true
+		case "bluetooth":
+			hw.Bluetooth = true
+		}
+	}
+	if entries, err := os.ReadDir(filepath.Join(root, "sys/class/bluetooth")); err == nil && len(entries) > 0 {
+		hw.Bluetooth = true
+	}
+
+	for _, t := range readClassAttr(filepath.Join(root, "sys/class/power_supply"), "type") {
+		if t == "Battery" {
+			hw.Battery = true
+		}
+	}
+
+	if raw, err := os.ReadFile(filepath.Join(root, "sys/class/dmi/id/chassis_type")); err == nil {
+		hw.Chassis, _ = strconv.Atoi(strings.TrimSpace(string(raw)))
+	}
+
+	return hw
+}
+
+// Laptop reports whether this looks like a portable machine
+func (h Hardware) Laptop() bool {
+	switch h.Chassis {
+	case 8, 9, 10, 11, 14, 30, 31, 32: // Portable, Laptop, Notebook, Hand Held, Sub Notebook, Tablet, Convertible, Detachable
+		return true
+	}
+	return h.Battery
+}
+
+// HasGPU reports whether a GPU from the given PCI vendor is present
+func (h Hardware) HasGPU(vendor uint16) bool {
+	for _, g := range h.GPUs {
+		if g.VendorID == vendor {
+			return true
+		}
+	}
+	return false
+}
+
+// Hybrid reports an integrated + NVIDIA setup that needs PRIME offloading
+func (h Hardware) Hybrid() bool {
+	return h.HasGPU(VendorNvidia) && (h.HasGPU(VendorIntel) || h.HasGPU(VendorAMD))
+}
+
+// Microcode returns the microcode package for the CPU, or "" if none applies
+func (h Hardware) Microcode() string {
+	switch h.CPUVendor {
+	case "GenuineIntel":
+		return "intel-ucode"
+	case "AuthenticAMD":
+		return "amd-ucode"
+	}
+	return ""
+}
+
+func readCPUInfo(path string) (vendor, model string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, val, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "vendor_id":
+			vendor = strings.TrimSpace(val)
+		case "model name":
+			model = strings.TrimSpace(val)
+		}
+		if vendor != "" && model != "" {
+			break
+		}
+	}
+	return vendor, model
+}
+
+func readPCIDevices(dir string) []PCIDevice {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var devs []PCIDevice
+	for _, e := range entries {
+		base := filepath.Join(dir, e.Name())
+		vendor, err1 := readHexFile(filepath.Join(base, "vendor"))
+		device, err2 := readHexFile(filepath.Join(base, "device"))
+		class, err3 := readHexFile(filepath.Join(base, "class"))
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		devs = append(devs, PCIDevice{
+			Slot:     e.Name(),
+			VendorID: uint16(vendor),
+			DeviceID: uint16(device),
+			Class:    uint32(class),
+		})
+	}
+	sort.Slice(devs, func(i, j int) bool { return devs[i].Slot < devs[j].Slot })
+	return devs
+}
+
+func readHexFile(path string) (uint64, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(string(raw)), "0x"), 16, 32)
+}
+
+// readClassAttr returns one attribute of every device in a /sys/class directory
+func readClassAttr(dir, attr string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var vals []string
+	for _, e := range entries {
+		raw, err := os.ReadFile(filepath.Join(dir, e.Name(), attr))
+		if err != nil {
+			continue
+		}
+		vals = append(vals, strings.TrimSpace(string(raw)))
+	}
+	return vals
+}
@@ -0,0 +1,109 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTree creates a fixture tree of small sysfs/procfs style files below a temp dir
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func pciFiles(slot, vendor, device, class string) map[string]string {
+	base := "sys/bus/pci/devices/" + slot + "/"
+	return map[string]string{
+		base + "vendor": vendor + "\n",
+		base + "device": device + "\n",
+		base + "class":  class + "\n",
+	}
+}
+
+func merge(maps ...map[string]string) map[string]string {
+	out := map[string]string{}
+	for _, m := range maps {
+		for k, v := range m {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func TestProbeHardwareHybridLaptop(t *testing.T) {
+	root := writeTree(t, merge(
+		map[string]string{
+			"proc/cpuinfo":                     "processor\t: 0\nvendor_id\t: GenuineIntel\nmodel name\t: Intel(R) Core(TM) i7-12700H\n",
+			"sys/class/dmi/id/chassis_type":    "10\n",
+			"sys/class/power_supply/AC/type":   "Mains\n",
+			"sys/class/power_supply/BAT0/type": "Battery\n",
+			"sys/class/rfkill/rfkill0/type":    "bluetooth\n",
+		},
+		pciFiles("0000:00:02.0", "0x8086", "0x46a6", "0x030000"), // Alder Lake iGPU
+		pciFiles("0000:01:00.0", "0x10de", "0x25a0", "0x030200"), // RTX 3050 Mobile (3D controller)
+		pciFiles("0000:00:14.3", "0x8086", "0x51f0", "0x028000"), // Wi-Fi 6
+		pciFiles("0000:00:1f.3", "0x8086", "0x51c8", "0x040380"), // Audio, ignored
+	))
+
+	hw := ProbeHardware(root)
+
+	if hw.CPUVendor != "GenuineIntel" || hw.Microcode() != "intel-ucode" {
+		t.Errorf("unexpected CPU detection: %q / %q", hw.CPUVendor, hw.Microcode())
+	}
+	if hw.CPUModel != "Intel(R) Core(TM) i7-12700H" {
+		t.Errorf("unexpected CPU model: %q", hw.CPUModel)
+	}
+	if len(hw.GPUs) != 2 {
+		t.Fatalf("expected 2 GPUs, got %+v", hw.GPUs)
+	}
+	if !hw.Hybrid() || !hw.HasGPU(VendorNvidia) || !hw.HasGPU(VendorIntel) || hw.HasGPU(VendorAMD) {
+		t.Errorf("expected hybrid Intel+NVIDIA, got %+v", hw.GPUs)
+	}
+	if hw.GPUs[1].DeviceID != 0x25a0 {
+		t.Errorf("expected NVIDIA device ID 0x25a0, got %#x", hw.GPUs[1].DeviceID)
+	}
+	if !hw.WiFi || !hw.Bluetooth || !hw.Battery || !hw.Laptop() {
+		t.Errorf("expected Wi-Fi, Bluetooth, battery and laptop, got %+v", hw)
+	}
+}
+
+func TestProbeHardwareDesktop(t *testing.T) {
+	root := writeTree(t, merge(
+		map[string]string{
+			"proc/cpuinfo":                  "processor\t: 0\nvendor_id\t: AuthenticAMD\nmodel name\t: AMD Ryzen 7 5800X\n",
+			"sys/class/dmi/id/chassis_type": "3\n",
+		},
+		pciFiles("0000:0a:00.0", "0x1002", "0x73bf", "0x030000"), // RX 6800
+	))
+
+	hw := ProbeHardware(root)
+
+	if hw.Microcode() != "amd-ucode" {
+		t.Errorf("expected amd-ucode, got %q", hw.Microcode())
+	}
+	if hw.Hybrid() || !hw.HasGPU(VendorAMD) {
+		t.Errorf("expected a single AMD GPU, got %+v", hw.GPUs)
+	}
+	if hw.WiFi || hw.Bluetooth || hw.Battery || hw.Laptop() {
+		t.Errorf("expected a plain desktop, got %+v", hw)
+	}
+}
+
+func TestProbeHardwareEmpty(t *testing.T) {
+	// Missing files (containers, VMs without DMI) must not break probing
+	hw := ProbeHardware(t.TempDir())
+	if hw.Microcode() != "" || len(hw.GPUs) != 0 || hw.Laptop() {
+		t.Errorf("expected empty result, got %+v", hw)
+	}
+}
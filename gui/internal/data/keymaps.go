@@ -0,0 +1,195 @@
+package data
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	kbdKeymapsDir = "/usr/share/kbd/keymaps"
+	xkbBaseList   = "/usr/share/X11/xkb/rules/base.lst"
+)
+
+// XkbItem is one line of base.lst. Parent is set for variants (the layout).
+type XkbItem struct {
+	Name        string
+	Description string
+	Parent      string
+}
+
+// XkbList holds the four sections of base.lst
+type XkbList struct {
+	Models   []XkbItem
+	Layouts  []XkbItem
+	Variants []XkbItem
+	Options  []XkbItem
+}
+
+// GetConsoleKeymaps returns the console keymaps available on the live system
+func GetConsoleKeymaps() []string {
+	keymaps, err := ListConsoleKeymaps(kbdKeymapsDir)
+	if err != nil || len(keymaps) == 0 {
+		return []string{"us"}
+	}
+	return keymaps
+}
+
+// ListConsoleKeymaps walks a kbd keymaps tree and returns the names loadkeys accepts
+func ListConsoleKeymaps(dir string) ([]string, error) {
+	seen := map[string]bool{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == "include" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := d.Name()
+		for _, ext := range []string{".map.gz", ".map"} {
+			if strings.HasSuffix(name, ext) {
+				seen[strings.TrimSuffix(name, ext)] = true
+			}
+		}
+		return nil
+	})
+
+	keymaps := make([]string, 0, len(seen))
+	for k := range seen {
+		keymaps = append(keymaps, k)
+	}
+	sort.Strings(keymaps)
+	return keymaps, err
+}
+
+// GetXkbList returns the X11 keyboard models, layouts, variants and options
+func GetXkbList() XkbList {
+	list, err := ParseXkbList(xkbBaseList)
+	if err != nil || len(list.Layouts) == 0 {
+		return XkbList{
+			Models:  []XkbItem{{Name: "pc105", Description: "Generic 105-key PC"}},
+			Layouts: []XkbItem{{Name: "us", Description: "English (US)"}},
+		}
+	}
+	return list
+}
+
+// ParseXkbList parses an xkeyboard-config .lst file
+func ParseXkbList(path string) (XkbList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return XkbList{}, err
+	}
+	defer f.Close()
+
+	var list XkbList
+	var section string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "! ") {
+			section = strings.TrimPrefix(line, "! ")
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		item := XkbItem{Name: fields[0], Description: strings.Join(fields[1:], " ")}
+
+		switch section {
+		case "model":
+			list.Models = append(list.Models, item)
+		case "layout":
+			list.Layouts = append(list.Layouts, item)
+		case "variant":
+			// "nodeadkeys      de: German (no dead keys)"
+			parent, desc, ok := strings.Cut(item.Description, ": ")
+			if !ok {
+				continue
+			}
+			item.Parent, item.Description = parent, desc
+			list.Variants = append(list.Variants, item)
+		case "option":
+			// Group headers ("grp  Switching to another layout") are not options
+			if !strings.Contains(item.Name, ":") {
+				continue
+			}
+			list.Options = append(list.Options, item)
+		}
+	}
+	return list, scanner.Err()
+}
+
+// VariantsFor returns the variants of one layout
+func (l XkbList) VariantsFor(layout string) []XkbItem {
+	var out []XkbItem
+	for _, v := range l.Variants {
+		if v.Parent == layout {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// HasOption reports whether name is a known XKB option
+func (l XkbList) HasOption(name string) bool {
+	for _, o := range l.Options {
+		if o.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Console keymaps whose name differs from the X11 layout
+var consoleAliases = map[string]string{
+	"se":    "sv-latin1",
+	"gb":    "uk",
+	"ch":    "sg",
+	"latam": "la-latin1",
+}
+
+// SuggestConsoleKeymap picks the console keymap closest to an X11 layout/variant
+func SuggestConsoleKeymap(keymaps []string, layout, variant string) string {
+	candidates := []string{}
+	if variant != "" {
+		candidates = append(candidates, layout+"-latin1-"+variant, layout+"-"+variant)
+	}
+	if alias, ok := consoleAliases[layout]; ok {
+		candidates = append(candidates, alias)
+	}
+	candidates = append(candidates, layout+"-latin1", layout)
+
+	for _, c := range candidates {
+		for _, k := range keymaps {
+			if k == c {
+				return c
+			}
+		}
+	}
+	return ""
+}
+
+// X11KeyboardConf renders /etc/X11/xorg.conf.d/00-keyboard.conf the way
+// localectl writes it
+func X11KeyboardConf(layout, model, variant, options string) string {
+	var b strings.Builder
+	b.WriteString("# Written by the Arch Linux GUI installer\n")
+	b.WriteString("Section \"InputClass\"\n")
+	b.WriteString("        Identifier \"system-keyboard\"\n")
+	b.WriteString("        MatchIsKeyboard \"on\"\n")
+	for _, kv := range [][2]string{{"XkbLayout", layout}, {"XkbModel", model}, {"XkbVariant", variant}, {"XkbOptions", options}} {
+		if kv[1] != "" {
+			b.WriteString("        Option \"" + kv[0] + "\" \"" + kv[1] + "\"\n")
+		}
+	}
+	b.WriteString("EndSection\n")
+	return b.String()
+}
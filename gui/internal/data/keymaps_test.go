@@ -0,0 +1,85 @@
+package data
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestListConsoleKeymaps(t *testing.T) {
+	keymaps, err := ListConsoleKeymaps("testdata/kbd/keymaps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"de", "de-latin1", "de-latin1-nodeadkeys", "dk-latin1", "dvorak", "fi", "no-latin1", "sv-latin1", "uk", "us"}
+	if !slices.Equal(keymaps, want) {
+		t.Errorf("got %v, want %v", keymaps, want)
+	}
+}
+
+func TestParseXkbList(t *testing.T) {
+	list, err := ParseXkbList("testdata/base.lst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Models) != 3 || len(list.Layouts) != 6 {
+		t.Errorf("expected 3 models and 6 layouts, got %d and %d", len(list.Models), len(list.Layouts))
+	}
+	if list.Layouts[3] != (XkbItem{Name: "de", Description: "German"}) {
+		t.Errorf("unexpected layout entry: %+v", list.Layouts[3])
+	}
+
+	de := list.VariantsFor("de")
+	if len(de) != 4 || de[0] != (XkbItem{Name: "nodeadkeys", Description: "German (no dead keys)", Parent: "de"}) {
+		t.Errorf("unexpected German variants: %+v", de)
+	}
+	if len(list.VariantsFor("fi")) != 0 {
+		t.Errorf("fi has no variants in the fixture")
+	}
+
+	// Group headers are skipped
+	if len(list.Options) != 4 || !list.HasOption("caps:escape") || list.HasOption("grp") {
+		t.Errorf("unexpected options: %+v", list.Options)
+	}
+}
+
+func TestSuggestConsoleKeymap(t *testing.T) {
+	keymaps, _ := ListConsoleKeymaps("testdata/kbd/keymaps")
+	tests := []struct{ layout, variant, want string }{
+		{"de", "nodeadkeys", "de-latin1-nodeadkeys"},
+		{"de", "", "de-latin1"},
+		{"no", "", "no-latin1"},
+		{"se", "", "sv-latin1"},
+		{"dk", "", "dk-latin1"},
+		{"fi", "", "fi"},
+		{"us", "dvorak", "us"},
+		{"jp", "", ""},
+	}
+	for _, tt := range tests {
+		if got := SuggestConsoleKeymap(keymaps, tt.layout, tt.variant); got != tt.want {
+			t.Errorf("%s/%s: got %q, want %q", tt.layout, tt.variant, got, tt.want)
+		}
+	}
+}
+
+func TestX11KeyboardConf(t *testing.T) {
+	got := X11KeyboardConf("de", "pc105", "nodeadkeys", "caps:escape")
+	want := `# Written by the Arch Linux GUI installer
+Section "InputClass"
+        Identifier "system-keyboard"
+        MatchIsKeyboard "on"
+        Option "XkbLayout" "de"
+        Option "XkbModel" "pc105"
+        Option "XkbVariant" "nodeadkeys"
+        Option "XkbOptions" "caps:escape"
+EndSection
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Empty values are left out instead of written as ""
+	if got := X11KeyboardConf("us", "", "", ""); strings.Contains(got, "XkbVariant") || strings.Contains(got, `""`) {
+		t.Errorf("unexpected output for plain us layout:\n%s", got)
+	}
+}
@@ -0,0 +1,125 @@
+package data
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+const supportedLocalesPath = "/usr/share/i18n/SUPPORTED"
+
+// LCCategories are the locale.conf overrides offered next to LANG
+var LCCategories = []string{
+	"LC_ADDRESS", "LC_COLLATE", "LC_CTYPE", "LC_IDENTIFICATION", "LC_MEASUREMENT", "LC_MESSAGES",
+	"LC_MONETARY", "LC_NAME", "LC_NUMERIC", "LC_PAPER", "LC_TELEPHONE", "LC_TIME",
+}
+
+// Locale is one entry of the SUPPORTED list, e.g. {"de_DE@euro", "ISO-8859-15"}
+type Locale struct {
+	Name    string // value for LANG / LC_*
+	Charset string
+}
+
+// String returns the line as it appears in locale.gen
+func (l Locale) String() string {
+	return l.Name + " " + l.Charset
+}
+
+// GetLocales returns the locales glibc can generate on the live system
+func GetLocales() []Locale {
+	locales, err := ListLocales(supportedLocalesPath)
+	if err != nil || len(locales) == 0 {
+		return []Locale{{Name: "en_US.UTF-8", Charset: "UTF-8"}}
+	}
+	return locales
+}
+
+// ListLocales parses a SUPPORTED file. Both the Arch layout ("name charset")
+// and the glibc source layout ("name/charset \") are accepted.
+func ListLocales(path string) ([]Locale, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var locales []Locale
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\\"))
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "SUPPORTED-LOCALES=") {
+			continue
+		}
+		name, charset, ok := strings.Cut(line, " ")
+		if !ok {
+			name, charset, ok = strings.Cut(line, "/")
+		}
+		if !ok {
+			continue
+		}
+		locales = append(locales, Locale{Name: name, Charset: strings.TrimSpace(charset)})
+	}
+	return locales, scanner.Err()
+}
+
+// FindLocale looks up a locale by name
+func FindLocale(locales []Locale, name string) (Locale, bool) {
+	for _, l := range locales {
+		if l.Name == name {
+			return l, true
+		}
+	}
+	return Locale{}, false
+}
+
+// ResolveLocales maps names to SUPPORTED entries, dropping duplicates.
+// Names missing from the list are kept only if the charset is obvious (.UTF-8).
+func ResolveLocales(supported []Locale, names []string) []Locale {
+	var out []Locale
+	seen := map[string]bool{}
+	for _, name := range names {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if l, ok := FindLocale(supported, name); ok {
+			out = append(out, l)
+		} else if strings.HasSuffix(name, ".UTF-8") {
+			out = append(out, Locale{Name: name, Charset: "UTF-8"})
+		}
+	}
+	return out
+}
+
+// LocaleNames returns just the names, for pickers
+func LocaleNames(locales []Locale) []string {
+	names := make([]string, len(locales))
+	for i, l := range locales {
+		names[i] = l.Name
+	}
+	return names
+}
+
+// LocaleGen renders /etc/locale.gen for the given locales
+func LocaleGen(locales []Locale) string {
+	var b strings.Builder
+	b.WriteString("# Generated by the Arch Linux GUI installer\n")
+	for _, l := range locales {
+		b.WriteString(l.String() + "\n")
+	}
+	return b.String()
+}
+
+// LocaleConf renders /etc/locale.conf: LANG followed by any LC_* overrides
+// in a stable order. Overrides equal to LANG are left out.
+func LocaleConf(lang string, overrides map[string]string) string {
+	var b strings.Builder
+	b.WriteString("LANG=" + lang + "\n")
+	for _, cat := range LCCategories {
+		if v := overrides[cat]; v != "" && v != lang {
+			b.WriteString(cat + "=" + v + "\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package data
+
+import (
+	"testing"
+)
+
+func TestListLocales(t *testing.T) {
+	locales, err := ListLocales("testdata/SUPPORTED")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locales) != 17 {
+		t.Errorf("expected 17 locales, got %d", len(locales))
+	}
+	euro, ok := FindLocale(locales, "de_DE@euro")
+	if !ok || euro.Charset != "ISO-8859-15" {
+		t.Errorf("de_DE@euro not parsed correctly: %+v", euro)
+	}
+	if _, ok := FindLocale(locales, "de_DE.UTF8"); ok {
+		t.Errorf("misspelled locale should not be found")
+	}
+
+	// glibc source layout
+	locales, err = ListLocales("testdata/SUPPORTED.glibc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locales) != 4 || locales[2] != (Locale{Name: "de_DE@euro", Charset: "ISO-8859-15"}) {
+		t.Errorf("unexpected glibc layout result: %+v", locales)
+	}
+}
+
+func TestLocaleGen(t *testing.T) {
+	got := LocaleGen([]Locale{
+		{Name: "en_US.UTF-8", Charset: "UTF-8"},
+		{Name: "de_DE@euro", Charset: "ISO-8859-15"},
+	})
+	want := "# Generated by the Arch Linux GUI installer\n" +
+		"en_US.UTF-8 UTF-8\n" +
+		"de_DE@euro ISO-8859-15\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLocaleConf(t *testing.T) {
+	// English UI with German date/time and paper formats
+	got := LocaleConf("en_US.UTF-8", map[string]string{
+		"LC_TIME":     "de_DE.UTF-8",
+		"LC_PAPER":    "de_DE.UTF-8",
+		"LC_MESSAGES": "en_US.UTF-8", // same as LANG, dropped
+		"LC_NUMERIC":  "",
+	})
+	want := "LANG=en_US.UTF-8\n" +
+		"LC_PAPER=de_DE.UTF-8\n" +
+		"LC_TIME=de_DE.UTF-8\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := LocaleConf("sv_SE.UTF-8", nil); got != "LANG=sv_SE.UTF-8\n" {
+		t.Errorf("unexpected locale.conf without overrides: %q", got)
+	}
+}
+
+func TestResolveLocales(t *testing.T) {
+	supported, err := ListLocales("testdata/SUPPORTED")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ResolveLocales(supported, []string{"en_US.UTF-8", "de_DE@euro", "en_US.UTF-8", "xx_XX", "fr_FR.UTF-8", ""})
+	want := []Locale{
+		{Name: "en_US.UTF-8", Charset: "UTF-8"},
+		{Name: "de_DE@euro", Charset: "ISO-8859-15"},
+		{Name: "fr_FR.UTF-8", Charset: "UTF-8"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entry %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
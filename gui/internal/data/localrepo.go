@@ -0,0 +1,92 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Kinds of offline package sources
+const (
+	LocalRepoDB    = "repo"  // <repo>.db files next to the packages (repo-add)
+	LocalRepoCache = "cache" // bare package files, e.g. a copied /var/cache/pacman/pkg
+)
+
+// pkgFile matches package files without their signatures
+var pkgFile = regexp.MustCompile(`\.pkg\.tar(\.(zst|xz|gz|bz2))?$`)
+
+// LocalRepo is a directory to install from without mirrors. A bare cache has
+// no database; the backend indexes it with repo-add and the live sync
+// databases stand in for it when checking dependencies here.
+type LocalRepo struct {
+	Dir   string
+	Kind  string
+	DB    *SyncDB         // the repo's own databases (LocalRepoDB only)
+	Files map[string]bool // package file names in Dir
+}
+
+// OpenLocalRepo inspects dir and loads its databases
+func OpenLocalRepo(dir string) (*LocalRepo, error) {
+	if !filepath.IsAbs(dir) {
+		return nil, fmt.Errorf("%s is not an absolute path", dir)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &LocalRepo{Dir: dir, Kind: LocalRepoCache, Files: map[string]bool{}}
+	for _, e := range entries {
+		switch name := e.Name(); {
+		case pkgFile.MatchString(name):
+			r.Files[name] = true
+		case strings.HasSuffix(name, ".db"):
+			r.Kind = LocalRepoDB
+		}
+	}
+	if len(r.Files) == 0 {
+		return nil, fmt.Errorf("no packages in %s", dir)
+	}
+	if r.Kind == LocalRepoDB {
+		if r.DB, err = LoadSyncDB(dir); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// ErrNoPackageDB means a bare cache could not be checked: there are no live
+// sync databases to resolve its dependencies with
+var ErrNoPackageDB = errors.New("no package database to check the cache against")
+
+// Missing lists what an install of names would lack from this source:
+// unknown names, unsatisfiable dependencies and package files that are not
+// in the directory. live is used for the dependencies of a bare cache.
+func (r *LocalRepo) Missing(names []string, live *SyncDB) ([]string, error) {
+	db := r.DB
+	if db == nil {
+		db = live
+	}
+	if db == nil {
+		return nil, ErrNoPackageDB
+	}
+	pkgs, missing := db.Closure(names)
+	for _, p := range pkgs {
+		if p.Filename != "" && !r.Files[p.Filename] {
+			missing = append(missing, p.Name)
+		}
+	}
+	slices.Sort(missing)
+	return slices.Compact(missing), nil
+}
+
+// String describes the source for the user
+func (r *LocalRepo) String() string {
+	if r.Kind == LocalRepoDB {
+		return fmt.Sprintf("repository %s with %d packages", strings.Join(r.DB.Repos, ", "), len(r.Files))
+	}
+	return fmt.Sprintf("package cache with %d packages", len(r.Files))
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// localRepoFixture lays out a repository like repo-add does: the database
+// plus the package files it references
+func localRepoFixture(t *testing.T, withDB bool) string {
+	t.Helper()
+	dir := t.TempDir()
+	descs := map[string]string{
+		"base-3-2":    "%NAME%\nbase\n\n%FILENAME%\nbase-3-2-any.pkg.tar.zst\n\n%DEPENDS%\nbash\nglibc>=2.40\n\n",
+		"bash-5.2-1":  "%NAME%\nbash\n\n%FILENAME%\nbash-5.2-1-x86_64.pkg.tar.zst\n\n%DEPENDS%\nglibc\n\n%PROVIDES%\nsh=5.2\n\n",
+		"glibc-2.40":  "%NAME%\nglibc\n\n%FILENAME%\nglibc-2.40-1-x86_64.pkg.tar.zst\n\n",
+		"vim-9.1-1":   "%NAME%\nvim\n\n%FILENAME%\nvim-9.1-1-x86_64.pkg.tar.zst\n\n%DEPENDS%\nsh\nlibsodium\n\n",
+		"nano-8.2-1":  "%NAME%\nnano\n\n%FILENAME%\nnano-8.2-1-x86_64.pkg.tar.zst\n\n",
+		"linux-6.11":  "%NAME%\nlinux\n\n%FILENAME%\nlinux-6.11-1-x86_64.pkg.tar.zst\n\n",
+		"btop-1.4-1":  "%NAME%\nbtop\n\n%FILENAME%\nbtop-1.4-1-x86_64.pkg.tar.zst\n\n",
+		"extra-group": "%NAME%\ngit\n\n%FILENAME%\ngit-2.47-1-x86_64.pkg.tar.zst\n\n%GROUPS%\ndevel\n\n",
+	}
+	if withDB {
+		writeSyncDB(t, filepath.Join(dir, "offline.db"), descs)
+	}
+	// linux is in the database but its file was never copied
+	for _, f := range []string{
+		"base-3-2-any.pkg.tar.zst", "bash-5.2-1-x86_64.pkg.tar.zst", "bash-5.2-1-x86_64.pkg.tar.zst.sig",
+		"glibc-2.40-1-x86_64.pkg.tar.zst", "vim-9.1-1-x86_64.pkg.tar.zst", "nano-8.2-1-x86_64.pkg.tar.zst",
+		"btop-1.4-1-x86_64.pkg.tar.zst", "git-2.47-1-x86_64.pkg.tar.zst",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, f), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestOpenLocalRepo(t *testing.T) {
+	r, err := OpenLocalRepo(localRepoFixture(t, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Kind != LocalRepoDB || len(r.Files) != 7 || !slices.Equal(r.DB.Repos, []string{"offline"}) {
+		t.Errorf("unexpected repo %s %v %d", r.Kind, r.DB.Repos, len(r.Files))
+	}
+
+	cache, err := OpenLocalRepo(localRepoFixture(t, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.Kind != LocalRepoCache || cache.DB != nil {
+		t.Errorf("bare packages should be a cache, got %s", cache.Kind)
+	}
+
+	for name, dir := range map[string]string{"empty": t.TempDir(), "relative": "repo", "absent": "/nonexistent/repo"} {
+		if _, err := OpenLocalRepo(dir); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLocalRepoMissing(t *testing.T) {
+	r, err := OpenLocalRepo(localRepoFixture(t, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// vim needs libsodium (not in the repo), linux has no file, firefox is unknown
+	got, err := r.Missing([]string{"base", "vim", "devel", "linux", "firefox"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"firefox", "libsodium", "linux"}; !slices.Equal(got, want) {
+		t.Errorf("missing %v, want %v", got, want)
+	}
+	if got, _ := r.Missing([]string{"base", "nano", "btop"}, nil); len(got) != 0 {
+		t.Errorf("complete selection reported missing %v", got)
+	}
+
+	// A bare cache is checked against the live databases
+	cache, err := OpenLocalRepo(localRepoFixture(t, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = cache.Missing([]string{"base", "linux"}, r.DB)
+	if want := []string{"linux"}; err != nil || !slices.Equal(got, want) {
+		t.Errorf("cache missing %v (%v), want %v", got, err, want)
+	}
+	// Without live databases (fresh ISO) nothing can be checked
+	if _, err := cache.Missing([]string{"base"}, nil); !errors.Is(err, ErrNoPackageDB) {
+		t.Errorf("unchecked cache: got %v", err)
+	}
+}
+
+func TestSyncDBClosure(t *testing.T) {
+	r, err := OpenLocalRepo(localRepoFixture(t, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgs, missing := r.DB.Closure([]string{"base"})
+	var names []string
+	for _, p := range pkgs {
+		names = append(names, p.Name)
+	}
+	if want := []string{"base", "bash", "glibc"}; !slices.Equal(names, want) || len(missing) != 0 {
+		t.Errorf("closure %v missing %v, want %v", names, missing, want)
+	}
+}
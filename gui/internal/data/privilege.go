@@ -0,0 +1,38 @@
+package data
+
+// Tools administrators (members of wheel) can use to run commands as root
+const (
+	PrivSudo = "sudo"
+	PrivDoas = "doas" // OpenBSD doas, packaged as opendoas on Arch
+	PrivBoth = "both"
+)
+
+// UsesSudo reports whether wheel gets a sudoers drop-in
+func UsesSudo(tool string) bool {
+	return tool != PrivDoas
+}
+
+// UsesDoas reports whether opendoas is installed and configured
+func UsesDoas(tool string) bool {
+	return tool == PrivDoas || tool == PrivBoth
+}
+
+// SudoersDropIn renders /etc/sudoers.d/10-wheel. It replaces uncommenting
+// the %wheel line in /etc/sudoers, so the main file stays as packaged.
+func SudoersDropIn(nopasswd bool) string {
+	rule := "%wheel ALL=(ALL:ALL) ALL"
+	if nopasswd {
+		rule = "%wheel ALL=(ALL:ALL) NOPASSWD: ALL"
+	}
+	return "# Written by the Arch Linux GUI installer\n" + rule + "\n"
+}
+
+// DoasConf renders /etc/doas.conf for wheel. persist caches the password for
+// a few minutes like sudo does. doas requires the trailing newline.
+func DoasConf(nopasswd bool) string {
+	rule := "permit persist setenv { LANG LC_ALL } :wheel"
+	if nopasswd {
+		rule = "permit nopass setenv { LANG LC_ALL } :wheel"
+	}
+	return "# Written by the Arch Linux GUI installer\n" + rule + "\n"
+}
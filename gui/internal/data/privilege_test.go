@@ -0,0 +1,40 @@
+package data
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrivilegeTools(t *testing.T) {
+	tests := []struct {
+		tool       string
+		sudo, doas bool
+	}{
+		{PrivSudo, true, false},
+		{PrivDoas, false, true},
+		{PrivBoth, true, true},
+	}
+	for _, tt := range tests {
+		if UsesSudo(tt.tool) != tt.sudo || UsesDoas(tt.tool) != tt.doas {
+			t.Errorf("%s: sudo=%v doas=%v, want %v %v", tt.tool, UsesSudo(tt.tool), UsesDoas(tt.tool), tt.sudo, tt.doas)
+		}
+	}
+}
+
+func TestSudoersDropIn(t *testing.T) {
+	if got := SudoersDropIn(false); !strings.HasSuffix(got, "\n%wheel ALL=(ALL:ALL) ALL\n") {
+		t.Errorf("unexpected sudoers rule:\n%s", got)
+	}
+	if got := SudoersDropIn(true); !strings.Contains(got, "NOPASSWD: ALL") {
+		t.Errorf("passwordless rule missing NOPASSWD:\n%s", got)
+	}
+}
+
+func TestDoasConf(t *testing.T) {
+	if got := DoasConf(false); !strings.HasSuffix(got, "permit persist setenv { LANG LC_ALL } :wheel\n") {
+		t.Errorf("unexpected doas rule:\n%s", got)
+	}
+	if got := DoasConf(true); !strings.Contains(got, "permit nopass") || strings.Contains(got, "persist") {
+		t.Errorf("unexpected passwordless doas rule:\n%s", got)
+	}
+}
@@ -0,0 +1,47 @@
+package data
+
+// Without a sync database the estimate falls back to rough values: a base
+// system plus an average per named package
+const (
+	fallbackBaseSize    = 4 << 30
+	fallbackPackageSize = 50 << 20
+	minReserve          = 2 << 30
+)
+
+// SizeEstimate is the space an installation needs on the root filesystem
+type SizeEstimate struct {
+	Installed int64 // unpacked packages including dependencies
+	Cache     int64 // package files pacstrap leaves in /var/cache/pacman/pkg
+	Swap      int64
+	Reserve   int64 // headroom for updates, logs and user data
+	Exact     bool  // sizes come from the sync database
+}
+
+// Required is what the installation itself writes, less fails mid-install
+func (e SizeEstimate) Required() int64 {
+	return e.Installed + e.Cache + e.Swap
+}
+
+// Recommended adds the reserve
+func (e SizeEstimate) Recommended() int64 {
+	return e.Required() + e.Reserve
+}
+
+// EstimateSize sums %ISIZE% and %CSIZE% over names and their dependencies.
+// Names the database does not know (AUR packages) are not counted.
+func EstimateSize(db *SyncDB, names []string, swap int64) SizeEstimate {
+	e := SizeEstimate{Swap: swap}
+	if db != nil {
+		pkgs, _ := db.Closure(names)
+		for _, p := range pkgs {
+			e.Installed += p.InstalledSize
+			e.Cache += p.DownloadSize
+		}
+		e.Exact = true
+	} else {
+		e.Installed = fallbackBaseSize + int64(len(names))*fallbackPackageSize
+		e.Cache = e.Installed / 3
+	}
+	e.Reserve = max(e.Installed/4, minReserve)
+	return e
+}
@@ -0,0 +1,51 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEstimateSize(t *testing.T) {
+	dir := t.TempDir()
+	writeSyncDB(t, filepath.Join(dir, "core.db"), map[string]string{
+		"plasma": "%NAME%\nplasma-meta\n\n%CSIZE%\n1000\n\n%ISIZE%\n4000\n\n%DEPENDS%\nqt6-base\n\n",
+		"qt6":    "%NAME%\nqt6-base\n\n%CSIZE%\n3000\n\n%ISIZE%\n9000\n\n",
+		"vim":    "%NAME%\nvim\n\n%CSIZE%\n100\n\n%ISIZE%\n500\n\n",
+	})
+	db, err := LoadSyncDB(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := EstimateSize(db, []string{"plasma-meta", "vim", "some-aur-package"}, 16<<30)
+	if e.Installed != 13500 || e.Cache != 4100 || !e.Exact {
+		t.Errorf("unexpected estimate %+v", e)
+	}
+	if e.Required() != 13500+4100+16<<30 {
+		t.Errorf("required %d", e.Required())
+	}
+	if e.Reserve != minReserve || e.Recommended() != e.Required()+minReserve {
+		t.Errorf("reserve %d", e.Reserve)
+	}
+
+	if fb := EstimateSize(nil, []string{"a", "b"}, 0); fb.Exact || fb.Installed != fallbackBaseSize+2*fallbackPackageSize {
+		t.Errorf("unexpected fallback %+v", fb)
+	}
+}
+
+func TestReadBlockDeviceSize(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "nvme0n1p2"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nvme0n1p2", "size"), []byte("2048\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadBlockDeviceSize(dir, "/dev/nvme0n1p2"); err != nil || got != 1<<20 {
+		t.Errorf("got %d, %v", got, err)
+	}
+	if _, err := ReadBlockDeviceSize(dir, "/dev/sdz"); err == nil {
+		t.Error("expected an error for a missing device")
+	}
+}
@@ -0,0 +1,251 @@
+package data
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Filled by `pacman -Sy` in the bootstrap, readable without network afterwards
+const syncDBDir = "/var/lib/pacman/sync"
+
+// SyncPackage is the part of a sync database entry the installer uses
+type SyncPackage struct {
+	Name          string
+	Repo          string
+	Version       string   // %VERSION%, e.g. 20250123-1
+	Filename      string   // %FILENAME%, the file below the repo's Server
+	Depends       []string // %DEPENDS% without version constraints
+	DownloadSize  int64    // %CSIZE%
+	InstalledSize int64    // %ISIZE%
+}
+
+// SyncDB indexes the repositories found in a sync directory
+type SyncDB struct {
+	Packages map[string]SyncPackage
+	Groups   map[string][]string // group -> member packages
+	Provides map[string]string   // virtual name -> first providing package
+	Repos    []string
+}
+
+var (
+	syncOnce sync.Once
+	syncDB   *SyncDB
+	syncErr  error
+)
+
+// GetSyncDB loads the live system's sync databases once
+func GetSyncDB() (*SyncDB, error) {
+	syncOnce.Do(func() {
+		syncDB, syncErr = LoadSyncDB(syncDBDir)
+	})
+	return syncDB, syncErr
+}
+
+// LoadSyncDB reads every <repo>.db in dir
+func LoadSyncDB(dir string) (*SyncDB, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.db"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no sync databases in %s, run pacman -Sy", dir)
+	}
+	slices.Sort(files)
+
+	db := &SyncDB{
+		Packages: map[string]SyncPackage{},
+		Groups:   map[string][]string{},
+		Provides: map[string]string{},
+	}
+	for _, f := range files {
+		repo := strings.TrimSuffix(filepath.Base(f), ".db")
+		if err := db.loadRepo(f, repo); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(f), err)
+		}
+		db.Repos = append(db.Repos, repo)
+	}
+	return db, nil
+}
+
+// loadRepo reads the desc files of one gzip compressed database
+func (db *SyncDB) loadRepo(path, repo string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	if magic, _ := br.Peek(4); bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}) {
+		return fmt.Errorf("zstd compressed databases are not supported")
+	}
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if filepath.Base(hdr.Name) != "desc" {
+			continue
+		}
+		raw, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		db.addDesc(string(raw), repo)
+	}
+}
+
+// addDesc parses one desc file: "%FIELD%" lines followed by values up to a blank line.
+// Repos are read in order, so the first repo wins like in pacman.
+func (db *SyncDB) addDesc(desc, repo string) {
+	fields := map[string][]string{}
+	var key string
+	for _, line := range strings.Split(desc, "\n") {
+		switch {
+		case strings.HasPrefix(line, "%") && strings.HasSuffix(line, "%"):
+			key = strings.Trim(line, "%")
+		case line == "":
+			key = ""
+		case key != "":
+			fields[key] = append(fields[key], line)
+		}
+	}
+	if len(fields["NAME"]) == 0 {
+		return
+	}
+	pkg := SyncPackage{Name: fields["NAME"][0], Repo: repo}
+	if _, dup := db.Packages[pkg.Name]; dup {
+		return
+	}
+	if v := fields["VERSION"]; len(v) > 0 {
+		pkg.Version = v[0]
+	}
+	if v := fields["FILENAME"]; len(v) > 0 {
+		pkg.Filename = v[0]
+	}
+	for _, d := range fields["DEPENDS"] {
+		pkg.Depends = append(pkg.Depends, depName(d))
+	}
+	if v := fields["CSIZE"]; len(v) > 0 {
+		pkg.DownloadSize, _ = strconv.ParseInt(v[0], 10, 64)
+	}
+	if v := fields["ISIZE"]; len(v) > 0 {
+		pkg.InstalledSize, _ = strconv.ParseInt(v[0], 10, 64)
+	}
+	db.Packages[pkg.Name] = pkg
+	for _, g := range fields["GROUPS"] {
+		db.Groups[g] = append(db.Groups[g], pkg.Name)
+	}
+	for _, p := range fields["PROVIDES"] {
+		name := depName(p)
+		if _, ok := db.Provides[name]; !ok {
+			db.Provides[name] = pkg.Name
+		}
+	}
+}
+
+// Resolve maps names as pacman -S would see them: packages, groups (all
+// members) and provided virtual names. Names found nowhere are returned as
+// missing. The result has no duplicates.
+func (db *SyncDB) Resolve(names []string) (pkgs []SyncPackage, missing []string) {
+	seen := map[string]bool{}
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			pkgs = append(pkgs, db.Packages[name])
+		}
+	}
+	for _, n := range names {
+		switch {
+		case db.Packages[n].Name != "":
+			add(n)
+		case len(db.Groups[n]) > 0:
+			for _, m := range db.Groups[n] {
+				add(m)
+			}
+		case db.Provides[n] != "":
+			add(db.Provides[n])
+		default:
+			missing = append(missing, n)
+		}
+	}
+	return pkgs, missing
+}
+
+// Closure resolves names like Resolve and adds their dependencies
+// recursively, which is what pacman -S would download. Dependencies that
+// cannot be satisfied are reported as missing as well.
+func (db *SyncDB) Closure(names []string) (pkgs []SyncPackage, missing []string) {
+	seen := map[string]bool{}
+	queue, missing := db.Resolve(names)
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if seen[p.Name] {
+			continue
+		}
+		seen[p.Name] = true
+		pkgs = append(pkgs, p)
+		for _, d := range p.Depends {
+			switch {
+			case db.Packages[d].Name != "":
+				queue = append(queue, db.Packages[d])
+			case db.Provides[d] != "":
+				queue = append(queue, db.Packages[db.Provides[d]])
+			case !slices.Contains(missing, d):
+				missing = append(missing, d)
+			}
+		}
+	}
+	return pkgs, missing
+}
+
+// depName strips the version constraint: "glibc>=2.40" -> "glibc"
+func depName(dep string) string {
+	if i := strings.IndexAny(dep, "<>="); i >= 0 {
+		return dep[:i]
+	}
+	return dep
+}
+
+// DownloadSize sums the package files, dependencies not included
+func DownloadSize(pkgs []SyncPackage) int64 {
+	var total int64
+	for _, p := range pkgs {
+		total += p.DownloadSize
+	}
+	return total
+}
+
+// FormatSize renders a byte count like pacman does (KiB, MiB, GiB)
+func FormatSize(n int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	v := float64(n)
+	i := 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", n)
+	}
+	return fmt.Sprintf("%.1f %s", v, units[i])
+}
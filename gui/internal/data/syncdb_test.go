@@ -0,0 +1,103 @@
+package data
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeSyncDB creates a repo database with one desc file per entry
+func writeSyncDB(t *testing.T, path string, descs map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for dir, desc := range descs {
+		if err := tw.WriteHeader(&tar.Header{Name: dir + "/desc", Mode: 0644, Size: int64(len(desc))}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(desc))
+	}
+	tw.Close()
+	gz.Close()
+}
+
+func testSyncDB(t *testing.T) *SyncDB {
+	dir := t.TempDir()
+	writeSyncDB(t, filepath.Join(dir, "core.db"), map[string]string{
+		"bash-5.2-1": "%NAME%\nbash\n\n%CSIZE%\n2000000\n\n%ISIZE%\n9000000\n\n%PROVIDES%\nsh\n\n",
+		"vim-9.1-1":  "%NAME%\nvim\n\n%CSIZE%\n2097152\n\n",
+		"gcc-14.2-1": "%NAME%\ngcc\n\n%CSIZE%\n50000000\n\n%GROUPS%\nbase-devel\n\n",
+		"make-4.4-1": "%NAME%\nmake\n\n%CSIZE%\n500000\n\n%GROUPS%\nbase-devel\n\n",
+		"not-a-desc": "",
+	})
+	writeSyncDB(t, filepath.Join(dir, "extra.db"), map[string]string{
+		"vim-0.1-1":  "%NAME%\nvim\n\n%CSIZE%\n1\n\n",
+		"mpv-0.39-1": "%NAME%\nmpv\n\n%CSIZE%\n1048576\n\n",
+	})
+	db, err := LoadSyncDB(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestLoadSyncDB(t *testing.T) {
+	db := testSyncDB(t)
+	if !slices.Equal(db.Repos, []string{"core", "extra"}) {
+		t.Errorf("unexpected repos %v", db.Repos)
+	}
+	// core comes first, like in pacman.conf
+	if vim := db.Packages["vim"]; vim.Repo != "core" || vim.DownloadSize != 2097152 {
+		t.Errorf("unexpected vim entry %+v", vim)
+	}
+	if bash := db.Packages["bash"]; bash.InstalledSize != 9000000 {
+		t.Errorf("unexpected bash entry %+v", bash)
+	}
+
+	if _, err := LoadSyncDB(t.TempDir()); err == nil {
+		t.Errorf("expected an error for an empty directory")
+	}
+}
+
+func TestSyncDBResolve(t *testing.T) {
+	db := testSyncDB(t)
+	pkgs, missing := db.Resolve([]string{"mpv", "base-devel", "sh", "make", "nosuchpkg"})
+
+	var names []string
+	for _, p := range pkgs {
+		names = append(names, p.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"bash", "gcc", "make", "mpv"}) {
+		t.Errorf("resolved %v", names)
+	}
+	if !slices.Equal(missing, []string{"nosuchpkg"}) {
+		t.Errorf("missing %v", missing)
+	}
+	if got := DownloadSize(pkgs); got != 2000000+50000000+500000+1048576 {
+		t.Errorf("download size %d", got)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		0:                  "0 B",
+		1023:               "1023 B",
+		1536:               "1.5 KiB",
+		1048576:            "1.0 MiB",
+		3 * 1024 * 1048576: "3.0 GiB",
+	}
+	for n, want := range tests {
+		if got := FormatSize(n); got != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
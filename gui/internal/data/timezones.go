@@ -0,0 +1,220 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const zoneinfoDir = "/usr/share/zoneinfo"
+
+// Region used for zones without a slash (UTC, GMT, EST5EDT, ...)
+const OtherRegion = "Other"
+
+// Duplicate trees and legacy aliases that should not show up in the picker
+var skipZoneDirs = map[string]bool{"posix": true, "right": true, "SystemV": true}
+
+// GetTimezones returns all valid zones from the live system
+func GetTimezones() []string {
+	zones, err := ListTimezones(zoneinfoDir)
+	if err != nil || len(zones) == 0 {
+		return []string{"UTC"}
+	}
+	return zones
+}
+
+// ListTimezones walks a zoneinfo tree and returns every TZif file as a zone name
+func ListTimezones(dir string) ([]string, error) {
+	var zones []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if d.IsDir() {
+			if skipZoneDirs[rel] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isTZif(path) {
+			zones = append(zones, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(zones)
+	return zones, err
+}
+
+// isTZif checks the magic bytes, which skips zone.tab, leapseconds, tzdata.zi etc.
+func isTZif(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	magic := make([]byte, 4)
+	if _, err := f.Read(magic); err != nil {
+		return false
+	}
+	return string(magic) == "TZif"
+}
+
+// ValidTimezone reports whether tz names a real zone file below dir.
+// A typo here would otherwise leave /etc/localtime as a dangling symlink.
+func ValidTimezone(dir, tz string) bool {
+	if tz == "" || filepath.IsAbs(tz) || strings.Contains(tz, "..") {
+		return false
+	}
+	first, _, _ := strings.Cut(tz, "/")
+	if skipZoneDirs[first] {
+		return false
+	}
+	return isTZif(filepath.Join(dir, filepath.FromSlash(tz)))
+}
+
+// IsValidTimezone validates against the live system's zoneinfo
+func IsValidTimezone(tz string) bool {
+	return ValidTimezone(zoneinfoDir, tz)
+}
+
+// SplitTimezone splits "America/Argentina/Buenos_Aires" into region and city
+func SplitTimezone(tz string) (region, city string) {
+	region, city, ok := strings.Cut(tz, "/")
+	if !ok {
+		return OtherRegion, tz
+	}
+	return region, city
+}
+
+// JoinTimezone is the inverse of SplitTimezone
+func JoinTimezone(region, city string) string {
+	if region == OtherRegion {
+		return city
+	}
+	return region + "/" + city
+}
+
+// GroupTimezones returns zones grouped as region -> cities
+func GroupTimezones(zones []string) map[string][]string {
+	groups := map[string][]string{}
+	for _, z := range zones {
+		region, city := SplitTimezone(z)
+		groups[region] = append(groups[region], city)
+	}
+	return groups
+}
+
+// SuggestTimezone guesses a zone for the live system and the chosen locale (e.g. "de_DE")
+func SuggestTimezone(locale string) string {
+	return suggestTimezone(zoneinfoDir, "/etc/localtime", "/sys/class/rtc/rtc0/since_epoch", time.Now(), locale)
+}
+
+func suggestTimezone(dir, localtime, rtcPath string, now time.Time, locale string) string {
+	// 1. The live system already has a zone configured (e.g. via a boot parameter)
+	if target, err := os.Readlink(localtime); err == nil {
+		if _, tz, ok := strings.Cut(target, "zoneinfo/"); ok && tz != "UTC" && ValidTimezone(dir, tz) {
+			return tz
+		}
+	}
+
+	candidates := zonesForCountry(filepath.Join(dir, "zone.tab"), localeCountry(locale))
+
+	// 2. A hardware clock kept in local time (dual boot with Windows) reveals
+	// the UTC offset, which narrows down the country's zones
+	if offset, ok := rtcOffset(rtcPath, now); ok {
+		pool := candidates
+		if len(pool) == 0 {
+			pool, _ = ListTimezones(dir)
+		}
+		for _, tz := range pool {
+			if zoneOffset(dir, tz, now) == offset {
+				return tz
+			}
+		}
+	}
+
+	// 3. Offline geo hint: first zone of the locale's territory
+	if len(candidates) > 0 {
+		return candidates[0]
+	}
+	return "UTC"
+}
+
+// localeCountry extracts the territory: "de_DE.UTF-8" -> "DE"
+func localeCountry(locale string) string {
+	_, rest, ok := strings.Cut(locale, "_")
+	if !ok || len(rest) < 2 {
+		return ""
+	}
+	return strings.ToUpper(rest[:2])
+}
+
+// zonesForCountry reads zone.tab (country code, coordinates, zone, comment)
+func zonesForCountry(zoneTab, country string) []string {
+	if country == "" {
+		return nil
+	}
+	f, err := os.Open(zoneTab)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var zones []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) >= 3 && fields[0] == country {
+			zones = append(zones, fields[2])
+		}
+	}
+	return zones
+}
+
+// rtcOffset returns how far the RTC is ahead of UTC, rounded to 15 minutes.
+// ok is false when the RTC runs in UTC (the normal Linux setup).
+func rtcOffset(rtcPath string, now time.Time) (int, bool) {
+	raw, err := os.ReadFile(rtcPath)
+	if err != nil {
+		return 0, false
+	}
+	rtc, err := strconv.ParseInt(string(bytes.TrimSpace(raw)), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	diff := rtc - now.Unix()
+	const quarter = 15 * 60
+	rounded := int((diff + quarter/2) / quarter * quarter)
+	if diff < 0 {
+		rounded = int((diff - quarter/2) / quarter * quarter)
+	}
+	if rounded == 0 {
+		return 0, false
+	}
+	return rounded, true
+}
+
+// zoneOffset returns the current UTC offset of a zone in seconds
+func zoneOffset(dir, tz string, now time.Time) int {
+	raw, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(tz)))
+	if err != nil {
+		return 0
+	}
+	loc, err := time.LoadLocationFromTZData(tz, raw)
+	if err != nil {
+		return 0
+	}
+	_, offset := now.In(loc).Zone()
+	return offset
+}
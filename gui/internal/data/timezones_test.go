@@ -0,0 +1,94 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testZoneinfo = "testdata/zoneinfo"
+
+func TestListTimezones(t *testing.T) {
+	zones, err := ListTimezones(testZoneinfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"America/Argentina/Buenos_Aires", "America/Chicago", "America/New_York",
+		"Asia/Tokyo", "Europe/Berlin", "Europe/London", "Europe/Oslo", "UTC",
+	}
+	if !slices.Equal(zones, want) {
+		t.Errorf("got %v, want %v", zones, want)
+	}
+}
+
+func TestValidTimezone(t *testing.T) {
+	tests := map[string]bool{
+		"Europe/Berlin":       true,
+		"UTC":                 true,
+		"Europe/Londn":        false,
+		"Europe":              false,
+		"zone.tab":            false,
+		"posix/Europe/Berlin": false,
+		"../zoneinfo/UTC":     false,
+		"/etc/passwd":         false,
+		"":                    false,
+	}
+	for tz, want := range tests {
+		if got := ValidTimezone(testZoneinfo, tz); got != want {
+			t.Errorf("ValidTimezone(%q) = %v, want %v", tz, got, want)
+		}
+	}
+}
+
+func TestSplitJoinTimezone(t *testing.T) {
+	for _, tz := range []string{"Europe/Berlin", "America/Argentina/Buenos_Aires", "UTC"} {
+		region, city := SplitTimezone(tz)
+		if got := JoinTimezone(region, city); got != tz {
+			t.Errorf("round trip of %s gave %s (%s, %s)", tz, got, region, city)
+		}
+	}
+	if region, city := SplitTimezone("America/Argentina/Buenos_Aires"); region != "America" || city != "Argentina/Buenos_Aires" {
+		t.Errorf("unexpected split: %s, %s", region, city)
+	}
+
+	groups := GroupTimezones([]string{"Europe/Berlin", "Europe/Oslo", "UTC"})
+	if !slices.Equal(groups["Europe"], []string{"Berlin", "Oslo"}) || !slices.Equal(groups[OtherRegion], []string{"UTC"}) {
+		t.Errorf("unexpected groups: %v", groups)
+	}
+}
+
+func TestSuggestTimezone(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing")
+	winter := time.Date(2026, time.January, 15, 12, 0, 0, 0, time.UTC)
+
+	// Locale territory only
+	if got := suggestTimezone(testZoneinfo, missing, missing, winter, "de_DE.UTF-8"); got != "Europe/Berlin" {
+		t.Errorf("de_DE: got %s", got)
+	}
+	if got := suggestTimezone(testZoneinfo, missing, missing, winter, "C"); got != "UTC" {
+		t.Errorf("no hints: got %s", got)
+	}
+
+	// RTC in local time, six hours behind UTC: Central time, not the first US zone
+	rtc := filepath.Join(dir, "since_epoch")
+	if err := os.WriteFile(rtc, []byte(strconv.FormatInt(winter.Unix()-6*3600+42, 10)+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := suggestTimezone(testZoneinfo, missing, rtc, winter, "en_US.UTF-8"); got != "America/Chicago" {
+		t.Errorf("en_US with UTC-6 RTC: got %s", got)
+	}
+
+	// A zone configured on the live system wins
+	localtime := filepath.Join(dir, "localtime")
+	if err := os.Symlink("/usr/share/zoneinfo/Asia/Tokyo", localtime); err != nil {
+		t.Fatal(err)
+	}
+	if got := suggestTimezone(testZoneinfo, localtime, rtc, winter, "en_US.UTF-8"); got != "Asia/Tokyo" {
+		t.Errorf("live /etc/localtime: got %s", got)
+	}
+}
@@ -0,0 +1,110 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"archgui/gui/internal/command"
+)
+
+// Engine carries out an installation from resolved settings
+type Engine interface {
+	Name() string
+	Install(ctx context.Context, s Settings) error
+	// Simulate returns the commands Install would run, without running them
+	Simulate(ctx context.Context, s Settings) ([]command.Cmd, error)
+}
+
+// Script runs the bash backend with the settings as its env file
+type Script struct {
+	Runner  command.Runner
+	Path    string       // backend/arch-install.sh
+	EnvFile string       // written with mode 0600, it holds passwords
+	Log     func(string) // the script's output, may be nil
+}
+
+func (e *Script) Name() string {
+	return "script (" + e.Path + ")"
+}
+
+func (e *Script) Install(ctx context.Context, s Settings) error {
+	if err := os.WriteFile(e.EnvFile, []byte(s.Env()), 0o600); err != nil {
+		return err
+	}
+	_, err := e.Runner.Run(ctx, command.Cmd{Name: "bash", Args: []string{e.Path, "--config", e.EnvFile}, Output: e.Log})
+	return err
+}
+
+// Simulate runs the script with DRY_RUN=yes, which prints the commands
+// instead of running them
+func (e *Script) Simulate(ctx context.Context, s Settings) ([]command.Cmd, error) {
+	if err := os.WriteFile(e.EnvFile, []byte(s.Env()+"DRY_RUN=yes\n"), 0o600); err != nil {
+		return nil, err
+	}
+	defer os.Remove(e.EnvFile)
+
+	var cmds []command.Cmd
+	var problems []string
+	_, err := e.Runner.Run(ctx, command.Cmd{Name: "bash", Args: []string{e.Path, "--config", e.EnvFile}, Output: func(line string) {
+		line = ansiCodes.ReplaceAllString(line, "")
+		if rest, ok := strings.CutPrefix(line, "[DRY-RUN] "); ok {
+			cmds = append(cmds, parseDryRun(rest))
+		} else if rest, ok := strings.CutPrefix(line, "[ERROR] "); ok {
+			problems = append(problems, rest)
+		}
+	}})
+	if err != nil && len(problems) > 0 {
+		err = fmt.Errorf("%s: %w", strings.Join(problems, "; "), err)
+	}
+	return cmds, err
+}
+
+var ansiCodes = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// parseDryRun reads back a line printed by the script's show_cmd, the
+// quoting of command.Cmd.String. Secrets piped in are marked "< stdin".
+func parseDryRun(line string) command.Cmd {
+	var c command.Cmd
+	if rest, ok := strings.CutSuffix(line, " < stdin"); ok {
+		line, c.Stdin = rest, "(not shown)"
+	}
+	var words []string
+	var word strings.Builder
+	started, quoted := false, false
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case quoted:
+			if ch == '\'' {
+				quoted = false
+			} else {
+				word.WriteByte(ch)
+			}
+		case ch == '\'':
+			quoted, started = true, true
+		case ch == '\\' && i+1 < len(line):
+			i++
+			word.WriteByte(line[i])
+			started = true
+		case ch == ' ':
+			if started {
+				words = append(words, word.String())
+				word.Reset()
+				started = false
+			}
+		default:
+			word.WriteByte(ch)
+			started = true
+		}
+	}
+	if started {
+		words = append(words, word.String())
+	}
+	if len(words) > 0 {
+		c.Name, c.Args = words[0], words[1:]
+	}
+	return c
+}
@@ -0,0 +1,78 @@
+package installer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"archgui/gui/internal/command"
+)
+
+func TestScript(t *testing.T) {
+	r := &command.Fake{}
+	env := filepath.Join(t.TempDir(), "install.env")
+	e := &Script{Runner: r, Path: "backend/arch-install.sh", EnvFile: env}
+	if err := e.Install(context.Background(), NewSettings([][2]string{{"HOSTNAME", "archlinux"}})); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Lines(); !slices.Equal(got, []string{"bash backend/arch-install.sh --config " + env}) {
+		t.Errorf("got %q", got)
+	}
+	fi, err := os.Stat(env)
+	if err != nil || fi.Mode().Perm() != 0o600 {
+		t.Fatalf("env file %v, %v", fi, err)
+	}
+	if raw, _ := os.ReadFile(env); string(raw) != "HOSTNAME=archlinux\n" {
+		t.Errorf("env %q", raw)
+	}
+}
+
+func TestParseDryRun(t *testing.T) {
+	for _, c := range []command.Cmd{
+		{Name: "wipefs", Args: []string{"-af", "/dev/sda"}},
+		{Name: "arch-chroot", Args: []string{"/mnt", "useradd", "-c", "Jo O'Neil", "-c", "", "a$b", `back\slash`, "tab\there"}},
+	} {
+		if got := parseDryRun(c.String()); got.String() != c.String() || len(got.Args) != len(c.Args) {
+			t.Errorf("parseDryRun(%q) = %q", c.String(), got.String())
+		}
+	}
+	got := parseDryRun("arch-chroot /mnt chpasswd < stdin")
+	if got.String() != "arch-chroot /mnt chpasswd" || got.Stdin == "" {
+		t.Errorf("stdin command parsed as %q, stdin %q", got.String(), got.Stdin)
+	}
+}
+
+func TestScriptSimulate(t *testing.T) {
+	if _, err := os.Stat("/usr/share/zoneinfo/UTC"); err != nil {
+		t.Skip("no zoneinfo, the script rejects the timezone")
+	}
+	env := filepath.Join(t.TempDir(), "install.env")
+	e := &Script{Runner: &command.Exec{}, Path: "../../../backend/arch-install.sh", EnvFile: env}
+	s := testSettings([2]string{"NONINTERACTIVE", "yes"}, [2]string{"USE_LUKS", "yes"}, [2]string{"LUKS_PASSWORD", "lukspw"})
+	cmds, err := e.Simulate(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, c := range cmds {
+		lines = append(lines, c.String())
+		for _, secret := range []string{"rootpw", "pa$$ word", "lukspw"} {
+			if strings.Contains(c.String(), secret) {
+				t.Errorf("%q shows a password", c.String())
+			}
+		}
+	}
+	wipe, part := slices.Index(lines, "wipefs -af /dev/nvme0n1"), slices.Index(lines, "parted -s /dev/nvme0n1 mklabel gpt")
+	if wipe < 0 || part < wipe {
+		t.Errorf("wipefs then parted expected in %q", lines)
+	}
+	if i := slices.IndexFunc(cmds, func(c command.Cmd) bool { return c.Name == "cryptsetup" }); i < 0 || cmds[i].Stdin == "" {
+		t.Errorf("cryptsetup with the passphrase on stdin expected in %q", lines)
+	}
+	if _, err := os.Stat(env); !os.IsNotExist(err) {
+		t.Errorf("env file left behind: %v", err)
+	}
+}
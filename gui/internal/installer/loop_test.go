@@ -0,0 +1,196 @@
+//go:build integration
+
+// Loop-device tests for the disk stages of the native engine. They need
+// root and the disk tools, but no network or real disks:
+//
+//	sudo go test -tags integration -run Loop ./gui/internal/installer
+package installer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"archgui/gui/internal/command"
+)
+
+// loopImageSize fits the 512 MiB ESP, a LUKS2 header and a btrfs root
+const loopImageSize = 2 << 30
+
+func TestLoopDiskStages(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("needs root for losetup, mkfs and mount")
+	}
+	for _, boot := range []string{"uefi", "bios"} {
+		for _, fs := range []string{"ext4", "btrfs"} {
+			for _, luks := range []bool{false, true} {
+				name := fmt.Sprintf("%s-%s-luks=%v", boot, fs, luks)
+				t.Run(name, func(t *testing.T) { testLoopInstall(t, boot, fs, luks) })
+			}
+		}
+	}
+}
+
+// loopTools are the programs a case runs, missing ones skip it
+func loopTools(boot, fs string, luks bool) []string {
+	tools := []string{"losetup", "wipefs", "parted", "blkid", "findmnt", "mount", "umount", "mkfs." + fs}
+	if boot == "uefi" {
+		tools = append(tools, "mkfs.fat")
+	}
+	if fs == "btrfs" {
+		tools = append(tools, "btrfs")
+	}
+	if luks {
+		tools = append(tools, "cryptsetup")
+	}
+	return tools
+}
+
+func testLoopInstall(t *testing.T, boot, fs string, luks bool) {
+	for _, tool := range loopTools(boot, fs, luks) {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not installed", tool)
+		}
+	}
+	ctx := context.Background()
+
+	img := filepath.Join(t.TempDir(), "disk.img")
+	if err := os.WriteFile(img, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(img, loopImageSize); err != nil {
+		t.Fatal(err)
+	}
+	disk := sh(t, "losetup", "--find", "--partscan", "--show", img)
+	t.Cleanup(func() { exec.Command("losetup", "-d", disk).Run() })
+
+	live, root := t.TempDir(), t.TempDir()
+	if err := writeFile(filepath.Join(live, "usr/share/zoneinfo/UTC"), "TZif", 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The loop device itself is checked through the live root
+	if err := os.Symlink("/dev", filepath.Join(live, "dev")); err != nil {
+		t.Fatal(err)
+	}
+	// A name of our own, so the test never touches a mapping it did not
+	// open, such as the live system's cryptroot
+	mapper := fmt.Sprintf("archgui-test-%d", os.Getpid())
+	if _, err := os.Stat("/dev/mapper/" + mapper); err == nil {
+		t.Fatalf("/dev/mapper/%s already exists", mapper)
+	}
+	// Cleanups run last-in first-out: unmount, close LUKS, then detach
+	t.Cleanup(func() {
+		if _, err := os.Stat("/dev/mapper/" + mapper); err == nil {
+			exec.Command("cryptsetup", "close", mapper).Run()
+		}
+	})
+	t.Cleanup(func() { exec.Command("umount", "-R", root).Run() })
+
+	s := testSettings(
+		[2]string{"BOOT_MODE", boot},
+		[2]string{"DISK", disk},
+		[2]string{"FS_TYPE", fs},
+		[2]string{"USE_LUKS", boolYes(luks)},
+		[2]string{"LUKS_PASSWORD", "loop test"},
+		[2]string{"SWAP_SIZE", "0"},
+	)
+	var logs []string
+	n := &Native{Runner: &command.Exec{}, Root: root, Live: live, Mapper: mapper, Log: func(l string) { logs = append(logs, l) }}
+	in, err := n.prepare(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range []step{{"partition", partition}, {"format", format}, {"mount", mount}} {
+		if err := st.run(ctx, in); err != nil {
+			t.Fatalf("%s: %v\n%s", st.name, err, strings.Join(logs, "\n"))
+		}
+	}
+
+	wantTable := map[string]string{"uefi": "gpt", "bios": "dos"}[boot]
+	if got := sh(t, "blkid", "-p", "-o", "value", "-s", "PTTYPE", disk); got != wantTable {
+		t.Errorf("partition table %s, want %s", got, wantTable)
+	}
+
+	rootPart := disk + "p1"
+	if boot == "uefi" {
+		rootPart = disk + "p2"
+		if got := sh(t, "blkid", "-o", "value", "-s", "TYPE", disk+"p1"); got != "vfat" {
+			t.Errorf("ESP is %s", got)
+		}
+		if got := sh(t, "findmnt", "-n", "-o", "SOURCE", filepath.Join(root, "boot")); got != disk+"p1" {
+			t.Errorf("/boot mounted from %s", got)
+		}
+	}
+	if luks {
+		if got := sh(t, "blkid", "-o", "value", "-s", "TYPE", rootPart); got != "crypto_LUKS" {
+			t.Errorf("root partition is %s, want crypto_LUKS", got)
+		}
+	}
+
+	if got := sh(t, "findmnt", "-n", "-o", "FSTYPE", root); got != fs {
+		t.Errorf("root filesystem %s, want %s", got, fs)
+	}
+	wantSource := rootPart
+	if luks {
+		wantSource = "/dev/mapper/" + mapper
+	}
+	if got := sh(t, "findmnt", "-n", "-o", "SOURCE", root); !strings.HasPrefix(got, wantSource) {
+		t.Errorf("root mounted from %s, want %s", got, wantSource)
+	}
+	if fs == "btrfs" {
+		if opts := sh(t, "findmnt", "-n", "-o", "OPTIONS", root); !strings.Contains(opts, "subvol=/@") {
+			t.Errorf("root options %s, want subvol=/@", opts)
+		}
+		for _, sub := range btrfsSubvolumes {
+			if opts := sh(t, "findmnt", "-n", "-o", "OPTIONS", filepath.Join(root, sub[1])); !strings.Contains(opts, "subvol=/"+sub[0]) {
+				t.Errorf("%s options %s", sub[1], opts)
+			}
+		}
+	}
+
+	if _, err := exec.LookPath("genfstab"); err != nil {
+		t.Log("genfstab not installed, fstab not checked")
+		return
+	}
+	if err := fstab(ctx, in); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(filepath.Join(root, "etc/fstab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootLine := false
+	for _, l := range strings.Split(string(raw), "\n") {
+		f := strings.Fields(l)
+		if len(f) >= 3 && f[1] == "/" {
+			rootLine = strings.HasPrefix(f[0], "UUID=") && f[2] == fs
+		}
+	}
+	if !rootLine {
+		t.Errorf("no UUID root entry for %s in fstab:\n%s", fs, raw)
+	}
+	if boot == "uefi" && !strings.Contains(string(raw), " /boot ") {
+		t.Errorf("no /boot entry in fstab:\n%s", raw)
+	}
+}
+
+// sh runs a command and returns its trimmed stdout, failing the test on errors
+func sh(t *testing.T, name string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		t.Fatalf("%s %s: %v", name, strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func boolYes(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
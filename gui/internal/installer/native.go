@@ -0,0 +1,329 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"archgui/gui/internal/command"
+)
+
+// Native installs with Go steps calling the system tools directly. User
+// values only ever reach programs as arguments or on stdin.
+type Native struct {
+	Runner command.Runner
+	Root   string       // mount point of the target, /mnt
+	Live   string       // root of the live system, / (pacman.conf, zoneinfo, firmware)
+	Mapper string       // LUKS mapping name for the root, cryptroot when empty
+	Log    func(string) // progress messages, may be nil
+
+	skipDevices bool // no block device check, like DRY_RUN in the script (Simulate, tests)
+}
+
+// mapper is the device-mapper name the encrypted root is opened as
+func (n *Native) mapper() string {
+	if n.Mapper == "" {
+		return "cryptroot"
+	}
+	return n.Mapper
+}
+
+// step is one stage of the installation, run in order
+type step struct {
+	name string
+	run  func(ctx context.Context, in *install) error
+}
+
+var steps = []step{
+	{"partition", partition},
+	{"format", format},
+	{"mount", mount},
+	{"pacstrap", pacstrap},
+	{"fstab", fstab},
+	{"configure", configure},
+	{"bootloader", bootloader},
+	{"aur", aur},
+}
+
+// install is the state threaded through the steps
+type install struct {
+	*Native
+	s     Settings
+	users []account
+
+	bootMode   string // uefi, bios
+	uefiBits   string
+	removable  bool   // installing for another machine, GRUB goes to the fallback path
+	rootPart   string // partition holding the root filesystem
+	efiPart    string
+	rootDev    string // rootPart, or its opened LUKS mapping
+	formatRoot bool
+
+	targetMirrorlist string // written to the target after the base install
+}
+
+func (n *Native) Name() string {
+	return "native"
+}
+
+// Unsupported lists the settings only the script engine handles
+func Unsupported(s Settings) []string {
+	var keys []string
+	for _, k := range []string{"OFFLINE_REPO", "CACHE_SERVER", "CACHE_DIR"} {
+		if s.Get(k) != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func (n *Native) Install(ctx context.Context, s Settings) error {
+	if keys := Unsupported(s); len(keys) > 0 {
+		return fmt.Errorf("not supported by the native engine: %s", strings.Join(keys, ", "))
+	}
+	in, err := n.prepare(s)
+	if err != nil {
+		return err
+	}
+	for _, st := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := st.run(ctx, in); err != nil {
+			return fmt.Errorf("%s: %w", st.name, err)
+		}
+	}
+	in.logf("Installation Complete!")
+	return nil
+}
+
+var (
+	usernamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_-]*[$]?$`)
+	groupsPattern   = regexp.MustCompile(`^[a-z0-9_,-]*$`)
+	homePattern     = regexp.MustCompile(`^/[^:\s]+$`)
+	hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`)
+	profilePattern  = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*\.nmconnection$`)
+	packagesPattern = regexp.MustCompile(`^[a-z0-9@._+ -]*$`)
+	mirrorPattern   = regexp.MustCompile(`^Server = https?://[^\s]+$`)
+	unitPattern     = regexp.MustCompile(`^[A-Za-z0-9@._-]*$`)
+	unitsPattern    = regexp.MustCompile(`^[A-Za-z0-9@._ -]*$`)
+)
+
+// prepare checks the settings the way validate_config does and detects
+// the boot mode
+func (n *Native) prepare(s Settings) (*install, error) {
+	in := &install{Native: n, s: s, formatRoot: true}
+	var err error
+	if in.users, err = s.accounts(); err != nil {
+		return nil, err
+	}
+
+	_, statErr := os.Stat(in.live("sys/firmware/efi/efivars"))
+	detected := "bios"
+	if statErr == nil {
+		detected = "uefi"
+	}
+	in.bootMode = s.Get("BOOT_MODE")
+	if in.bootMode == "" {
+		in.bootMode = detected
+	}
+	if in.bootMode != "uefi" && in.bootMode != "bios" {
+		return nil, fmt.Errorf("invalid BOOT_MODE: %s (expected uefi or bios)", in.bootMode)
+	}
+	in.uefiBits = s.Get("UEFI_BITS")
+	if in.bootMode == "uefi" && in.uefiBits == "" {
+		in.uefiBits = "64"
+		if raw, err := os.ReadFile(in.live("sys/firmware/efi/fw_platform_size")); err == nil && strings.TrimSpace(string(raw)) == "32" {
+			in.uefiBits = "32"
+		}
+	}
+	in.removable = in.bootMode != detected
+	in.logf("Boot Mode: %s (live system: %s)", in.bootMode, detected)
+
+	if s.Yes("MANUAL_PARTITIONING") {
+		if s.Get("TARGET_ROOT") == "" {
+			return nil, fmt.Errorf("TARGET_ROOT is required for manual partitioning")
+		}
+		if in.bootMode == "uefi" && s.Get("TARGET_EFI") == "" {
+			return nil, fmt.Errorf("TARGET_EFI is required for manual partitioning in UEFI mode")
+		}
+	} else if s.Get("DISK") == "" {
+		return nil, fmt.Errorf("DISK is not set")
+	}
+	if !n.skipDevices {
+		if err := in.checkBlockDevice(); err != nil {
+			return nil, err
+		}
+	}
+	if s.Yes("USE_LUKS") && s.Get("LUKS_PASSWORD") == "" {
+		return nil, fmt.Errorf("LUKS_PASSWORD is required for encryption")
+	}
+	if s.Get("ROOT_PASSWORD") == "" && !s.Yes("ROOT_LOCKED") {
+		return nil, fmt.Errorf("ROOT_PASSWORD is not set")
+	}
+
+	hasKeys := false
+	for _, u := range in.users {
+		switch {
+		case !usernamePattern.MatchString(u.Name) || len(u.Name) > 32:
+			return nil, fmt.Errorf("invalid username: %s", u.Name)
+		case u.Password == "":
+			return nil, fmt.Errorf("no password for %s", u.Name)
+		case !groupsPattern.MatchString(u.Groups):
+			return nil, fmt.Errorf("invalid groups for %s: %s", u.Name, u.Groups)
+		case u.Home != "" && !homePattern.MatchString(u.Home):
+			return nil, fmt.Errorf("invalid home directory for %s: %s", u.Name, u.Home)
+		}
+		hasKeys = hasKeys || u.SSHKeys != ""
+	}
+	if !hostnamePattern.MatchString(s.Get("HOSTNAME")) {
+		return nil, fmt.Errorf("invalid hostname: %s", s.Get("HOSTNAME"))
+	}
+	if s.Yes("ENABLE_SSHD") && s.Yes("SSH_DISABLE_PASSWORDS") && !hasKeys {
+		return nil, fmt.Errorf("SSH_DISABLE_PASSWORDS needs USER_<i>_SSH_KEYS for at least one user")
+	}
+	// Only Server lines and comments, pacman.conf Includes this file
+	for _, line := range strings.Split(s.Get("MIRRORLIST"), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") && !mirrorPattern.MatchString(line) {
+			return nil, fmt.Errorf("invalid mirrorlist line: %s", line)
+		}
+	}
+	if s.Get("WIFI_PROFILE") != "" && !profilePattern.MatchString(s.Get("WIFI_PROFILE_NAME")) {
+		return nil, fmt.Errorf("invalid Wi-Fi profile name: %s", s.Get("WIFI_PROFILE_NAME"))
+	}
+	if !unitPattern.MatchString(s.Get("DISPLAY_MANAGER")) {
+		return nil, fmt.Errorf("invalid display manager: %s", s.Get("DISPLAY_MANAGER"))
+	}
+	if !unitsPattern.MatchString(s.Get("EXTRA_SERVICES")) {
+		return nil, fmt.Errorf("invalid service list: %s", s.Get("EXTRA_SERVICES"))
+	}
+	if !packagesPattern.MatchString(s.Get("EXTRA_PACKAGES")) {
+		return nil, fmt.Errorf("invalid package list: %s", s.Get("EXTRA_PACKAGES"))
+	}
+	if !packagesPattern.MatchString(s.Get("DESKTOP_PACKAGES")) {
+		return nil, fmt.Errorf("invalid desktop package list: %s", s.Get("DESKTOP_PACKAGES"))
+	}
+	// The package sets live in the desktop catalog only
+	if d := s.Get("DESKTOP_ENV"); d != "" && d != "none" && s.Get("DESKTOP_PACKAGES") == "" {
+		return nil, fmt.Errorf("DESKTOP_ENV=%s needs DESKTOP_PACKAGES", d)
+	}
+	if h := s.Get("AUR_HELPER"); h != "" && h != "none" && h != "yay" && h != "paru" {
+		return nil, fmt.Errorf("unknown AUR helper: %s", h)
+	}
+	// AUR names end up in su -c command strings
+	if !packagesPattern.MatchString(s.Get("AUR_PACKAGES")) {
+		return nil, fmt.Errorf("invalid AUR package list: %s", s.Get("AUR_PACKAGES"))
+	}
+	if !packagesPattern.MatchString(s.Get("GPU_AUR_PACKAGES")) {
+		return nil, fmt.Errorf("invalid GPU AUR package list: %s", s.Get("GPU_AUR_PACKAGES"))
+	}
+	tz := s.Get("TIMEZONE")
+	if _, err := os.Stat(in.live("usr/share/zoneinfo", tz)); tz == "" || strings.Contains(tz, "..") || err != nil {
+		return nil, fmt.Errorf("unknown timezone: %s", tz)
+	}
+	return in, nil
+}
+
+// checkBlockDevice makes sure the target exists before wipefs or mkfs touch it
+func (in *install) checkBlockDevice() error {
+	kind, dev := "disk", in.s.Get("DISK")
+	if in.s.Yes("MANUAL_PARTITIONING") {
+		kind, dev = "partition", in.s.Get("TARGET_ROOT")
+	}
+	fi, err := os.Stat(in.live(dev))
+	if err != nil {
+		return fmt.Errorf("target %s %s does not exist", kind, dev)
+	}
+	if fi.Mode()&os.ModeDevice == 0 || fi.Mode()&os.ModeCharDevice != 0 {
+		return fmt.Errorf("target %s %s is not a block device", kind, dev)
+	}
+	return nil
+}
+
+func (in *install) logf(format string, args ...any) {
+	if in.Log != nil {
+		in.Log(fmt.Sprintf(format, args...))
+	}
+}
+
+// exec runs c with its output going to the log
+func (in *install) exec(ctx context.Context, c command.Cmd) ([]byte, error) {
+	c.Output = in.Log
+	return in.Runner.Run(ctx, c)
+}
+
+// run executes a program on the live system
+func (in *install) run(ctx context.Context, name string, args ...string) error {
+	_, err := in.exec(ctx, command.Cmd{Name: name, Args: args})
+	return err
+}
+
+// output executes a program and returns its trimmed stdout
+func (in *install) output(ctx context.Context, name string, args ...string) (string, error) {
+	out, err := in.exec(ctx, command.Cmd{Name: name, Args: args})
+	return strings.TrimSpace(string(out)), err
+}
+
+// chroot executes a program inside the target
+func (in *install) chroot(ctx context.Context, args ...string) error {
+	return in.run(ctx, "arch-chroot", append([]string{in.Root}, args...)...)
+}
+
+// chrootStdin executes a program inside the target with input on stdin
+func (in *install) chrootStdin(ctx context.Context, stdin string, args ...string) error {
+	_, err := in.exec(ctx, command.Cmd{Name: "arch-chroot", Args: append([]string{in.Root}, args...), Stdin: stdin})
+	return err
+}
+
+// target is a path inside the installed system
+func (in *install) target(elem ...string) string {
+	return filepath.Join(append([]string{in.Root}, elem...)...)
+}
+
+// live is a path on the live system
+func (in *install) live(elem ...string) string {
+	return filepath.Join(append([]string{in.Live}, elem...)...)
+}
+
+// writeFile creates parent directories and sets perm also on existing files
+func writeFile(path, content string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		return err
+	}
+	return os.Chmod(path, perm)
+}
+
+// appendFile adds content to the end of path
+func appendFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// editLines rewrites path line by line
+func editLines(path string, edit func(line string) string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(raw), "\n")
+	for i, l := range lines {
+		lines[i] = edit(l)
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644)
+}
@@ -0,0 +1,405 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"archgui/gui/internal/command"
+)
+
+var errFailed = errors.New("failed")
+
+func testSettings(extra ...[2]string) Settings {
+	vars := [][2]string{
+		{"BOOT_MODE", "uefi"},
+		{"UEFI_BITS", "64"},
+		{"DISK", "/dev/nvme0n1"},
+		{"MANUAL_PARTITIONING", "no"},
+		{"FS_TYPE", "ext4"},
+		{"HOSTNAME", "archlinux"},
+		{"ROOT_PASSWORD", "rootpw"},
+		{"PRIVILEGE_TOOL", "sudo"},
+		{"SUDOERS_DROPIN", "%wheel ALL=(ALL:ALL) ALL"},
+		{"TIMEZONE", "UTC"},
+		{"LOCALE", "en_US.UTF-8"},
+		{"LOCALE_GEN", "en_US.UTF-8 UTF-8"},
+		{"LOCALE_CONF", "LANG=en_US.UTF-8"},
+		{"KEYMAP", "us"},
+		{"DESKTOP_ENV", "none"},
+		{"KERNEL", "linux"},
+		{"MICROCODE", "none"},
+		{"POWER_PROFILE", "none"},
+		{"AUR_HELPER", "none"},
+		{"SWAP_SIZE", "0"},
+		{"USER_COUNT", "1"},
+		{"USER_0_NAME", "alice"},
+		{"USER_0_FULL_NAME", "Alice Smith"},
+		{"USER_0_PASSWORD", "pa$$ word"},
+		{"USER_0_ADMIN", "yes"},
+		{"USER_0_GROUPS", "audio,video"},
+		{"USER_0_SHELL", "bash"},
+	}
+	return NewSettings(append(vars, extra...))
+}
+
+// newTestInstall prepares an install against temp directories; the live
+// system has UEFI firmware and a UTC zone
+func newTestInstall(t *testing.T, s Settings) (*install, *command.Fake) {
+	t.Helper()
+	live, root := t.TempDir(), t.TempDir()
+	for _, dir := range []string{"sys/firmware/efi/efivars", "usr/share/zoneinfo", "etc/pacman.d"} {
+		if err := os.MkdirAll(filepath.Join(live, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for file, content := range map[string]string{
+		"usr/share/zoneinfo/UTC":  "TZif",
+		"etc/pacman.conf":         "[core]\nInclude = /etc/pacman.d/mirrorlist\n\n#[multilib]\n#Include = /etc/pacman.d/mirrorlist\n",
+		"etc/pacman.d/mirrorlist": "Server = https://geo.mirror.pkgbuild.com/$repo/os/$arch\n",
+	} {
+		if err := os.WriteFile(filepath.Join(live, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := &command.Fake{Replies: []command.Reply{
+		{Prefix: "genfstab", Stdout: "UUID=1234 / ext4 rw 0 1\n"},
+		{Prefix: "findmnt", Stdout: "ext4\n"},
+		{Prefix: "blkid", Stdout: "5678\n"},
+		{Prefix: "lsblk", Stdout: "sda\n"},
+	}}
+	in, err := (&Native{Runner: r, Root: root, Live: live, skipDevices: true}).prepare(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return in, r
+}
+
+func TestPrepareRejects(t *testing.T) {
+	tests := map[string][2]string{
+		"bad username":    {"USER_0_NAME", "Alice"},
+		"bad hostname":    {"HOSTNAME", "-bad"},
+		"no disk":         {"DISK", ""},
+		"unknown zone":    {"TIMEZONE", "Mars/Olympus"},
+		"zone traversal":  {"TIMEZONE", "../../etc/passwd"},
+		"aur injection":   {"AUR_PACKAGES", "yay; rm -rf /"},
+		"gpu aur subst":   {"GPU_AUR_PACKAGES", "nvidia-580xx-dkms $(reboot)"},
+		"bad boot mode":   {"BOOT_MODE", "coreboot"},
+		"mirror include":  {"MIRRORLIST", "## Germany\nServer = https://mirror.example/$repo/os/$arch\nInclude = /etc/evil"},
+		"mirror no tls":   {"MIRRORLIST", "Server = ftp://mirror.example/$repo/os/$arch"},
+		"dm injection":    {"DISPLAY_MANAGER", "gdm; rm -rf /"},
+		"service subst":   {"EXTRA_SERVICES", "cups $(reboot)"},
+		"package semi":    {"EXTRA_PACKAGES", "vim;rm"},
+		"desktop no pkgs": {"DESKTOP_ENV", "xfce"},
+	}
+	live := t.TempDir()
+	os.MkdirAll(filepath.Join(live, "usr/share/zoneinfo"), 0o755)
+	os.WriteFile(filepath.Join(live, "usr/share/zoneinfo/UTC"), nil, 0o644)
+	n := &Native{Runner: &command.Fake{}, Root: t.TempDir(), Live: live, skipDevices: true}
+	valid := testSettings(
+		[2]string{"MIRRORLIST", "## Germany\n\nServer = https://mirror.example/$repo/os/$arch"},
+		[2]string{"DISPLAY_MANAGER", "gdm"},
+		[2]string{"EXTRA_SERVICES", "cups libvirtd.socket"},
+		[2]string{"EXTRA_PACKAGES", "firefox gtk+ libreoffice-fresh"},
+	)
+	if _, err := n.prepare(valid); err != nil {
+		t.Fatalf("valid settings: %v", err)
+	}
+	for name, kv := range tests {
+		if _, err := n.prepare(testSettings(kv)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestPrepareBlockDevice(t *testing.T) {
+	live := t.TempDir()
+	os.MkdirAll(filepath.Join(live, "usr/share/zoneinfo"), 0o755)
+	os.WriteFile(filepath.Join(live, "usr/share/zoneinfo/UTC"), nil, 0o644)
+	n := &Native{Runner: &command.Fake{}, Root: t.TempDir(), Live: live}
+	if _, err := n.prepare(testSettings()); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("missing disk: got %v", err)
+	}
+	// A typo like /dev/nvme0n1 as a regular file must not be formatted either
+	writeFile(filepath.Join(live, "dev/nvme0n1"), "", 0o644)
+	if _, err := n.prepare(testSettings()); err == nil || !strings.Contains(err.Error(), "not a block device") {
+		t.Errorf("regular file: got %v", err)
+	}
+	manual := testSettings([2]string{"MANUAL_PARTITIONING", "yes"}, [2]string{"TARGET_ROOT", "/dev/sda2"}, [2]string{"TARGET_EFI", "/dev/sda1"})
+	if _, err := n.prepare(manual); err == nil || !strings.Contains(err.Error(), "partition /dev/sda2") {
+		t.Errorf("missing partition: got %v", err)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	s := testSettings([2]string{"OFFLINE_REPO", "/run/media/usb"}, [2]string{"CACHE_DIR", "/srv/cache"})
+	if got := Unsupported(s); !slices.Equal(got, []string{"OFFLINE_REPO", "CACHE_DIR"}) {
+		t.Errorf("got %v", got)
+	}
+	if err := (&Native{Runner: &command.Fake{}}).Install(context.Background(), s); err == nil {
+		t.Error("expected the native engine to refuse an offline install")
+	}
+}
+
+func TestPartitionAuto(t *testing.T) {
+	in, r := newTestInstall(t, testSettings())
+	if err := partition(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"wipefs -af /dev/nvme0n1",
+		"parted -s /dev/nvme0n1 mklabel gpt",
+		"parted -s /dev/nvme0n1 mkpart EFI fat32 1MiB 513MiB",
+		"parted -s /dev/nvme0n1 set 1 esp on",
+		"parted -s /dev/nvme0n1 mkpart root ext4 513MiB 100%",
+		"partprobe /dev/nvme0n1",
+		"udevadm settle",
+	}
+	if got := r.Lines(); !slices.Equal(got, want) {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+	if in.efiPart != "/dev/nvme0n1p1" || in.rootPart != "/dev/nvme0n1p2" || !in.formatRoot {
+		t.Errorf("partitions %s %s, format %v", in.efiPart, in.rootPart, in.formatRoot)
+	}
+}
+
+func TestFormatAndMountLUKSBtrfs(t *testing.T) {
+	in, r := newTestInstall(t, testSettings([2]string{"FS_TYPE", "btrfs"}, [2]string{"USE_LUKS", "yes"}, [2]string{"LUKS_PASSWORD", "secret pw"}))
+	in.rootPart, in.efiPart, in.formatRoot = "/dev/sda2", "/dev/sda1", true
+	if err := format(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	if err := mount(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	got := r.Lines()
+	for _, want := range []string{
+		"mkfs.fat -F32 /dev/sda1",
+		"cryptsetup luksFormat --type luks2 /dev/sda2 -",
+		"mkfs.btrfs -f /dev/mapper/cryptroot",
+		"btrfs subvolume create " + in.target("@"),
+		"btrfs subvolume create " + in.target("@var_log"),
+		"mount -o subvol=@," + btrfsOpts + " /dev/mapper/cryptroot " + in.Root,
+		"mount -o subvol=@home," + btrfsOpts + " /dev/mapper/cryptroot " + in.target("home"),
+		"mount /dev/sda1 " + in.target("boot"),
+	} {
+		if !slices.Contains(got, want) {
+			t.Errorf("%q missing from %q", want, got)
+		}
+	}
+	for _, c := range r.Calls() {
+		if c.Name == "cryptsetup" && c.Stdin != "secret pw" {
+			t.Errorf("passphrase not on stdin: %+v", c)
+		}
+		if slices.Contains(c.Args, "secret pw") {
+			t.Errorf("passphrase in argv: %s", c)
+		}
+	}
+}
+
+func TestFormatLUKSMapper(t *testing.T) {
+	in, r := newTestInstall(t, testSettings([2]string{"USE_LUKS", "yes"}, [2]string{"LUKS_PASSWORD", "pw"}))
+	in.Mapper = "archgui-test"
+	in.rootPart, in.efiPart, in.formatRoot = "/dev/sda2", "/dev/sda1", true
+	if err := format(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	got := r.Lines()
+	for _, want := range []string{"cryptsetup open /dev/sda2 archgui-test -", "mkfs.ext4 -F /dev/mapper/archgui-test"} {
+		if !slices.Contains(got, want) {
+			t.Errorf("%q missing from %q", want, got)
+		}
+	}
+}
+
+func TestFstabSwapfile(t *testing.T) {
+	in, r := newTestInstall(t, testSettings([2]string{"SWAP_SIZE", "4"}))
+	os.MkdirAll(in.target("etc"), 0o755)
+	if err := fstab(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(in.target("etc/fstab"))
+	if string(raw) != "UUID=1234 / ext4 rw 0 1\n/swapfile none swap defaults 0 0\n" {
+		t.Errorf("fstab %q", raw)
+	}
+	if !slices.Contains(r.Lines(), "fallocate -l 4G "+in.target("swapfile")) {
+		t.Errorf("no swapfile created: %q", r.Lines())
+	}
+	if !slices.Contains(r.Lines(), "chmod 600 "+in.target("swapfile")) {
+		t.Errorf("swapfile not made private: %q", r.Lines())
+	}
+}
+
+func TestPacstrapBatches(t *testing.T) {
+	in, r := newTestInstall(t, testSettings(
+		[2]string{"MULTILIB", "yes"},
+		[2]string{"MIRRORLIST", "Server = https://fast.example/$repo/os/$arch"},
+		[2]string{"GPU_PACKAGES", "mesa vulkan-radeon"},
+		[2]string{"ENABLE_SSHD", "yes"},
+	))
+	os.MkdirAll(in.target("etc"), 0o755)
+	os.WriteFile(in.target("etc/pacman.conf"), []byte("#[multilib]\n#Include = /etc/pacman.d/mirrorlist\n"), 0o644)
+	if err := pacstrap(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	got := r.Lines()
+	want := []string{
+		"pacman -Sy",
+		"pacstrap -K " + in.Root + " base base-devel linux linux-firmware networkmanager grub sudo nano vim git btop efibootmgr",
+		"pacstrap " + in.Root + " mesa vulkan-radeon",
+		"pacstrap " + in.Root + " openssh",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+	if raw, _ := os.ReadFile(in.live("etc/pacman.d/mirrorlist.archgui-bak")); !strings.Contains(string(raw), "geo.mirror") {
+		t.Errorf("live mirrorlist not backed up: %q", raw)
+	}
+	if raw, _ := os.ReadFile(in.target("etc/pacman.d/mirrorlist")); !strings.Contains(string(raw), "fast.example") {
+		t.Errorf("target mirrorlist %q", raw)
+	}
+	if raw, _ := os.ReadFile(in.target("etc/pacman.conf")); !strings.HasPrefix(string(raw), "[multilib]\nInclude") {
+		t.Errorf("multilib not enabled on the target: %q", raw)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	in, r := newTestInstall(t, testSettings(
+		[2]string{"EXTRA_SERVICES", "cups"},
+		[2]string{"DISPLAY_MANAGER", "gdm"},
+		[2]string{"INITRAMFS_MODULES", "nvidia nvidia_drm"},
+		[2]string{"REMOVE_KMS_HOOK", "yes"},
+		[2]string{"USER_0_SSH_KEYS", "ssh-ed25519 AAAA alice@laptop"},
+		[2]string{"ENABLE_SSHD", "yes"},
+		[2]string{"SSH_DISABLE_PASSWORDS", "yes"},
+	))
+	os.MkdirAll(in.target("etc"), 0o755)
+	os.WriteFile(in.target("etc/mkinitcpio.conf"), []byte("MODULES=()\nHOOKS=(base udev autodetect modconf kms keyboard block filesystems fsck)\n"), 0o644)
+	if err := configure(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+
+	got := r.Lines()
+	root := in.Root
+	for _, want := range []string{
+		"arch-chroot " + root + " visudo -cf /root/10-wheel.sudoers",
+		"arch-chroot " + root + " ln -sf /usr/share/zoneinfo/UTC /etc/localtime",
+		"arch-chroot " + root + " systemctl enable NetworkManager",
+		"arch-chroot " + root + " systemctl enable cups",
+		"arch-chroot " + root + " systemctl enable gdm",
+		"arch-chroot " + root + " mkinitcpio -P",
+		"arch-chroot " + root + " useradd -m -c 'Alice Smith' -s /bin/bash -G wheel,audio,video alice",
+		"arch-chroot " + root + " chown -R alice: /home/alice/.ssh",
+		"arch-chroot " + root + " systemctl enable sshd",
+	} {
+		if !slices.Contains(got, want) {
+			t.Errorf("%q missing from %q", want, got)
+		}
+	}
+	var stdin []string
+	for _, c := range r.Calls() {
+		if c.Stdin != "" {
+			stdin = append(stdin, c.Stdin)
+		}
+	}
+	if !slices.Equal(stdin, []string{"alice:pa$$ word\n", "root:rootpw\n"}) {
+		t.Errorf("passwords on stdin %q", stdin)
+	}
+
+	for file, want := range map[string]string{
+		"etc/hostname":                          "archlinux\n",
+		"etc/locale.gen":                        "en_US.UTF-8 UTF-8\n",
+		"etc/sudoers.d/10-wheel":                "%wheel ALL=(ALL:ALL) ALL\n",
+		"etc/mkinitcpio.conf":                   "MODULES=(nvidia nvidia_drm)\nHOOKS=(base udev autodetect modconf keyboard block filesystems fsck)\n",
+		"home/alice/.ssh/authorized_keys":       "ssh-ed25519 AAAA alice@laptop\n",
+		"etc/ssh/sshd_config.d/20-archgui.conf": "# Written by the Arch Linux GUI installer: key-only logins\nPasswordAuthentication no\nKbdInteractiveAuthentication no\n",
+	} {
+		if raw, err := os.ReadFile(in.target(file)); err != nil || string(raw) != want {
+			t.Errorf("%s = %q, %v", file, raw, err)
+		}
+	}
+	if fi, _ := os.Stat(in.target("home/alice/.ssh/authorized_keys")); fi.Mode().Perm() != 0o600 {
+		t.Errorf("authorized_keys mode %v", fi.Mode())
+	}
+}
+
+func TestConfigureStopsOnInvalidSudoers(t *testing.T) {
+	in, r := newTestInstall(t, testSettings())
+	r.Replies = []command.Reply{{Prefix: "arch-chroot", Err: errFailed}}
+	if err := configure(context.Background(), in); err == nil || !strings.Contains(err.Error(), "sudoers") {
+		t.Errorf("got %v", err)
+	}
+	if _, err := os.Stat(in.target("etc/sudoers.d/10-wheel")); err == nil {
+		t.Error("invalid drop-in was installed")
+	}
+}
+
+func TestBootloader(t *testing.T) {
+	in, r := newTestInstall(t, testSettings([2]string{"USE_LUKS", "yes"}, [2]string{"LUKS_PASSWORD", "x"}, [2]string{"KERNEL_PARAMS", "nvidia_drm.modeset=1"}))
+	in.rootPart = "/dev/nvme0n1p2"
+	os.MkdirAll(in.target("etc/default"), 0o755)
+	os.WriteFile(in.target("etc/default/grub"), []byte("GRUB_CMDLINE_LINUX_DEFAULT=\"loglevel=3 quiet\"\nGRUB_CMDLINE_LINUX=\"\"\n"), 0o644)
+	if err := bootloader(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(in.target("etc/default/grub"))
+	want := "GRUB_CMDLINE_LINUX_DEFAULT=\"loglevel=3 quiet nvidia_drm.modeset=1\"\n" +
+		"GRUB_CMDLINE_LINUX=\"cryptdevice=UUID=5678:cryptroot root=/dev/mapper/cryptroot\"\n" +
+		"GRUB_ENABLE_CRYPTODISK=y\n"
+	if string(raw) != want {
+		t.Errorf("grub defaults %q", raw)
+	}
+	got := r.Lines()
+	if !slices.Contains(got, "arch-chroot "+in.Root+" grub-install --target=x86_64-efi --efi-directory=/boot --bootloader-id=ARCH") {
+		t.Errorf("no UEFI grub-install in %q", got)
+	}
+
+	// BIOS from a UEFI live system, manual partitioning: the disk comes from lsblk
+	in, r = newTestInstall(t, testSettings([2]string{"BOOT_MODE", "bios"}, [2]string{"DISK", ""}, [2]string{"MANUAL_PARTITIONING", "yes"}, [2]string{"TARGET_ROOT", "/dev/sda1"}))
+	in.rootPart = "/dev/sda1"
+	if err := bootloader(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(r.Lines(), "arch-chroot "+in.Root+" grub-install --target=i386-pc /dev/sda") {
+		t.Errorf("no BIOS grub-install in %q", r.Lines())
+	}
+}
+
+func TestEnableMultilib(t *testing.T) {
+	conf := "[core]\nInclude = /etc/pacman.d/mirrorlist\n\n#[multilib]\n#Include = /etc/pacman.d/mirrorlist\n\n#[custom]\n#Server = file:///home\n"
+	want := "[core]\nInclude = /etc/pacman.d/mirrorlist\n\n[multilib]\nInclude = /etc/pacman.d/mirrorlist\n\n#[custom]\n#Server = file:///home\n"
+	if got := enableMultilib(conf); got != want {
+		t.Errorf("got %q", got)
+	}
+	if got := enableMultilib(want); got != want {
+		t.Errorf("enabled twice: %q", got)
+	}
+}
+
+func TestInstallRunsAllSteps(t *testing.T) {
+	in, r := newTestInstall(t, testSettings())
+	var logs []string
+	in.Native.Log = func(s string) { logs = append(logs, s) }
+	if err := in.Native.Install(context.Background(), in.s); err != nil {
+		t.Fatal(err)
+	}
+	got := r.Lines()
+	if got[0] != "wipefs -af /dev/nvme0n1" || got[len(got)-1] != "arch-chroot "+in.Root+" grub-mkconfig -o /boot/grub/grub.cfg" {
+		t.Errorf("unexpected order %q", got)
+	}
+	if r.Calls()[0].Output == nil {
+		t.Error("command output does not reach the log")
+	}
+	if logs[len(logs)-1] != "Installation Complete!" {
+		t.Errorf("last log %q", logs[len(logs)-1])
+	}
+
+	r.Replies = []command.Reply{{Prefix: "pacstrap", Err: errFailed}}
+	if err := in.Native.Install(context.Background(), in.s); err == nil || !strings.HasPrefix(err.Error(), "pacstrap: ") {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package installer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Settings are the resolved installation settings under the keys of the
+// backend's env file, so every engine reads the same values
+type Settings struct {
+	keys   []string
+	values map[string]string
+}
+
+// NewSettings keeps the order of vars for the env file
+func NewSettings(vars [][2]string) Settings {
+	s := Settings{values: make(map[string]string, len(vars))}
+	for _, kv := range vars {
+		if _, ok := s.values[kv[0]]; !ok {
+			s.keys = append(s.keys, kv[0])
+		}
+		s.values[kv[0]] = kv[1]
+	}
+	return s
+}
+
+func (s Settings) Get(key string) string {
+	return s.values[key]
+}
+
+// Yes reports whether a yes/no key is "yes"
+func (s Settings) Yes(key string) bool {
+	return s.values[key] == "yes"
+}
+
+// Fields splits a space separated list such as GPU_PACKAGES
+func (s Settings) Fields(key string) []string {
+	return strings.Fields(s.values[key])
+}
+
+// Env renders the settings as the env file the script sources
+func (s Settings) Env() string {
+	var b strings.Builder
+	for _, k := range s.keys {
+		b.WriteString(k + "=" + ShellQuote(s.values[k]) + "\n")
+	}
+	return b.String()
+}
+
+// shellSafe matches values that need no quoting when the env file is sourced
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]*$`)
+
+// ShellQuote single-quotes a value for bash, so spaces, $ and quotes in
+// names or passwords survive `source`
+func ShellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// account is one USER_<i>_* entry
+type account struct {
+	Name     string
+	FullName string
+	Password string
+	Admin    bool
+	Groups   string // comma separated, without wheel
+	Shell    string // bash, zsh, zsh-ohmyzsh
+	Home     string // "" for /home/<name>
+	SSHKeys  string // authorized_keys content
+}
+
+// accounts reads USER_COUNT and the indexed USER_<i>_* keys
+func (s Settings) accounts() ([]account, error) {
+	n, err := strconv.Atoi(s.Get("USER_COUNT"))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("USER_COUNT must be at least 1")
+	}
+	users := make([]account, n)
+	for i := range users {
+		field := func(f string) string { return s.Get(fmt.Sprintf("USER_%d_%s", i, f)) }
+		users[i] = account{
+			Name:     field("NAME"),
+			FullName: field("FULL_NAME"),
+			Password: field("PASSWORD"),
+			Admin:    field("ADMIN") == "yes",
+			Groups:   field("GROUPS"),
+			Shell:    field("SHELL"),
+			Home:     field("HOME"),
+			SSHKeys:  field("SSH_KEYS"),
+		}
+	}
+	return users, nil
+}
@@ -0,0 +1,37 @@
+package installer
+
+import (
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"":            "",
+		"/dev/sda":    "/dev/sda",
+		"Alice Smith": "'Alice Smith'",
+		"pa$$word":    "'pa$$word'",
+		"it's":        `'it'\''s'`,
+		"$HOME`id`":   "'$HOME`id`'",
+	}
+	for in, want := range tests {
+		if got := ShellQuote(in); got != want {
+			t.Errorf("ShellQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestSettings(t *testing.T) {
+	s := NewSettings([][2]string{
+		{"DISK", "/dev/sda"},
+		{"USE_LUKS", "yes"},
+		{"GPU_PACKAGES", "mesa  vulkan-radeon"},
+		{"HOSTNAME", "my host"},
+	})
+	if !s.Yes("USE_LUKS") || s.Yes("DISK") || len(s.Fields("GPU_PACKAGES")) != 2 {
+		t.Errorf("unexpected values %+v", s)
+	}
+	want := "DISK=/dev/sda\nUSE_LUKS=yes\nGPU_PACKAGES='mesa  vulkan-radeon'\nHOSTNAME='my host'\n"
+	if got := s.Env(); got != want {
+		t.Errorf("Env() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"archgui/gui/internal/command"
+)
+
+// liveFiles are what the native engine reads or edits on the live system
+var liveFiles = []string{
+	"etc/pacman.conf",
+	"etc/pacman.d/mirrorlist",
+	"proc/cpuinfo",
+	"sys/firmware/efi/fw_platform_size",
+}
+
+// targetStubs stand in for files pacstrap would install and later steps edit
+var targetStubs = map[string]string{
+	"etc/mkinitcpio.conf": "MODULES=()\nHOOKS=(base udev autodetect microcode modconf kms keyboard keymap consolefont block filesystems fsck)\n",
+	"etc/default/grub":    "GRUB_CMDLINE_LINUX_DEFAULT=\"loglevel=3 quiet\"\nGRUB_CMDLINE_LINUX=\"\"\n",
+}
+
+// Simulate runs the native engine against a recording runner and returns
+// the commands a real installation would run, in order. File writes go to a
+// scratch copy of the live system and target, nothing on live is touched.
+func Simulate(ctx context.Context, s Settings, live string) ([]command.Cmd, error) {
+	if keys := Unsupported(s); len(keys) > 0 {
+		return nil, fmt.Errorf("the simulation does not support %s yet", strings.Join(keys, ", "))
+	}
+	scratch, err := os.MkdirTemp("", "archgui-simulate-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(scratch)
+	liveCopy, root := filepath.Join(scratch, "live"), filepath.Join(scratch, "mnt")
+
+	files := liveFiles
+	if tz := s.Get("TIMEZONE"); !strings.Contains(tz, "..") {
+		files = append([]string{filepath.Join("usr/share/zoneinfo", tz)}, files...)
+	}
+	for _, f := range files {
+		raw, err := os.ReadFile(filepath.Join(live, f))
+		if err != nil {
+			continue // optional, or reported by the engine's own checks
+		}
+		if err := writeFile(filepath.Join(liveCopy, f), string(raw), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := os.Stat(filepath.Join(live, "sys/firmware/efi/efivars")); err == nil {
+		if err := os.MkdirAll(filepath.Join(liveCopy, "sys/firmware/efi/efivars"), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	if raw, err := os.ReadFile(filepath.Join(live, "etc/pacman.conf")); err == nil {
+		if err := writeFile(filepath.Join(root, "etc/pacman.conf"), string(raw), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	for f, content := range targetStubs {
+		if err := writeFile(filepath.Join(root, f), content, 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	rec := &command.Recorder{Outputs: map[string]string{
+		"blkid":   "<uuid>",
+		"lsblk":   "<disk>",
+		"findmnt": s.Get("FS_TYPE"),
+	}}
+	err = (&Native{Runner: rec, Root: root, Live: liveCopy, skipDevices: true}).Install(ctx, s)
+
+	// Report paths as the real installation would see them
+	cmds := rec.Commands()
+	for i, c := range cmds {
+		args := make([]string, len(c.Args))
+		for j, a := range c.Args {
+			a = strings.Replace(a, root, "/mnt", 1)
+			args[j] = strings.Replace(a, liveCopy, "", 1)
+		}
+		cmds[i].Args = args
+	}
+	return cmds, err
+}
+
+// Simulate is the package level Simulate against the engine's live system
+func (n *Native) Simulate(ctx context.Context, s Settings) ([]command.Cmd, error) {
+	return Simulate(ctx, s, n.Live)
+}
+
+// Destructive reports whether a command erases or rewrites a disk
+func Destructive(c command.Cmd) bool {
+	switch {
+	case c.Name == "wipefs", c.Name == "parted", c.Name == "mkswap", strings.HasPrefix(c.Name, "mkfs."):
+		return true
+	case c.Name == "cryptsetup":
+		return len(c.Args) > 0 && c.Args[0] == "luksFormat"
+	}
+	return false
+}
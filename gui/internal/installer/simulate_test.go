@@ -0,0 +1,78 @@
+package installer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"archgui/gui/internal/command"
+)
+
+func TestSimulate(t *testing.T) {
+	live := t.TempDir()
+	conf := "#[multilib]\n#Include = /etc/pacman.d/mirrorlist\n"
+	for file, content := range map[string]string{
+		"usr/share/zoneinfo/UTC":  "TZif",
+		"etc/pacman.conf":         conf,
+		"etc/pacman.d/mirrorlist": "Server = https://geo.mirror.pkgbuild.com/$repo/os/$arch\n",
+	} {
+		if err := writeFile(filepath.Join(live, file), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.MkdirAll(filepath.Join(live, "sys/firmware/efi/efivars"), 0o755)
+
+	s := testSettings([2]string{"USE_LUKS", "yes"}, [2]string{"LUKS_PASSWORD", "pw"}, [2]string{"MULTILIB", "yes"}, [2]string{"SWAP_SIZE", "2"})
+	cmds, err := Simulate(context.Background(), s, live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines, destructive []string
+	for _, c := range cmds {
+		lines = append(lines, c.String())
+		if Destructive(c) {
+			destructive = append(destructive, c.String())
+		}
+	}
+	wantDestructive := []string{
+		"wipefs -af /dev/nvme0n1",
+		"parted -s /dev/nvme0n1 mklabel gpt",
+		"parted -s /dev/nvme0n1 mkpart EFI fat32 1MiB 513MiB",
+		"parted -s /dev/nvme0n1 set 1 esp on",
+		"parted -s /dev/nvme0n1 mkpart root ext4 513MiB 100%",
+		"mkfs.fat -F32 /dev/nvme0n1p1",
+		"cryptsetup luksFormat --type luks2 /dev/nvme0n1p2 -",
+		"mkfs.ext4 -F /dev/mapper/cryptroot",
+		"mkswap /mnt/swapfile",
+	}
+	if !slices.Equal(destructive, wantDestructive) {
+		t.Errorf("destructive %q\nwant %q", destructive, wantDestructive)
+	}
+	for _, want := range []string{
+		"mount /dev/mapper/cryptroot /mnt",
+		"pacstrap -K /mnt base base-devel linux linux-firmware networkmanager grub sudo nano vim git btop efibootmgr",
+		"arch-chroot /mnt grub-mkconfig -o /boot/grub/grub.cfg",
+	} {
+		if !slices.Contains(lines, want) {
+			t.Errorf("%q missing from %q", want, lines)
+		}
+	}
+	if raw, _ := os.ReadFile(filepath.Join(live, "etc/pacman.conf")); string(raw) != conf {
+		t.Errorf("live pacman.conf changed: %q", raw)
+	}
+
+	if _, err := Simulate(context.Background(), testSettings([2]string{"OFFLINE_REPO", "/repo"}), live); err == nil {
+		t.Error("expected an error for an offline install")
+	}
+}
+
+func TestDestructive(t *testing.T) {
+	if Destructive(command.Cmd{Name: "cryptsetup", Args: []string{"open", "/dev/sda2", "cryptroot", "-"}}) {
+		t.Error("cryptsetup open does not erase anything")
+	}
+	if !Destructive(command.Cmd{Name: "mkfs.btrfs", Args: []string{"-f", "/dev/sda2"}}) {
+		t.Error("mkfs.btrfs erases the partition")
+	}
+}
@@ -0,0 +1,698 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"archgui/gui/internal/command"
+)
+
+const btrfsOpts = "noatime,compress=zstd,space_cache=v2,discard=async"
+
+// btrfsSubvolumes are created on a fresh btrfs root and mounted at their path
+var btrfsSubvolumes = [][2]string{{"@home", "home"}, {"@snapshots", ".snapshots"}, {"@var_log", "var/log"}}
+
+// partition wipes and partitions DISK, or takes the manual targets as they are
+func partition(ctx context.Context, in *install) error {
+	s := in.s
+	if s.Yes("MANUAL_PARTITIONING") {
+		in.logf("Mode: Manual Partitioning")
+		in.rootPart, in.efiPart = s.Get("TARGET_ROOT"), s.Get("TARGET_EFI")
+		in.formatRoot = s.Yes("FORMAT_ROOT")
+		if s.Yes("USE_LUKS") && !in.formatRoot {
+			in.logf("Warning: Encryption requested but Format Root is No. Forcing Format for LUKS setup safety.")
+			in.formatRoot = true
+		}
+		return nil
+	}
+
+	disk := s.Get("DISK")
+	in.logf("Mode: Auto Partitioning on %s", disk)
+	in.logf("Wiping %s...", disk)
+	if err := in.run(ctx, "wipefs", "-af", disk); err != nil {
+		return err
+	}
+	// Names ending in a digit (nvme0n1, mmcblk0, loop0) get a "p" before the number
+	prefix := disk
+	if last := disk[len(disk)-1]; last >= '0' && last <= '9' {
+		prefix += "p"
+	}
+	fs := s.Get("FS_TYPE")
+	var layout [][]string
+	if in.bootMode == "uefi" {
+		layout = [][]string{
+			{"mklabel", "gpt"},
+			{"mkpart", "EFI", "fat32", "1MiB", "513MiB"},
+			{"set", "1", "esp", "on"},
+			{"mkpart", "root", fs, "513MiB", "100%"},
+		}
+		in.efiPart, in.rootPart = prefix+"1", prefix+"2"
+	} else {
+		layout = [][]string{
+			{"mklabel", "msdos"},
+			{"mkpart", "primary", fs, "1MiB", "100%"},
+			{"set", "1", "boot", "on"},
+		}
+		in.rootPart = prefix + "1"
+	}
+	for _, args := range layout {
+		if err := in.run(ctx, "parted", append([]string{"-s", disk}, args...)...); err != nil {
+			return err
+		}
+	}
+	// The partition nodes appear asynchronously
+	_ = in.run(ctx, "partprobe", disk)
+	_ = in.run(ctx, "udevadm", "settle")
+	in.formatRoot = true
+	return nil
+}
+
+// format creates the EFI filesystem, the LUKS container and the root
+// filesystem with its btrfs subvolumes
+func format(ctx context.Context, in *install) error {
+	s := in.s
+	if in.bootMode == "uefi" && in.efiPart != "" && (!s.Yes("MANUAL_PARTITIONING") || s.Yes("FORMAT_EFI")) {
+		in.logf("Formatting EFI partition %s...", in.efiPart)
+		if err := in.run(ctx, "mkfs.fat", "-F32", in.efiPart); err != nil {
+			return err
+		}
+	}
+
+	in.rootDev = in.rootPart
+	if s.Yes("USE_LUKS") && in.formatRoot {
+		in.logf("Encrypting root partition %s...", in.rootPart)
+		pass := s.Get("LUKS_PASSWORD")
+		for _, args := range [][]string{
+			{"luksFormat", "--type", "luks2", in.rootPart, "-"},
+			{"open", in.rootPart, in.mapper(), "-"},
+		} {
+			if _, err := in.exec(ctx, command.Cmd{Name: "cryptsetup", Args: args, Stdin: pass}); err != nil {
+				return err
+			}
+		}
+		in.rootDev = "/dev/mapper/" + in.mapper()
+	}
+
+	if !in.formatRoot {
+		return nil
+	}
+	if s.Get("FS_TYPE") != "btrfs" {
+		in.logf("Formatting EXT4...")
+		return in.run(ctx, "mkfs.ext4", "-F", in.rootDev)
+	}
+	in.logf("Formatting BTRFS...")
+	if err := in.run(ctx, "mkfs.btrfs", "-f", in.rootDev); err != nil {
+		return err
+	}
+	if err := in.run(ctx, "mount", in.rootDev, in.Root); err != nil {
+		return err
+	}
+	for _, sub := range append([][2]string{{"@", ""}}, btrfsSubvolumes...) {
+		if err := in.run(ctx, "btrfs", "subvolume", "create", in.target(sub[0])); err != nil {
+			return err
+		}
+	}
+	return in.run(ctx, "umount", in.Root)
+}
+
+// mount assembles the target under Root
+func mount(ctx context.Context, in *install) error {
+	if in.formatRoot && in.s.Get("FS_TYPE") == "btrfs" {
+		if err := in.run(ctx, "mount", "-o", "subvol=@,"+btrfsOpts, in.rootDev, in.Root); err != nil {
+			return err
+		}
+		for _, sub := range btrfsSubvolumes {
+			if err := os.MkdirAll(in.target(sub[1]), 0o755); err != nil {
+				return err
+			}
+			if err := in.run(ctx, "mount", "-o", "subvol="+sub[0]+","+btrfsOpts, in.rootDev, in.target(sub[1])); err != nil {
+				return err
+			}
+		}
+	} else {
+		if !in.formatRoot {
+			in.logf("Mounting existing root partition without formatting...")
+		}
+		if err := in.run(ctx, "mount", in.rootDev, in.Root); err != nil {
+			return err
+		}
+	}
+
+	if in.bootMode == "uefi" {
+		if err := os.MkdirAll(in.target("boot"), 0o755); err != nil {
+			return err
+		}
+		if in.efiPart != "" {
+			return in.run(ctx, "mount", in.efiPart, in.target("boot"))
+		}
+	}
+	return nil
+}
+
+// basePackages mirrors base_packages in arch-install.sh
+func (in *install) basePackages() []string {
+	s := in.s
+	pkgs := []string{"base", "base-devel", s.Get("KERNEL"), "linux-firmware", "networkmanager", "grub", "sudo", "nano", "vim", "git", "btop"}
+	microcode := s.Get("MICROCODE")
+	if microcode == "" {
+		raw, _ := os.ReadFile(in.live("proc/cpuinfo"))
+		switch {
+		case strings.Contains(string(raw), "GenuineIntel"):
+			microcode = "intel-ucode"
+		case strings.Contains(string(raw), "AuthenticAMD"):
+			microcode = "amd-ucode"
+		}
+	}
+	if microcode != "" && microcode != "none" {
+		pkgs = append(pkgs, microcode)
+	}
+	if s.Yes("BLUETOOTH") {
+		pkgs = append(pkgs, "bluez", "bluez-utils")
+	}
+	if p := s.Get("POWER_PROFILE"); p != "" && p != "none" {
+		pkgs = append(pkgs, p)
+	}
+	if s.Get("FS_TYPE") == "btrfs" {
+		pkgs = append(pkgs, "btrfs-progs")
+	}
+	if in.bootMode == "uefi" {
+		pkgs = append(pkgs, "efibootmgr")
+	}
+	return pkgs
+}
+
+// anyShell reports whether some account's shell choice has the prefix
+func (in *install) anyShell(prefix string) bool {
+	for _, u := range in.users {
+		if strings.HasPrefix(u.Shell, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// enableMultilib uncomments the [multilib] section of a pacman.conf
+func enableMultilib(conf string) string {
+	lines := strings.Split(conf, "\n")
+	for _, l := range lines {
+		if l == "[multilib]" {
+			return conf
+		}
+	}
+	inSection := false
+	for i, l := range lines {
+		if l == "#[multilib]" {
+			inSection = true
+		}
+		if inSection {
+			lines[i] = strings.TrimPrefix(l, "#")
+			if strings.HasPrefix(l, "#Include") {
+				inSection = false
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func enableMultilibFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(enableMultilib(string(raw))), 0o644)
+}
+
+// pacstrap installs the package sets in the same batches as install_packages
+func pacstrap(ctx context.Context, in *install) error {
+	s := in.s
+	if list := s.Get("MIRRORLIST"); list != "" {
+		in.logf("Writing mirrorlist...")
+		live, backup := in.live("etc/pacman.d/mirrorlist"), in.live("etc/pacman.d/mirrorlist.archgui-bak")
+		if _, err := os.Stat(backup); err != nil {
+			raw, err := os.ReadFile(live)
+			if err != nil {
+				return err
+			}
+			if err := writeFile(backup, string(raw), 0o644); err != nil {
+				return err
+			}
+		}
+		if err := writeFile(live, list+"\n", 0o644); err != nil {
+			return err
+		}
+		in.targetMirrorlist = list
+	}
+
+	if s.Yes("MULTILIB") {
+		in.logf("Enabling multilib repository...")
+		if err := enableMultilibFile(in.live("etc/pacman.conf")); err != nil {
+			return err
+		}
+		if err := in.run(ctx, "pacman", "-Sy"); err != nil {
+			return err
+		}
+	}
+
+	in.logf("Installing base system...")
+	if err := in.run(ctx, "pacstrap", append([]string{"-K", in.Root}, in.basePackages()...)...); err != nil {
+		return err
+	}
+	if in.targetMirrorlist != "" {
+		if err := writeFile(in.target("etc/pacman.d/mirrorlist"), in.targetMirrorlist+"\n", 0o644); err != nil {
+			return err
+		}
+	}
+	if s.Yes("MULTILIB") {
+		if err := enableMultilibFile(in.target("etc/pacman.conf")); err != nil {
+			return err
+		}
+	}
+
+	if gpu := s.Get("GPU_AUR_PACKAGES"); gpu != "" && s.Get("AUR_HELPER") == "none" {
+		in.logf("Warning: %s are only available from the AUR and were not installed.", gpu)
+	}
+	var batches [][]string
+	batches = append(batches, s.Fields("GPU_PACKAGES"))
+	if d := s.Get("DESKTOP_ENV"); d != "" && d != "none" {
+		in.logf("Installing Desktop: %s", d)
+		batches = append(batches, s.Fields("DESKTOP_PACKAGES"))
+	}
+	batches = append(batches, s.Fields("EXTRA_PACKAGES"))
+	if in.anyShell("zsh") {
+		batches = append(batches, []string{"zsh", "zsh-completions"})
+	}
+	if in.anyShell("zsh-ohmyzsh") {
+		batches = append(batches, []string{"git", "curl"})
+	}
+	if t := s.Get("PRIVILEGE_TOOL"); t == "doas" || t == "both" {
+		batches = append(batches, []string{"opendoas"})
+	}
+	if s.Yes("ENABLE_SSHD") {
+		batches = append(batches, []string{"openssh"})
+	}
+	for _, pkgs := range batches {
+		if len(pkgs) == 0 {
+			continue
+		}
+		if err := in.run(ctx, "pacstrap", append([]string{in.Root}, pkgs...)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fstab writes the mounts by UUID and adds the swapfile
+func fstab(ctx context.Context, in *install) error {
+	out, err := in.exec(ctx, command.Cmd{Name: "genfstab", Args: []string{"-U", in.Root}})
+	if err != nil {
+		return err
+	}
+	if err := appendFile(in.target("etc/fstab"), string(out)); err != nil {
+		return err
+	}
+
+	size := in.s.Get("SWAP_SIZE")
+	if size == "" || size == "0" {
+		return nil
+	}
+	in.logf("Creating %s GiB swapfile...", size)
+	fsType, err := in.output(ctx, "findmnt", "-n", "-o", "FSTYPE", in.Root)
+	if err != nil {
+		return err
+	}
+	if fsType == "btrfs" {
+		// A nested subvolume is skipped by snapshots of @, mkswapfile sets NOCOW
+		if err := in.run(ctx, "btrfs", "subvolume", "create", in.target("swap")); err != nil {
+			return err
+		}
+		if err := in.run(ctx, "btrfs", "filesystem", "mkswapfile", "--size", size+"g", "--uuid", "clear", in.target("swap/swapfile")); err != nil {
+			return err
+		}
+		return appendFile(in.target("etc/fstab"), "/swap/swapfile none swap defaults 0 0\n")
+	}
+	if err := in.run(ctx, "fallocate", "-l", size+"G", in.target("swapfile")); err != nil {
+		return err
+	}
+	if err := in.run(ctx, "chmod", "600", in.target("swapfile")); err != nil {
+		return err
+	}
+	if err := in.run(ctx, "mkswap", in.target("swapfile")); err != nil {
+		return err
+	}
+	return appendFile(in.target("etc/fstab"), "/swapfile none swap defaults 0 0\n")
+}
+
+// configure sets up locale, keyboard, time, services, the initramfs and
+// the accounts
+func configure(ctx context.Context, in *install) error {
+	s := in.s
+	in.logf("Configuring system...")
+
+	locale, localeGen, localeConf := s.Get("LOCALE"), s.Get("LOCALE_GEN"), s.Get("LOCALE_CONF")
+	if localeGen == "" {
+		// Older configs passed "en_US" and relied on .UTF-8 being appended
+		if !strings.ContainsAny(locale, ".@") {
+			locale += ".UTF-8"
+		}
+		localeGen = locale + " " + locale[strings.LastIndex(locale, ".")+1:]
+	}
+	if localeConf == "" {
+		localeConf = "LANG=" + locale
+	}
+	files := [][2]string{
+		{"etc/locale.gen", localeGen + "\n"},
+		{"etc/locale.conf", localeConf + "\n"},
+		{"etc/vconsole.conf", "KEYMAP=" + s.Get("KEYMAP") + "\n"},
+		{"etc/hostname", s.Get("HOSTNAME") + "\n"},
+	}
+	if x11 := s.Get("X11_KEYBOARD_CONF"); x11 != "" {
+		files = append(files, [2]string{"etc/X11/xorg.conf.d/00-keyboard.conf", x11})
+	}
+	for _, f := range files {
+		if err := writeFile(in.target(f[0]), f[1], 0o644); err != nil {
+			return err
+		}
+	}
+
+	if err := configurePrivileges(ctx, in); err != nil {
+		return err
+	}
+
+	if err := in.chroot(ctx, "ln", "-sf", "/usr/share/zoneinfo/"+s.Get("TIMEZONE"), "/etc/localtime"); err != nil {
+		return err
+	}
+	if err := in.chroot(ctx, "hwclock", "--systohc"); err != nil {
+		return err
+	}
+	if err := in.chroot(ctx, "locale-gen"); err != nil {
+		return err
+	}
+
+	units := append([]string{"NetworkManager"}, s.Fields("EXTRA_SERVICES")...)
+	if s.Yes("BLUETOOTH") {
+		units = append(units, "bluetooth")
+	}
+	switch s.Get("POWER_PROFILE") {
+	case "tlp":
+		units = append(units, "tlp")
+		// TLP manages radios itself, see the TLP installation notes
+		if err := in.chroot(ctx, "systemctl", "mask", "systemd-rfkill.service", "systemd-rfkill.socket"); err != nil {
+			return err
+		}
+	case "power-profiles-daemon":
+		units = append(units, "power-profiles-daemon")
+	}
+	if dm := s.Get("DISPLAY_MANAGER"); dm != "" {
+		units = append(units, dm)
+	}
+	for _, u := range units {
+		if err := in.chroot(ctx, "systemctl", "enable", u); err != nil {
+			return err
+		}
+	}
+
+	if err := configureInitramfs(ctx, in); err != nil {
+		return err
+	}
+	if err := createUsers(ctx, in); err != nil {
+		return err
+	}
+	if err := configureSSHD(ctx, in); err != nil {
+		return err
+	}
+	if name, profile := s.Get("WIFI_PROFILE_NAME"), s.Get("WIFI_PROFILE"); profile != "" {
+		in.logf("Copying Wi-Fi profile...")
+		dir := in.target("etc/NetworkManager/system-connections")
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+		if err := os.Chmod(dir, 0o700); err != nil {
+			return err
+		}
+		if err := writeFile(path.Join(dir, name), profile, 0o600); err != nil {
+			return err
+		}
+	}
+
+	in.logf("Setting root password...")
+	if s.Yes("ROOT_LOCKED") {
+		return in.chroot(ctx, "passwd", "-l", "root")
+	}
+	return in.chrootStdin(ctx, "root:"+s.Get("ROOT_PASSWORD")+"\n", "chpasswd")
+}
+
+// configurePrivileges writes the sudoers drop-in and doas.conf, each checked
+// with the target's own tool before it takes effect
+func configurePrivileges(ctx context.Context, in *install) error {
+	sudoers, doas := in.s.Get("SUDOERS_DROPIN"), in.s.Get("DOAS_CONF")
+	if sudoers == "" && doas == "" && in.s.Get("PRIVILEGE_TOOL") == "sudo" {
+		sudoers = "%wheel ALL=(ALL:ALL) ALL"
+	}
+	if sudoers != "" {
+		in.logf("Writing sudoers drop-in...")
+		// Staged outside sudoers.d; sudo ignores names with a dot, not broken content
+		if err := writeFile(in.target("root/10-wheel.sudoers"), sudoers+"\n", 0o440); err != nil {
+			return err
+		}
+		if err := in.chroot(ctx, "visudo", "-cf", "/root/10-wheel.sudoers"); err != nil {
+			return fmt.Errorf("generated sudoers drop-in is invalid: %w", err)
+		}
+		if err := os.MkdirAll(in.target("etc/sudoers.d"), 0o750); err != nil {
+			return err
+		}
+		if err := os.Rename(in.target("root/10-wheel.sudoers"), in.target("etc/sudoers.d/10-wheel")); err != nil {
+			return err
+		}
+	}
+	if doas != "" {
+		in.logf("Writing doas.conf...")
+		if err := writeFile(in.target("etc/doas.conf"), doas+"\n", 0o400); err != nil {
+			return err
+		}
+		if err := in.chroot(ctx, "doas", "-C", "/etc/doas.conf"); err != nil {
+			return fmt.Errorf("generated doas.conf is invalid: %w", err)
+		}
+	}
+	return nil
+}
+
+var modulesLine = regexp.MustCompile(`^MODULES=\((.*)\)$`)
+
+const encryptHooks = "HOOKS=(base udev autodetect modconf kms keyboard keymap consolefont block encrypt filesystems fsck)"
+
+// configureInitramfs adds the encrypt hook and early KMS modules to
+// mkinitcpio.conf and rebuilds the images when it changed
+func configureInitramfs(ctx context.Context, in *install) error {
+	s := in.s
+	modules, removeKMS := s.Get("INITRAMFS_MODULES"), s.Yes("REMOVE_KMS_HOOK")
+	if !s.Yes("USE_LUKS") && modules == "" && !removeKMS {
+		return nil
+	}
+	err := editLines(in.target("etc/mkinitcpio.conf"), func(l string) string {
+		if strings.HasPrefix(l, "HOOKS=") {
+			if s.Yes("USE_LUKS") {
+				l = encryptHooks
+			}
+			if removeKMS {
+				l = strings.Replace(l, " kms", "", 1)
+			}
+		}
+		if m := modulesLine.FindStringSubmatch(l); m != nil && modules != "" {
+			l = "MODULES=(" + strings.TrimSpace(m[1]+" "+modules) + ")"
+		}
+		return l
+	})
+	if err != nil {
+		return err
+	}
+	return in.chroot(ctx, "mkinitcpio", "-P")
+}
+
+// createUsers adds the accounts; passwords go to chpasswd on stdin
+func createUsers(ctx context.Context, in *install) error {
+	for _, u := range in.users {
+		groups := u.Groups
+		if u.Admin {
+			groups = strings.TrimSuffix("wheel,"+groups, ",")
+		}
+		shell := "/bin/bash"
+		if strings.HasPrefix(u.Shell, "zsh") {
+			shell = "/bin/zsh"
+		}
+		args := []string{"useradd", "-m", "-c", u.FullName, "-s", shell}
+		if groups != "" {
+			args = append(args, "-G", groups)
+		}
+		home := u.Home
+		if home != "" {
+			if err := in.chroot(ctx, "mkdir", "-p", path.Dir(home)); err != nil {
+				return err
+			}
+			args = append(args, "-d", home)
+		} else {
+			home = "/home/" + u.Name
+		}
+
+		in.logf("Creating user %s (groups: %s)...", u.Name, groups)
+		if err := in.chroot(ctx, append(args, u.Name)...); err != nil {
+			return err
+		}
+		if err := in.chrootStdin(ctx, u.Name+":"+u.Password+"\n", "chpasswd"); err != nil {
+			return err
+		}
+
+		if u.SSHKeys != "" {
+			in.logf("Installing SSH keys for %s...", u.Name)
+			if err := os.MkdirAll(in.target(home, ".ssh"), 0o700); err != nil {
+				return err
+			}
+			if err := writeFile(in.target(home, ".ssh/authorized_keys"), u.SSHKeys+"\n", 0o600); err != nil {
+				return err
+			}
+			// Owner is resolved inside the target, its UIDs differ from the live system
+			if err := in.chroot(ctx, "chown", "-R", u.Name+":", home+"/.ssh"); err != nil {
+				return err
+			}
+		}
+
+		if u.Shell == "zsh-ohmyzsh" {
+			in.logf("Installing Oh-My-Zsh for user %s...", u.Name)
+			if err := in.chroot(ctx, "su", "-", u.Name, "-c", `sh -c "$(curl -fsSL https://raw.githubusercontent.com/ohmyzsh/ohmyzsh/master/tools/install.sh)" "" --unattended`); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// configureSSHD enables sshd and, if asked, turns off password logins
+func configureSSHD(ctx context.Context, in *install) error {
+	if !in.s.Yes("ENABLE_SSHD") {
+		return nil
+	}
+	in.logf("Enabling SSH server...")
+	if in.s.Yes("SSH_DISABLE_PASSWORDS") {
+		conf := "# Written by the Arch Linux GUI installer: key-only logins\nPasswordAuthentication no\nKbdInteractiveAuthentication no\n"
+		if err := writeFile(in.target("etc/ssh/sshd_config.d/20-archgui.conf"), conf, 0o644); err != nil {
+			return err
+		}
+	}
+	return in.chroot(ctx, "systemctl", "enable", "sshd")
+}
+
+var (
+	grubCmdline        = regexp.MustCompile(`^GRUB_CMDLINE_LINUX=""$`)
+	grubCmdlineDefault = regexp.MustCompile(`^GRUB_CMDLINE_LINUX_DEFAULT="(.*)"$`)
+)
+
+// bootloader installs GRUB for the boot mode and writes its config
+func bootloader(ctx context.Context, in *install) error {
+	s := in.s
+	var cryptdevice string
+	if s.Yes("USE_LUKS") {
+		uuid, err := in.output(ctx, "blkid", "-s", "UUID", "-o", "value", in.rootPart)
+		if err != nil {
+			return err
+		}
+		cryptdevice = "cryptdevice=UUID=" + uuid + ":" + in.mapper() + " root=/dev/mapper/" + in.mapper()
+	}
+	params := s.Get("KERNEL_PARAMS")
+	if cryptdevice != "" || params != "" {
+		grub := in.target("etc/default/grub")
+		err := editLines(grub, func(l string) string {
+			if cryptdevice != "" && grubCmdline.MatchString(l) {
+				return `GRUB_CMDLINE_LINUX="` + cryptdevice + `"`
+			}
+			if m := grubCmdlineDefault.FindStringSubmatch(l); m != nil && params != "" {
+				return `GRUB_CMDLINE_LINUX_DEFAULT="` + m[1] + " " + params + `"`
+			}
+			return l
+		})
+		if err != nil {
+			return err
+		}
+		if cryptdevice != "" {
+			if err := appendFile(grub, "GRUB_ENABLE_CRYPTODISK=y\n"); err != nil {
+				return err
+			}
+		}
+	}
+
+	if in.bootMode == "uefi" {
+		target := "x86_64-efi"
+		if in.uefiBits == "32" {
+			target = "i386-efi"
+		}
+		args := []string{"grub-install", "--target=" + target, "--efi-directory=/boot", "--bootloader-id=ARCH"}
+		// Installing for another machine: NVRAM entries would land on this one
+		if in.removable {
+			args = append(args, "--removable")
+		}
+		if err := in.chroot(ctx, args...); err != nil {
+			return err
+		}
+	} else {
+		disk := s.Get("DISK")
+		if disk == "" {
+			parent, err := in.output(ctx, "lsblk", "-no", "pkname", in.rootPart)
+			if err != nil {
+				return err
+			}
+			disk = "/dev/" + strings.SplitN(parent, "\n", 2)[0]
+		}
+		if err := in.chroot(ctx, "grub-install", "--target=i386-pc", disk); err != nil {
+			return err
+		}
+	}
+	return in.chroot(ctx, "grub-mkconfig", "-o", "/boot/grub/grub.cfg")
+}
+
+// aur builds yay or paru as the first user and installs the AUR packages
+// with it. Failures are reported as warnings, they do not fail the install.
+func aur(ctx context.Context, in *install) error {
+	s := in.s
+	helper := s.Get("AUR_HELPER")
+	if helper == "" || helper == "none" {
+		return nil
+	}
+	user := in.users[0].Name
+	pkgs := append(s.Fields("GPU_AUR_PACKAGES"), s.Fields("AUR_PACKAGES")...)
+
+	in.logf("Building %s as %s...", helper, user)
+	if err := in.chroot(ctx, "pacman", "-S", "--noconfirm", "--needed", "git", "base-devel"); err != nil {
+		in.logf("Warning: could not install git and base-devel, skipping the AUR")
+		return nil
+	}
+
+	// makepkg -si and the helper call sudo pacman; allowed without a password
+	// only while this step runs
+	sudoers := in.target("etc/sudoers.d/90-archgui-aur")
+	if err := writeFile(sudoers, user+" ALL=(ALL) NOPASSWD: /usr/bin/pacman\n", 0o440); err != nil {
+		return err
+	}
+	defer os.Remove(sudoers)
+	defer os.RemoveAll(in.target("tmp/aur-helper"))
+
+	var failed []string
+	// The -bin packages avoid compiling Go/Rust in the chroot
+	build := "rm -rf /tmp/aur-helper && git clone https://aur.archlinux.org/" + helper + "-bin.git /tmp/aur-helper && cd /tmp/aur-helper && makepkg -si --noconfirm"
+	if err := in.chroot(ctx, "su", "-", user, "-c", build); err != nil {
+		failed = append([]string{helper}, pkgs...)
+	} else {
+		for _, pkg := range pkgs {
+			in.logf("Installing %s from the AUR...", pkg)
+			if err := in.chroot(ctx, "su", "-", user, "-c", helper+" -S --noconfirm --needed "+pkg); err != nil {
+				failed = append(failed, pkg)
+			}
+		}
+	}
+	if len(failed) > 0 {
+		in.logf("Warning: AUR packages not installed: %s", strings.Join(failed, " "))
+		in.logf("Warning: install them after the first boot with %s -S", helper)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package mirrors
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Benchmarks download the core database, small enough for a quick test and
+// present on every mirror
+const (
+	benchRepo = "core"
+	benchArch = "x86_64"
+)
+
+// Result is the outcome of benchmarking one mirror
+type Result struct {
+	Mirror   Mirror
+	Duration time.Duration
+	Bytes    int64
+	Err      error
+}
+
+// Rate is the download speed in bytes per second, 0 for failures
+func (r Result) Rate() float64 {
+	if r.Err != nil || r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / r.Duration.Seconds()
+}
+
+// dbURL expands a Server value to the core database URL
+func dbURL(server string) string {
+	u := strings.NewReplacer("$repo", benchRepo, "$arch", benchArch).Replace(server)
+	return strings.TrimSuffix(u, "/") + "/" + benchRepo + ".db"
+}
+
+// Benchmark downloads from up to workers mirrors at a time, each limited
+// to timeout. progress, if set, is called from the worker goroutines after
+// every mirror. Results are in the order of mirrors.
+func Benchmark(ctx context.Context, mirrors []Mirror, workers int, timeout time.Duration, progress func(done, total int)) []Result {
+	results := make([]Result, len(mirrors))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = benchmarkOne(ctx, mirrors[i], timeout)
+				if progress != nil {
+					mu.Lock()
+					done++
+					progress(done, len(mirrors))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range mirrors {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func benchmarkOne(ctx context.Context, m Mirror, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	r := Result{Mirror: m}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dbURL(m.Server), nil)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		r.Err = fmt.Errorf("HTTP %d", resp.StatusCode)
+		return r
+	}
+	r.Bytes, r.Err = io.Copy(io.Discard, resp.Body)
+	r.Duration = time.Since(start)
+	return r
+}
+
+// Probe checks that a server, e.g. a caching proxy, serves the core
+// database within timeout
+func Probe(ctx context.Context, server string, timeout time.Duration) error {
+	return benchmarkOne(ctx, Mirror{Server: server}, timeout).Err
+}
+
+// Rank orders results fastest first; failed mirrors go last in their
+// original order
+func Rank(results []Result) []Result {
+	ranked := slices.Clone(results)
+	slices.SortStableFunc(ranked, func(a, b Result) int {
+		switch {
+		case a.Err != nil && b.Err != nil:
+			return 0
+		case a.Err != nil:
+			return 1
+		case b.Err != nil:
+			return -1
+		}
+		return cmp.Compare(b.Rate(), a.Rate())
+	})
+	return ranked
+}
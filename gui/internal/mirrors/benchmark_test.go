@@ -0,0 +1,82 @@
+package mirrors
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mirrorStandIn serves core.db like a mirror, delayed to simulate its speed
+func mirrorStandIn(t *testing.T, delay time.Duration, status int) Mirror {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/archlinux/core/os/x86_64/core.db" {
+			http.NotFound(w, r)
+			return
+		}
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(strings.Repeat("x", 64*1024)))
+	}))
+	t.Cleanup(srv.Close)
+	return Mirror{Server: srv.URL + "/archlinux/$repo/os/$arch", Protocol: "http"}
+}
+
+func TestBenchmarkRanking(t *testing.T) {
+	slow := mirrorStandIn(t, 150*time.Millisecond, http.StatusOK)
+	fast := mirrorStandIn(t, 0, http.StatusOK)
+	broken := mirrorStandIn(t, 0, http.StatusNotFound)
+	hanging := mirrorStandIn(t, 10*time.Second, http.StatusOK)
+
+	var calls atomic.Int32
+	start := time.Now()
+	results := Benchmark(context.Background(), []Mirror{hanging, slow, broken, fast}, 4, 500*time.Millisecond, func(done, total int) {
+		calls.Add(1)
+		if total != 4 {
+			t.Errorf("progress total %d", total)
+		}
+	})
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("the timeout was not applied, took %s", elapsed)
+	}
+	if calls.Load() != 4 {
+		t.Errorf("progress called %d times", calls.Load())
+	}
+	if results[0].Err == nil || results[2].Err == nil {
+		t.Errorf("hanging and broken mirrors should fail: %v, %v", results[0].Err, results[2].Err)
+	}
+	if results[3].Bytes != 64*1024 {
+		t.Errorf("downloaded %d bytes", results[3].Bytes)
+	}
+
+	ranked := Rank(results)
+	order := []Mirror{fast, slow, hanging, broken}
+	for i, r := range ranked {
+		if r.Mirror != order[i] {
+			t.Errorf("rank %d: got %s, want %s", i, r.Mirror.Server, order[i].Server)
+		}
+	}
+}
+
+func TestDBURL(t *testing.T) {
+	if got := dbURL("https://mirror.example/arch/$repo/os/$arch"); got != "https://mirror.example/arch/core/os/x86_64/core.db" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	if err := Probe(context.Background(), mirrorStandIn(t, 0, http.StatusOK).Server, time.Second); err != nil {
+		t.Errorf("working proxy: %v", err)
+	}
+	if err := Probe(context.Background(), mirrorStandIn(t, 0, http.StatusBadGateway).Server, time.Second); err == nil {
+		t.Error("failing proxy should be reported")
+	}
+}
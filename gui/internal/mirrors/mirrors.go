@@ -0,0 +1,184 @@
+package mirrors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	// Written by reflector when the ISO boots
+	mirrorlistPath = "/etc/pacman.d/mirrorlist"
+	// Mirror metadata published by archlinux.org
+	StatusURL = "https://archlinux.org/mirrors/status/json/"
+
+	serverSuffix = "$repo/os/$arch"
+)
+
+// Mirror is one Server entry
+type Mirror struct {
+	Server   string // pacman Server value with $repo and $arch
+	Country  string // "" when unknown
+	Protocol string // http, https
+	Enabled  bool   // an active Server line in the live mirrorlist
+}
+
+// ValidateServer accepts http(s) Server values, the only kind the backend
+// writes to a mirrorlist
+func ValidateServer(server string) error {
+	u, err := url.Parse(server)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", server)
+	}
+	if strings.ContainsAny(server, " \t\n") {
+		return fmt.Errorf("%q contains whitespace", server)
+	}
+	return nil
+}
+
+// GetMirrorlist reads the live system's mirrorlist
+func GetMirrorlist() ([]Mirror, error) {
+	raw, err := os.ReadFile(mirrorlistPath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMirrorlist(string(raw)), nil
+}
+
+// ParseMirrorlist reads Server lines, active and commented out. "## Country"
+// headers, as in the pacman-mirrorlist package, set the country of the
+// entries below them.
+func ParseMirrorlist(text string) []Mirror {
+	var mirrors []Mirror
+	var country string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		enabled := !strings.HasPrefix(line, "#")
+		body := strings.TrimSpace(strings.TrimLeft(line, "#"))
+		key, value, ok := strings.Cut(body, "=")
+		if !ok || strings.TrimSpace(key) != "Server" {
+			if strings.HasPrefix(line, "## ") && !strings.Contains(line, ":") {
+				country = strings.TrimPrefix(line, "## ")
+			}
+			continue
+		}
+		value = strings.TrimSpace(value)
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		mirrors = append(mirrors, Mirror{Server: value, Country: country, Protocol: u.Scheme, Enabled: enabled})
+	}
+	return mirrors
+}
+
+// statusJSON is the part of archlinux.org/mirrors/status/json the installer uses
+type statusJSON struct {
+	URLs []struct {
+		URL           string  `json:"url"`
+		Protocol      string  `json:"protocol"`
+		Country       string  `json:"country"`
+		Active        bool    `json:"active"`
+		CompletionPct float64 `json:"completion_pct"`
+	} `json:"urls"`
+}
+
+// FetchStatus downloads the official mirror list with countries. Mirrors that
+// are inactive or not fully synced are left out.
+func FetchStatus(ctx context.Context, statusURL string) ([]Mirror, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("mirror status: HTTP %d", resp.StatusCode)
+	}
+	var status statusJSON
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("mirror status: %w", err)
+	}
+	var mirrors []Mirror
+	for _, u := range status.URLs {
+		if !u.Active || u.CompletionPct < 1 || (u.Protocol != "http" && u.Protocol != "https") {
+			continue
+		}
+		mirrors = append(mirrors, Mirror{
+			Server:   strings.TrimSuffix(u.URL, "/") + "/" + serverSuffix,
+			Country:  u.Country,
+			Protocol: u.Protocol,
+		})
+	}
+	return mirrors, nil
+}
+
+// Merge completes the live list with the status list: countries are filled
+// in and unknown mirrors appended, the live order comes first
+func Merge(live, status []Mirror) []Mirror {
+	byServer := map[string]Mirror{}
+	for _, m := range status {
+		byServer[m.Server] = m
+	}
+	merged := slices.Clone(live)
+	seen := map[string]bool{}
+	for i, m := range merged {
+		seen[m.Server] = true
+		if s, ok := byServer[m.Server]; ok && m.Country == "" {
+			merged[i].Country = s.Country
+		}
+	}
+	for _, m := range status {
+		if !seen[m.Server] {
+			seen[m.Server] = true
+			merged = append(merged, m)
+		}
+	}
+	return merged
+}
+
+// Filter keeps mirrors in one of countries (all when empty) served over
+// one of protocols (all when empty)
+func Filter(mirrors []Mirror, countries, protocols []string) []Mirror {
+	var out []Mirror
+	for _, m := range mirrors {
+		if len(countries) > 0 && !slices.Contains(countries, m.Country) {
+			continue
+		}
+		if len(protocols) > 0 && !slices.Contains(protocols, m.Protocol) {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// Countries lists the known countries, sorted
+func Countries(mirrors []Mirror) []string {
+	var out []string
+	for _, m := range mirrors {
+		if m.Country != "" && !slices.Contains(out, m.Country) {
+			out = append(out, m.Country)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Render writes a mirrorlist for /etc/pacman.d with servers in order
+func Render(servers []string) string {
+	var b strings.Builder
+	b.WriteString("## Arch Linux mirrorlist, ranked by the Arch Linux GUI installer\n")
+	for _, s := range servers {
+		b.WriteString("Server = " + s + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package mirrors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+const testMirrorlist = `##
+## Arch Linux repository mirrorlist
+## Generated on 2025-01-01
+##
+
+## Worldwide
+#Server = https://geo.mirror.pkgbuild.com/$repo/os/$arch
+
+## Germany
+Server = https://mirror.example.de/archlinux/$repo/os/$arch
+#Server = http://ftp.example.de/pub/archlinux/$repo/os/$arch
+#Server = rsync://rsync.example.de/archlinux/$repo/os/$arch
+`
+
+func TestParseMirrorlist(t *testing.T) {
+	got := ParseMirrorlist(testMirrorlist)
+	want := []Mirror{
+		{Server: "https://geo.mirror.pkgbuild.com/$repo/os/$arch", Country: "Worldwide", Protocol: "https"},
+		{Server: "https://mirror.example.de/archlinux/$repo/os/$arch", Country: "Germany", Protocol: "https", Enabled: true},
+		{Server: "http://ftp.example.de/pub/archlinux/$repo/os/$arch", Country: "Germany", Protocol: "http"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	// reflector output has no country headers
+	got = ParseMirrorlist("# With: reflector --latest 5\n# When: 2025-01-01 10:00:00 UTC\nServer = https://a.example/$repo/os/$arch\n")
+	if len(got) != 1 || got[0].Country != "" || !got[0].Enabled {
+		t.Errorf("reflector list parsed as %+v", got)
+	}
+}
+
+func TestFetchStatusAndMerge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"urls":[
+			{"url":"https://mirror.example.de/archlinux/","protocol":"https","country":"Germany","active":true,"completion_pct":1.0},
+			{"url":"https://mirror.example.fr/arch/","protocol":"https","country":"France","active":true,"completion_pct":1.0},
+			{"url":"https://stale.example.fr/arch/","protocol":"https","country":"France","active":true,"completion_pct":0.8},
+			{"url":"rsync://mirror.example.fr/arch/","protocol":"rsync","country":"France","active":true,"completion_pct":1.0},
+			{"url":"https://old.example.se/arch/","protocol":"https","country":"Sweden","active":false,"completion_pct":1.0}
+		]}`)
+	}))
+	defer srv.Close()
+
+	status, err := FetchStatus(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 2 || status[1].Server != "https://mirror.example.fr/arch/$repo/os/$arch" {
+		t.Errorf("unexpected status mirrors %+v", status)
+	}
+
+	live := []Mirror{{Server: "https://mirror.example.de/archlinux/$repo/os/$arch", Protocol: "https", Enabled: true}}
+	merged := Merge(live, status)
+	if len(merged) != 2 || merged[0].Country != "Germany" || !merged[0].Enabled || merged[1].Country != "France" {
+		t.Errorf("unexpected merge %+v", merged)
+	}
+	if got := Countries(merged); !slices.Equal(got, []string{"France", "Germany"}) {
+		t.Errorf("countries %v", got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	mirrors := ParseMirrorlist(testMirrorlist)
+	if got := Filter(mirrors, []string{"Germany"}, []string{"https"}); len(got) != 1 || got[0].Country != "Germany" {
+		t.Errorf("filtered %+v", got)
+	}
+	if got := Filter(mirrors, nil, nil); len(got) != len(mirrors) {
+		t.Errorf("empty filters should keep everything, got %d", len(got))
+	}
+}
+
+func TestRender(t *testing.T) {
+	got := ParseMirrorlist(Render([]string{"https://b.example/$repo/os/$arch", "https://a.example/$repo/os/$arch"}))
+	if len(got) != 2 || got[0].Server != "https://b.example/$repo/os/$arch" || !got[1].Enabled {
+		t.Errorf("rendered list reads back as %+v", got)
+	}
+}
+
+func TestValidateServer(t *testing.T) {
+	for _, ok := range []string{"http://cache.lan:9129/repo/archlinux/$repo/os/$arch", "https://mirror.example/arch/$repo/os/$arch"} {
+		if err := ValidateServer(ok); err != nil {
+			t.Errorf("%s: %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "cache.lan:9129", "ftp://mirror.example/arch", "http://", "http://cache.lan/ x"} {
+		if err := ValidateServer(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
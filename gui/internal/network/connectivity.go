@@ -0,0 +1,40 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Same check NetworkManager uses on Arch
+const (
+	ConnectivityURL      = "http://ping.archlinux.org/nm-check.txt"
+	connectivityResponse = "NetworkManager is online"
+)
+
+// CheckConnectivity fetches the check URL. Captive portals answer with a
+// redirect or their own page, which is reported as not online.
+func CheckConnectivity(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), connectivityResponse) {
+		return fmt.Errorf("unexpected answer from %s (HTTP %d), a captive portal may need a login", url, resp.StatusCode)
+	}
+	return nil
+}
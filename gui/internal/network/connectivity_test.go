@@ -0,0 +1,32 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckConnectivity(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			fmt.Fprintln(w, connectivityResponse)
+		case "/portal":
+			http.Redirect(w, r, "/login", http.StatusFound)
+		default:
+			fmt.Fprintln(w, "<html>login</html>")
+		}
+	}))
+	defer srv.Close()
+
+	if err := CheckConnectivity(context.Background(), srv.URL+"/ok"); err != nil {
+		t.Errorf("online: %v", err)
+	}
+	for _, path := range []string{"/portal", "/login"} {
+		if err := CheckConnectivity(context.Background(), srv.URL+path); err == nil {
+			t.Errorf("%s: expected an error", path)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package network
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const sysClassNet = "/sys/class/net"
+
+// Interface is one network device of the live system
+type Interface struct {
+	Name     string
+	MAC      string
+	State    string // operstate: up, down, dormant, ...
+	Wireless bool
+}
+
+// GetInterfaces lists the live system's interfaces
+func GetInterfaces() ([]Interface, error) {
+	return ListInterfaces(sysClassNet)
+}
+
+// ListInterfaces reads interfaces from a /sys/class/net style directory,
+// skipping loopback
+func ListInterfaces(dir string) ([]Interface, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var ifaces []Interface
+	for _, e := range entries {
+		if e.Name() == "lo" {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		iface := Interface{
+			Name:  e.Name(),
+			MAC:   readTrimmed(filepath.Join(path, "address")),
+			State: readTrimmed(filepath.Join(path, "operstate")),
+		}
+		// wireless/ exists for cfg80211 devices, phy80211 links to the radio
+		if _, err := os.Stat(filepath.Join(path, "wireless")); err == nil {
+			iface.Wireless = true
+		} else if _, err := os.Stat(filepath.Join(path, "phy80211")); err == nil {
+			iface.Wireless = true
+		}
+		ifaces = append(ifaces, iface)
+	}
+	slices.SortFunc(ifaces, func(a, b Interface) int { return strings.Compare(a.Name, b.Name) })
+	return ifaces, nil
+}
+
+func readTrimmed(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
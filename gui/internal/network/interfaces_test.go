@@ -0,0 +1,39 @@
+package network
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestListInterfaces(t *testing.T) {
+	dir := t.TempDir()
+	write := func(iface, file, content string) {
+		path := filepath.Join(dir, iface, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("lo", "operstate", "unknown\n")
+	write("wlan0", "address", "aa:bb:cc:dd:ee:ff\n")
+	write("wlan0", "operstate", "dormant\n")
+	write("wlan0", "wireless/.keep", "")
+	write("enp3s0", "address", "11:22:33:44:55:66\n")
+	write("enp3s0", "operstate", "up\n")
+
+	got, err := ListInterfaces(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Interface{
+		{Name: "enp3s0", MAC: "11:22:33:44:55:66", State: "up"},
+		{Name: "wlan0", MAC: "aa:bb:cc:dd:ee:ff", State: "dormant", Wireless: true},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
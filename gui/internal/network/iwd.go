@@ -0,0 +1,203 @@
+package network
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// iwd D-Bus API (see iwd's doc/*-api.txt)
+const (
+	iwdService      = "net.connman.iwd"
+	iwdStation      = iwdService + ".Station"
+	iwdNetwork      = iwdService + ".Network"
+	iwdDevice       = iwdService + ".Device"
+	iwdAgentManager = iwdService + ".AgentManager"
+	iwdAgent        = iwdService + ".Agent"
+
+	agentPath   = dbus.ObjectPath("/archgui/iwd_agent")
+	scanTimeout = 15 * time.Second
+)
+
+// Station is a wireless device in station (client) mode
+type Station struct {
+	Path     dbus.ObjectPath
+	Device   string // interface name, e.g. wlan0
+	State    string // connected, disconnected, connecting, ...
+	Scanning bool
+}
+
+// WifiNetwork is one scan result
+type WifiNetwork struct {
+	Path      dbus.ObjectPath
+	Name      string // SSID
+	Security  string // open, psk, 8021x, wep
+	Signal    int    // dBm
+	Connected bool
+	Known     bool // iwd has stored credentials
+}
+
+// managedObjects is the reply of ObjectManager.GetManagedObjects
+type managedObjects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+
+// IWD talks to the iwd daemon of the live system over the system bus
+type IWD struct {
+	conn *dbus.Conn
+}
+
+// ConnectIWD opens a system bus connection and checks iwd is running
+func ConnectIWD() (*IWD, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("system bus: %w", err)
+	}
+	var owned bool
+	if err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, iwdService).Store(&owned); err != nil || !owned {
+		conn.Close()
+		return nil, fmt.Errorf("iwd is not running (systemctl start iwd)")
+	}
+	return &IWD{conn: conn}, nil
+}
+
+func (c *IWD) Close() error {
+	return c.conn.Close()
+}
+
+func (c *IWD) objects() (managedObjects, error) {
+	var objs managedObjects
+	err := c.conn.Object(iwdService, "/").Call("org.freedesktop.DBus.ObjectManager.GetManagedObjects", 0).Store(&objs)
+	return objs, err
+}
+
+// Stations lists the wireless devices in station mode
+func (c *IWD) Stations() ([]Station, error) {
+	objs, err := c.objects()
+	if err != nil {
+		return nil, err
+	}
+	return parseStations(objs), nil
+}
+
+func parseStations(objs managedObjects) []Station {
+	var stations []Station
+	for path, ifaces := range objs {
+		props, ok := ifaces[iwdStation]
+		if !ok {
+			continue
+		}
+		s := Station{Path: path}
+		// The station lives on the same object as its device
+		s.Device, _ = ifaces[iwdDevice]["Name"].Value().(string)
+		s.State, _ = props["State"].Value().(string)
+		s.Scanning, _ = props["Scanning"].Value().(bool)
+		stations = append(stations, s)
+	}
+	slices.SortFunc(stations, func(a, b Station) int { return strings.Compare(a.Device, b.Device) })
+	return stations
+}
+
+// Scan triggers a scan and waits until iwd has finished it
+func (c *IWD) Scan(station dbus.ObjectPath) error {
+	obj := c.conn.Object(iwdService, station)
+	if call := obj.Call(iwdStation+".Scan", 0); call.Err != nil {
+		// Busy: a scan is already running, wait for it like for our own
+		if e, ok := call.Err.(dbus.Error); !ok || e.Name != iwdService+".Busy" {
+			return fmt.Errorf("scan: %w", call.Err)
+		}
+	}
+	deadline := time.Now().Add(scanTimeout)
+	for time.Now().Before(deadline) {
+		v, err := obj.GetProperty(iwdStation + ".Scanning")
+		if err != nil {
+			return err
+		}
+		if scanning, _ := v.Value().(bool); !scanning {
+			return nil
+		}
+		time.Sleep(300 * time.Millisecond)
+	}
+	return fmt.Errorf("scan did not finish within %s", scanTimeout)
+}
+
+// Networks returns the station's scan results, strongest first
+func (c *IWD) Networks(station dbus.ObjectPath) ([]WifiNetwork, error) {
+	var ordered []struct {
+		Path   dbus.ObjectPath
+		Signal int16 // dBm * 100
+	}
+	if err := c.conn.Object(iwdService, station).Call(iwdStation+".GetOrderedNetworks", 0).Store(&ordered); err != nil {
+		return nil, fmt.Errorf("networks: %w", err)
+	}
+	objs, err := c.objects()
+	if err != nil {
+		return nil, err
+	}
+	var nets []WifiNetwork
+	for _, o := range ordered {
+		if n, ok := parseNetwork(o.Path, objs); ok {
+			n.Signal = int(o.Signal) / 100
+			nets = append(nets, n)
+		}
+	}
+	return nets, nil
+}
+
+func parseNetwork(path dbus.ObjectPath, objs managedObjects) (WifiNetwork, bool) {
+	props, ok := objs[path][iwdNetwork]
+	if !ok {
+		return WifiNetwork{}, false
+	}
+	n := WifiNetwork{Path: path}
+	n.Name, _ = props["Name"].Value().(string)
+	n.Security, _ = props["Type"].Value().(string)
+	n.Connected, _ = props["Connected"].Value().(bool)
+	_, n.Known = props["KnownNetwork"]
+	return n, true
+}
+
+// Connect joins a network. For psk networks iwd asks the registered agent for
+// the passphrase, so it is only sent when iwd needs it.
+func (c *IWD) Connect(network dbus.ObjectPath, passphrase string) error {
+	a := &agent{passphrase: passphrase}
+	if err := c.conn.Export(a, agentPath, iwdAgent); err != nil {
+		return err
+	}
+	defer c.conn.Export(nil, agentPath, iwdAgent)
+
+	manager := c.conn.Object(iwdService, "/net/connman/iwd")
+	if err := manager.Call(iwdAgentManager+".RegisterAgent", 0, agentPath).Err; err != nil {
+		return fmt.Errorf("register agent: %w", err)
+	}
+	defer manager.Call(iwdAgentManager+".UnregisterAgent", 0, agentPath)
+
+	if err := c.conn.Object(iwdService, network).Call(iwdNetwork+".Connect", 0).Err; err != nil {
+		if e, ok := err.(dbus.Error); ok && e.Name == iwdService+".Failed" && passphrase != "" {
+			return fmt.Errorf("connection failed, check the passphrase")
+		}
+		return fmt.Errorf("connect: %w", err)
+	}
+	return nil
+}
+
+// agent implements net.connman.iwd.Agent for a single connection attempt
+type agent struct {
+	passphrase string
+}
+
+func (a *agent) Release() *dbus.Error {
+	return nil
+}
+
+func (a *agent) RequestPassphrase(network dbus.ObjectPath) (string, *dbus.Error) {
+	if a.passphrase == "" {
+		return "", dbus.NewError(iwdAgent+".Error.Canceled", []any{"no passphrase entered"})
+	}
+	return a.passphrase, nil
+}
+
+func (a *agent) Cancel(reason string) *dbus.Error {
+	return nil
+}
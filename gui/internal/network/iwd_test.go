@@ -0,0 +1,46 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestParseManagedObjects(t *testing.T) {
+	objs := managedObjects{
+		"/net/connman/iwd/0/4": {
+			iwdDevice:  {"Name": dbus.MakeVariant("wlan0")},
+			iwdStation: {"State": dbus.MakeVariant("connected"), "Scanning": dbus.MakeVariant(false)},
+		},
+		"/net/connman/iwd/0/5": {
+			iwdDevice: {"Name": dbus.MakeVariant("wlan1")}, // access point mode, no station
+		},
+		"/net/connman/iwd/0/4/486f6d65_psk": {
+			iwdNetwork: {
+				"Name":         dbus.MakeVariant("Home"),
+				"Type":         dbus.MakeVariant("psk"),
+				"Connected":    dbus.MakeVariant(true),
+				"KnownNetwork": dbus.MakeVariant(dbus.ObjectPath("/net/connman/iwd/486f6d65_psk")),
+			},
+		},
+	}
+
+	stations := parseStations(objs)
+	if len(stations) != 1 {
+		t.Fatalf("got %d stations, want 1", len(stations))
+	}
+	if s := stations[0]; s.Device != "wlan0" || s.State != "connected" || s.Scanning {
+		t.Errorf("unexpected station %+v", s)
+	}
+
+	n, ok := parseNetwork("/net/connman/iwd/0/4/486f6d65_psk", objs)
+	if !ok {
+		t.Fatal("network not found")
+	}
+	if n.Name != "Home" || n.Security != SecurityPSK || !n.Connected || !n.Known {
+		t.Errorf("unexpected network %+v", n)
+	}
+	if _, ok := parseNetwork("/net/connman/iwd/0/4", objs); ok {
+		t.Error("a station is not a network")
+	}
+}
@@ -0,0 +1,68 @@
+package network
+
+import (
+	"crypto/rand"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Wi-Fi security types as reported by iwd's Network.Type
+const (
+	SecurityOpen  = "open"
+	SecurityPSK   = "psk"
+	Security8021X = "8021x"
+)
+
+// WifiProfile renders a NetworkManager keyfile for the installed system, so
+// the network used during the install is known on first boot
+func WifiProfile(ssid, passphrase, security string) (string, error) {
+	if ssid == "" {
+		return "", fmt.Errorf("no network selected")
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "[connection]\nid=%s\nuuid=%s\ntype=wifi\n\n", keyfileEscape(ssid), newUUID())
+	fmt.Fprintf(&b, "[wifi]\nmode=infrastructure\nssid=%s\n\n", keyfileEscape(ssid))
+	switch security {
+	case SecurityOpen:
+	case SecurityPSK:
+		if passphrase == "" {
+			return "", fmt.Errorf("passphrase is required for %s", ssid)
+		}
+		fmt.Fprintf(&b, "[wifi-security]\nkey-mgmt=wpa-psk\npsk=%s\n\n", keyfileEscape(passphrase))
+	default:
+		return "", fmt.Errorf("%s networks cannot be copied to the installed system", security)
+	}
+	b.WriteString("[ipv4]\nmethod=auto\n\n[ipv6]\nmethod=auto\n")
+	return b.String(), nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// ProfileFileName is the keyfile name below /etc/NetworkManager/system-connections
+func ProfileFileName(ssid string) string {
+	// NetworkManager skips hidden files
+	name := strings.TrimLeft(unsafeFileChars.ReplaceAllString(ssid, "_"), ".")
+	if strings.Trim(name, "._") == "" {
+		name = "wifi"
+	}
+	return name + ".nmconnection"
+}
+
+// keyfileEscape applies GLib key file escaping to a value
+func keyfileEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`).Replace(s)
+	if strings.HasPrefix(s, " ") {
+		s = `\s` + s[1:]
+	}
+	return s
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	var u [16]byte
+	rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
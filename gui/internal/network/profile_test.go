@@ -0,0 +1,64 @@
+package network
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWifiProfile(t *testing.T) {
+	got, err := WifiProfile("Café", `p\ss word`, SecurityPSK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"id=Café\n", "type=wifi\n", "ssid=Café\n", "key-mgmt=wpa-psk\n", `psk=p\\ss word` + "\n", "[ipv4]\nmethod=auto\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("profile lacks %q:\n%s", want, got)
+		}
+	}
+
+	open, err := WifiProfile("Guest", "", SecurityOpen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(open, "[wifi-security]") {
+		t.Errorf("open network has a security section:\n%s", open)
+	}
+
+	for name, args := range map[string][3]string{
+		"no ssid":       {"", "secret123", SecurityPSK},
+		"no passphrase": {"Home", "", SecurityPSK},
+		"enterprise":    {"Work", "", Security8021X},
+	} {
+		if _, err := WifiProfile(args[0], args[1], args[2]); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestKeyfileEscape(t *testing.T) {
+	tests := map[string]string{
+		"plain":      "plain",
+		" lead":      `\slead`,
+		"a\nb":       `a\nb`,
+		`back\slash`: `back\\slash`,
+	}
+	for in, want := range tests {
+		if got := keyfileEscape(in); got != want {
+			t.Errorf("keyfileEscape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestProfileFileName(t *testing.T) {
+	tests := map[string]string{
+		"Home":          "Home.nmconnection",
+		"My Wi-Fi 5G":   "My_Wi-Fi_5G.nmconnection",
+		"../../etc/foo": "_.._etc_foo.nmconnection",
+		"..":            "wifi.nmconnection",
+	}
+	for in, want := range tests {
+		if got := ProfileFileName(in); got != want {
+			t.Errorf("ProfileFileName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
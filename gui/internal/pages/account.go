@@ -1,64 +1,435 @@
 package pages
 
 import (
+	"archgui/gui/internal/data"
 	"archgui/gui/internal/state"
+	"archgui/gui/internal/validation"
 	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/widget"
 )
 
-type AccountPage struct{}
+// Root account choices
+const (
+	rootOwnPassword  = "Set a root password"
+	rootSamePassword = "Use the first user's password for root"
+	rootLocked       = "Lock the root account"
+)
+
+var rootModes = map[string]string{
+	rootOwnPassword:  "password",
+	rootSamePassword: "same",
+	rootLocked:       "locked",
+}
+
+// Groups offered for each user. All exist in a fresh Arch install (filesystem package).
+var userGroupChoices = []string{"audio", "video", "storage", "input", "optical", "network", "power", "lp", "scanner", "uucp", "games", "kvm", "rfkill"}
+
+var shellChoices = []string{"bash", "zsh", "zsh-ohmyzsh"}
+
+// Privilege tool choices
+var privilegeTools = []struct{ label, tool string }{
+	{"sudo", data.PrivSudo},
+	{"doas (opendoas)", data.PrivDoas},
+	{"sudo and doas", data.PrivBoth},
+}
+
+type AccountPage struct {
+	// Confirmation values stay on the page, only the passwords go into the config
+	rootConfirm  string
+	userConfirms []string // parallel to config.Users
+	selected     int      // index of the user shown in the editor
+}
 
 func (p *AccountPage) Title() string {
-	return "User Account"
+	return "User Accounts"
 }
 
 func (p *AccountPage) Content(config *state.InstallConfig, ctrl WizardController) fyne.CanvasObject {
 	hostEntry := widget.NewEntry()
 	hostEntry.Text = config.Hostname
 	hostEntry.OnChanged = func(s string) { config.Hostname = s }
+	// The form shows validator errors as hint text under the entry
+	hostEntry.Validator = validation.Hostname
 
-	fullEntry := widget.NewEntry()
-	fullEntry.SetPlaceHolder("Firstname Lastname")
-	fullEntry.OnChanged = func(s string) { config.FullName = s }
+	if len(config.Users) == 0 {
+		config.Users = []state.User{state.NewUser("")}
+	}
+	for len(p.userConfirms) < len(config.Users) {
+		p.userConfirms = append(p.userConfirms, "")
+	}
+	p.selected = min(p.selected, len(config.Users)-1)
 
-	userEntry := widget.NewEntry()
-	userEntry.Text = config.Username
-	userEntry.OnChanged = func(s string) { config.Username = s }
+	// --- Users ---
+	editor := container.NewVBox()
+	userSelect := widget.NewSelect(nil, nil)
+	removeBtn := widget.NewButton("Remove User", nil)
+
+	refreshList := func() {
+		userSelect.Options = userLabels(config.Users)
+		userSelect.Selected = userSelect.Options[p.selected]
+		userSelect.Refresh()
+		if len(config.Users) > 1 {
+			removeBtn.Enable()
+		} else {
+			removeBtn.Disable()
+		}
+	}
+	showUser := func(i int) {
+		p.selected = i
+		editor.Objects = []fyne.CanvasObject{p.userEditor(config, ctrl, i, refreshList)}
+		editor.Refresh()
+		refreshList()
+	}
+
+	userSelect.OnChanged = func(string) {
+		if i := userSelect.SelectedIndex(); i >= 0 && i != p.selected {
+			showUser(i)
+		}
+	}
+	addBtn := widget.NewButton("Add User", func() {
+		u := state.NewUser("")
+		u.Admin = false
+		config.Users = append(config.Users, u)
+		p.userConfirms = append(p.userConfirms, "")
+		showUser(len(config.Users) - 1)
+	})
+	removeBtn.OnTapped = func() {
+		if len(config.Users) < 2 {
+			return
+		}
+		i := p.selected
+		config.Users = append(config.Users[:i], config.Users[i+1:]...)
+		p.userConfirms = append(p.userConfirms[:i], p.userConfirms[i+1:]...)
+		showUser(max(i-1, 0))
+	}
+	showUser(p.selected)
+
+	// --- Root account ---
+	rootMeter, updateRootMeter := newStrengthMeter()
 
 	rootPass := widget.NewPasswordEntry()
-	rootPass.OnChanged = func(s string) { config.RootPassword = s }
+	rootPass.Text = config.RootPassword
+	rootPass.OnChanged = func(s string) {
+		config.RootPassword = s
+		updateRootMeter(s, "root")
+	}
+
+	rootConfirm := widget.NewPasswordEntry()
+	rootConfirm.Text = p.rootConfirm
+	rootConfirm.OnChanged = func(s string) { p.rootConfirm = s }
+
+	updateRootMeter(config.RootPassword, "root")
+
+	rootForm := widget.NewForm(
+		widget.NewFormItem("Root Password", rootPass),
+		widget.NewFormItem("Confirm", rootConfirm),
+		widget.NewFormItem("", rootMeter),
+	)
 
-	userPass := widget.NewPasswordEntry()
-	userPass.OnChanged = func(s string) { config.UserPassword = s }
+	rootMode := widget.NewRadioGroup([]string{rootOwnPassword, rootSamePassword, rootLocked}, func(s string) {
+		config.RootMode = rootModes[s]
+		if config.RootMode == "password" {
+			rootForm.Show()
+		} else {
+			rootForm.Hide()
+		}
+	})
+	for label, mode := range rootModes {
+		if mode == config.RootMode {
+			rootMode.SetSelected(label)
+		}
+	}
 
-	// Shell Select
-	shellSelect := widget.NewSelect([]string{"bash", "zsh", "zsh-ohmyzsh"}, func(s string) {
-		config.Shell = s
+	// --- Administration ---
+	var toolLabels []string
+	for _, t := range privilegeTools {
+		toolLabels = append(toolLabels, t.label)
+	}
+	toolSelect := widget.NewSelect(toolLabels, func(s string) {
+		for _, t := range privilegeTools {
+			if t.label == s {
+				config.PrivilegeTool = t.tool
+			}
+		}
 	})
-	shellSelect.SetSelected(config.Shell)
+	for _, t := range privilegeTools {
+		if t.tool == config.PrivilegeTool {
+			toolSelect.SetSelected(t.label)
+		}
+	}
+	noAdminPass := widget.NewCheck("Administrators run commands without a password", func(b bool) { config.AdminNoPassword = b })
+	noAdminPass.Checked = config.AdminNoPassword
+
+	// --- SSH server ---
+	noPasswords := widget.NewCheck("Disable password login (keys only)", func(b bool) { config.SSHDisablePasswords = b })
+	noPasswords.Checked = config.SSHDisablePasswords
+	sshd := widget.NewCheck("Enable SSH server (sshd)", func(b bool) {
+		config.EnableSSHD = b
+		if b {
+			noPasswords.Enable()
+		} else {
+			noPasswords.Disable()
+		}
+	})
+	sshd.SetChecked(config.EnableSSHD)
+	if !config.EnableSSHD {
+		noPasswords.Disable()
+	}
 
 	return container.NewVBox(
-		widget.NewLabelWithStyle("System & User Account", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("System", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		widget.NewForm(
-			widget.NewFormItem("Hostname", hostEntry),
-			widget.NewFormItem("Full Name", fullEntry),
-			widget.NewFormItem("Username", userEntry),
-			widget.NewFormItem("Root Password", rootPass),
-			widget.NewFormItem("User Password", userPass),
-			widget.NewFormItem("Preferred Shell", shellSelect),
+			&widget.FormItem{Text: "Hostname", Widget: hostEntry, HintText: "Letters, digits and hyphens, parts separated by dots"},
 		),
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Users", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		container.NewBorder(nil, nil, nil, container.NewHBox(addBtn, removeBtn), userSelect),
+		editor,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Root Account", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		rootMode,
+		rootForm,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Administration", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewForm(widget.NewFormItem("Privilege Tool", toolSelect)),
+		noAdminPass,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Remote Access", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		sshd,
+		noPasswords,
+	)
+}
+
+// userEditor builds the form for config.Users[i]. Closures index the slice
+// instead of holding a pointer, since adding users may reallocate it.
+func (p *AccountPage) userEditor(config *state.InstallConfig, ctrl WizardController, i int, changed func()) fyne.CanvasObject {
+	u := config.Users[i]
+	meter, updateMeter := newStrengthMeter()
+
+	fullEntry := widget.NewEntry()
+	fullEntry.Text = u.FullName
+	fullEntry.SetPlaceHolder("Firstname Lastname")
+	fullEntry.OnChanged = func(s string) { config.Users[i].FullName = s }
+
+	userEntry := widget.NewEntry()
+	userEntry.Text = u.Username
+	userEntry.OnChanged = func(s string) {
+		config.Users[i].Username = s
+		updateMeter(config.Users[i].Password, s)
+		changed()
+	}
+	userEntry.Validator = validation.Username
+
+	pass := widget.NewPasswordEntry()
+	pass.Text = u.Password
+	pass.OnChanged = func(s string) {
+		config.Users[i].Password = s
+		updateMeter(s, config.Users[i].Username)
+	}
+
+	confirm := widget.NewPasswordEntry()
+	confirm.Text = p.userConfirms[i]
+	confirm.OnChanged = func(s string) { p.userConfirms[i] = s }
+
+	updateMeter(u.Password, u.Username)
+
+	admin := widget.NewCheck("Administrator (member of wheel)", func(b bool) {
+		config.Users[i].Admin = b
+		changed()
+	})
+	admin.Checked = u.Admin
+
+	groups := widget.NewCheckGroup(userGroupChoices, func(sel []string) { config.Users[i].Groups = sel })
+	groups.Horizontal = true
+	groups.Selected = slices.Clone(u.Groups)
+
+	shellSelect := widget.NewSelect(shellChoices, func(s string) { config.Users[i].Shell = s })
+	shellSelect.SetSelected(u.Shell)
+
+	homeEntry := widget.NewEntry()
+	homeEntry.Text = u.Home
+	homeEntry.SetPlaceHolder("/home/<username>")
+	homeEntry.OnChanged = func(s string) { config.Users[i].Home = s }
+	homeEntry.Validator = validation.HomeDir
+
+	// Raw lines are kept while typing, OnNext parses and normalizes them
+	keysEntry := widget.NewMultiLineEntry()
+	keysEntry.Text = strings.Join(u.SSHKeys, "\n")
+	keysEntry.SetPlaceHolder("ssh-ed25519 AAAA... user@host")
+	keysEntry.Wrapping = fyne.TextWrapBreak
+	keysEntry.SetMinRowsVisible(3)
+	keysEntry.OnChanged = func(s string) { config.Users[i].SSHKeys = strings.Split(s, "\n") }
+	keysEntry.Validator = func(s string) error {
+		_, err := validation.ParseAuthorizedKeys(s)
+		return err
+	}
+	loadKeys := widget.NewButton("Load from File...", func() {
+		loadPublicKeys(ctrl.Window(), func(text string) {
+			if keysEntry.Text != "" && !strings.HasSuffix(keysEntry.Text, "\n") {
+				text = "\n" + text
+			}
+			keysEntry.SetText(keysEntry.Text + text)
+		})
+	})
+
+	return widget.NewForm(
+		widget.NewFormItem("Full Name", fullEntry),
+		&widget.FormItem{Text: "Username", Widget: userEntry, HintText: "Lower case, starts with a letter"},
+		widget.NewFormItem("Password", pass),
+		widget.NewFormItem("Confirm", confirm),
+		widget.NewFormItem("", meter),
+		widget.NewFormItem("", admin),
+		widget.NewFormItem("Groups", groups),
+		widget.NewFormItem("Shell", shellSelect),
+		&widget.FormItem{Text: "Home", Widget: homeEntry, HintText: "Leave empty for the default"},
+		&widget.FormItem{Text: "SSH Keys", Widget: container.NewBorder(nil, loadKeys, nil, nil, keysEntry), HintText: "Public keys for ~/.ssh/authorized_keys, one per line"},
 	)
 }
 
+// loadPublicKeys lets the user pick a .pub or authorized_keys file, e.g. from
+// a USB stick, and passes its checked contents on
+func loadPublicKeys(win fyne.Window, add func(text string)) {
+	d := dialog.NewFileOpen(func(r fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		if r == nil {
+			return
+		}
+		defer r.Close()
+		raw, err := io.ReadAll(io.LimitReader(r, 1<<20))
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		keys, err := validation.ParseAuthorizedKeys(string(raw))
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("%s: %w", r.URI().Name(), err), win)
+			return
+		}
+		if len(keys) == 0 {
+			dialog.ShowError(fmt.Errorf("%s contains no public keys", r.URI().Name()), win)
+			return
+		}
+		add(strings.Join(keys, "\n") + "\n")
+	}, win)
+	// Removable media is auto-mounted below /run/media on most live systems
+	if dir, err := storage.ListerForURI(storage.NewFileURI("/run/media")); err == nil {
+		d.SetLocation(dir)
+	}
+	d.Show()
+}
+
+// userLabels names the entries of the user selector; an index keeps them
+// unique while names are still empty or duplicated
+func userLabels(users []state.User) []string {
+	labels := make([]string, len(users))
+	for i, u := range users {
+		name := u.Username
+		if name == "" {
+			name = "(new user)"
+		}
+		if u.Admin {
+			name += " (admin)"
+		}
+		labels[i] = fmt.Sprintf("%d. %s", i+1, name)
+	}
+	return labels
+}
+
+// newStrengthMeter returns a bar with warnings below it and a function to re-rate a password
+func newStrengthMeter() (fyne.CanvasObject, func(password, username string)) {
+	bar := widget.NewProgressBar()
+	bar.Max = 100
+	var strength validation.Strength
+	var empty bool
+	bar.TextFormatter = func() string {
+		if empty {
+			return "Enter a password"
+		}
+		return strength.String()
+	}
+
+	warnings := widget.NewLabel("")
+	warnings.Wrapping = fyne.TextWrapWord
+	warnings.Importance = widget.WarningImportance
+
+	update := func(password, username string) {
+		r := validation.CheckPassword(password, username)
+		strength, empty = r.Strength, password == ""
+		// 100 bits and more fills the bar
+		bar.SetValue(math.Min(r.Entropy, 100))
+		warnings.SetText(strings.Join(r.Warnings, "\n"))
+		if len(r.Warnings) == 0 {
+			warnings.Hide()
+		} else {
+			warnings.Show()
+		}
+	}
+	return container.NewVBox(bar, warnings), update
+}
+
 func (p *AccountPage) OnNext(config *state.InstallConfig) error {
-	if config.Username == "" || config.Hostname == "" {
-		return fmt.Errorf("username and hostname are required")
+	if err := validation.Hostname(config.Hostname); err != nil {
+		return err
+	}
+	if len(config.Users) == 0 {
+		return fmt.Errorf("at least one user is required")
+	}
+	seen := map[string]bool{}
+	admins, withKeys := 0, 0
+	for i, u := range config.Users {
+		if err := validation.Username(u.Username); err != nil {
+			return fmt.Errorf("user %d: %w", i+1, err)
+		}
+		if seen[u.Username] {
+			return fmt.Errorf("user %q is listed twice", u.Username)
+		}
+		seen[u.Username] = true
+		if u.Password == "" {
+			return fmt.Errorf("user %q needs a password", u.Username)
+		}
+		if i < len(p.userConfirms) && u.Password != p.userConfirms[i] {
+			return fmt.Errorf("passwords for %q do not match", u.Username)
+		}
+		if err := validation.HomeDir(u.Home); err != nil {
+			return fmt.Errorf("user %q: %w", u.Username, err)
+		}
+		keys, err := validation.ParseAuthorizedKeys(strings.Join(u.SSHKeys, "\n"))
+		if err != nil {
+			return fmt.Errorf("SSH keys for %q: %w", u.Username, err)
+		}
+		config.Users[i].SSHKeys = keys
+		if len(keys) > 0 {
+			withKeys++
+		}
+		if u.Admin {
+			admins++
+		}
+	}
+	if config.EnableSSHD && config.SSHDisablePasswords && withKeys == 0 {
+		return fmt.Errorf("password login is disabled for SSH, add a public key for at least one user")
+	}
+	if config.RootMode == "locked" && admins == 0 {
+		return fmt.Errorf("with a locked root account at least one user must be an administrator")
 	}
-	if config.RootPassword == "" || config.UserPassword == "" {
-		return fmt.Errorf("passwords are required")
+	if config.RootMode == "password" {
+		if config.RootPassword == "" {
+			return fmt.Errorf("root password is required")
+		}
+		if config.RootPassword != p.rootConfirm {
+			return fmt.Errorf("root passwords do not match")
+		}
 	}
 	return nil
 }
@@ -1,13 +1,25 @@
 package pages
 
 import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"archgui/gui/internal/data"
 	"archgui/gui/internal/state"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
 )
 
+const (
+	noNvidia  = "none"
+	noDesktop = "None (console only)"
+)
+
 type DesktopPage struct{}
 
 func (p *DesktopPage) Title() string {
@@ -15,27 +27,235 @@ func (p *DesktopPage) Title() string {
 }
 
 func (p *DesktopPage) Content(config *state.InstallConfig, ctrl WizardController) fyne.CanvasObject {
-	desktopSelect := widget.NewSelect([]string{
-		"none", "xfce", "gnome", "kde", "i3", "sway", "hyprland", "cinnamon", "mate", "lxqt", "budgie",
-	}, func(s string) {
-		config.Desktop = s
+	catalog := data.GetDesktopCatalog()
+
+	descLabel := widget.NewLabel("")
+	descLabel.Wrapping = fyne.TextWrapWord
+	screenshotBox := container.NewVBox()
+	extrasBox := container.NewVBox()
+	variantBox := container.NewVBox()
+
+	// Names in the select, ids in the config
+	names := []string{noDesktop}
+	for _, d := range catalog.Desktops {
+		names = append(names, d.Name)
+	}
+	desktopSelect := widget.NewSelect(names, func(s string) {
+		d, ok := findDesktopByName(catalog, s)
+		if !ok {
+			config.Desktop = "none"
+			config.DesktopExtras = nil
+			descLabel.SetText("Console only, no graphical session.")
+			screenshotBox.Objects = nil
+			screenshotBox.Refresh()
+			extrasBox.Objects = nil
+			extrasBox.Refresh()
+			variantBox.Objects = nil
+			variantBox.Refresh()
+			return
+		}
+		if config.Desktop != d.ID {
+			config.DesktopExtras = nil
+		}
+		config.Desktop = d.ID
+		descLabel.SetText(desktopDescription(d))
+		screenshotBox.Objects = nil
+		if img := desktopScreenshot(d); img != nil {
+			screenshotBox.Objects = []fyne.CanvasObject{img}
+		}
+		screenshotBox.Refresh()
+
+		variantBox.Objects = nil
+		if len(d.Variants) > 0 {
+			var labels []string
+			for _, v := range d.Variants {
+				labels = append(labels, v.Name)
+			}
+			variants := widget.NewRadioGroup(labels, func(s string) {
+				for _, v := range d.Variants {
+					if v.Name == s {
+						config.DesktopVariant = v.ID
+					}
+				}
+			})
+			variants.Horizontal = true
+			v, _ := d.Variant(config.DesktopVariant)
+			variants.SetSelected(v.Name)
+			variantBox.Objects = []fyne.CanvasObject{variants}
+		}
+		variantBox.Refresh()
+		extrasBox.Objects = nil
+		if len(d.Extras) > 0 {
+			extras := widget.NewCheckGroup(nil, nil)
+			for _, e := range d.Extras {
+				extras.Append(e.Name)
+			}
+			for _, e := range d.Extras {
+				if slices.Contains(config.DesktopExtras, e.ID) {
+					extras.Selected = append(extras.Selected, e.Name)
+				}
+			}
+			extras.OnChanged = func(sel []string) {
+				config.DesktopExtras = nil
+				for _, e := range d.Extras {
+					if slices.Contains(sel, e.Name) {
+						config.DesktopExtras = append(config.DesktopExtras, e.ID)
+					}
+				}
+			}
+			extrasBox.Objects = []fyne.CanvasObject{widget.NewLabel("Optional extras:"), extras}
+		}
+		extrasBox.Refresh()
+	})
+	if d, ok := catalog.Find(config.Desktop); ok {
+		desktopSelect.SetSelected(d.Name)
+	} else {
+		desktopSelect.SetSelected(noDesktop)
+	}
+
+	// --- Graphics driver matrix ---
+	planLabel := widget.NewLabel("")
+	planLabel.Wrapping = fyne.TextWrapWord
+	updatePlan := func() {
+		planLabel.SetText(gpuPlanSummary(config))
+	}
+
+	nvidiaSelect := widget.NewSelect(nvidiaOptions(config.Kernel), func(s string) {
+		if s == noNvidia {
+			s = ""
+		}
+		config.NvidiaDriver = s
+		updatePlan()
 	})
-	desktopSelect.SetSelected(config.Desktop)
+	selectNvidia := func() {
+		if config.NvidiaDriver == "" {
+			nvidiaSelect.SetSelected(noNvidia)
+		} else {
+			nvidiaSelect.SetSelected(config.NvidiaDriver)
+		}
+	}
+	selectNvidia()
 
-	nvidiaCheck := widget.NewCheck("Install NVIDIA Drivers", func(b bool) {
-		config.InstallNvidia = b
+	kernelSelect := widget.NewSelect(data.Kernels, func(s string) {
+		config.Kernel = s
+		config.NvidiaDriver = data.NvidiaDriverForKernel(config.NvidiaDriver, s)
+		nvidiaSelect.Options = nvidiaOptions(s)
+		selectNvidia()
+		updatePlan()
 	})
-	nvidiaCheck.Checked = config.InstallNvidia
+	kernelSelect.SetSelected(config.Kernel)
+
+	intelCheck := widget.NewCheck("Intel (vulkan-intel, intel-media-driver)", func(b bool) {
+		config.GPUIntel = b
+		updatePlan()
+	})
+	intelCheck.Checked = config.GPUIntel
+
+	amdCheck := widget.NewCheck("AMD (vulkan-radeon)", func(b bool) {
+		config.GPUAMD = b
+		updatePlan()
+	})
+	amdCheck.Checked = config.GPUAMD
+
+	multilibCheck := widget.NewCheck("Enable multilib (32-bit libraries for Steam/Wine)", func(b bool) {
+		config.Multilib = b
+		updatePlan()
+	})
+	multilibCheck.Checked = needsMultilib(config)
+	if data.BundlesNeedMultilib(config.Bundles) {
+		multilibCheck.Text += ", needed by the selected bundles"
+		multilibCheck.Disable()
+	}
+
+	updatePlan()
 
 	return container.NewVBox(
 		widget.NewLabelWithStyle("Choose Desktop Environment", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		widget.NewForm(
 			widget.NewFormItem("Desktop", desktopSelect),
-			widget.NewFormItem("Graphics", nvidiaCheck),
 		),
+		descLabel,
+		screenshotBox,
+		variantBox,
+		extrasBox,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Kernel & Graphics", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewForm(
+			widget.NewFormItem("Kernel", kernelSelect),
+			widget.NewFormItem("Integrated/AMD", container.NewVBox(intelCheck, amdCheck)),
+			widget.NewFormItem("NVIDIA Driver", nvidiaSelect),
+			widget.NewFormItem("", multilibCheck),
+		),
+		planLabel,
 	)
 }
 
+func findDesktopByName(c *data.DesktopCatalog, name string) (data.Desktop, bool) {
+	for _, d := range c.Desktops {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return data.Desktop{}, false
+}
+
+func desktopDescription(d data.Desktop) string {
+	session := "X11"
+	if d.Session == data.SessionWayland {
+		session = "Wayland"
+	}
+	return fmt.Sprintf("%s\nSession: %s, login screen: %s", d.Description, session, d.DisplayManager)
+}
+
+// desktopScreenshot shows the catalog image when it ships with the
+// installer, nil otherwise
+func desktopScreenshot(d data.Desktop) fyne.CanvasObject {
+	if d.Screenshot == "" {
+		return nil
+	}
+	if _, err := os.Stat(d.Screenshot); err != nil {
+		return nil
+	}
+	img := canvas.NewImageFromFile(d.Screenshot)
+	img.FillMode = canvas.ImageFillContain
+	img.SetMinSize(fyne.NewSize(320, 180))
+	return img
+}
+
+func nvidiaOptions(kernel string) []string {
+	return append([]string{noNvidia}, data.NvidiaDriverOptions(kernel)...)
+}
+
+// gpuPlanSummary describes what the current graphics selection will install
+func gpuPlanSummary(config *state.InstallConfig) string {
+	plan := planGPU(config)
+	if len(plan.Packages) == 0 && len(plan.AURPackages) == 0 {
+		return "No graphics drivers selected (basic framebuffer only)."
+	}
+
+	s := "Packages: " + strings.Join(plan.Packages, " ")
+	if len(plan.AURPackages) > 0 {
+		s += "\nFrom the AUR (needs an AUR helper): " + strings.Join(plan.AURPackages, " ")
+	}
+	if drv, ok := data.FindNvidiaDriver(config.NvidiaDriver); ok && drv.Package != "nouveau" {
+		s += "\nNVIDIA: " + drv.Label
+	}
+	if len(plan.KernelParams) > 0 {
+		s += "\nKernel parameters: " + strings.Join(plan.KernelParams, " ")
+	}
+	return s
+}
+
+func planGPU(config *state.InstallConfig) data.GPUPlan {
+	return data.PlanGPUDrivers(config.GPUIntel, config.GPUAMD, config.NvidiaDriver, config.Kernel, needsMultilib(config))
+}
+
+// needsMultilib is the user's choice or a selected bundle needing lib32
+// packages; derived each time so deselecting a bundle turns it off again
+func needsMultilib(config *state.InstallConfig) bool {
+	return config.Multilib || data.BundlesNeedMultilib(config.Bundles)
+}
+
 func (p *DesktopPage) OnNext(config *state.InstallConfig) error {
 	return nil
 }
@@ -0,0 +1,133 @@
+package pages
+
+import (
+	"fmt"
+	"strings"
+
+	"archgui/gui/internal/data"
+	"archgui/gui/internal/state"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+type HardwarePage struct {
+	hw     data.Hardware
+	probed bool
+}
+
+func (p *HardwarePage) Title() string {
+	return "Hardware"
+}
+
+func (p *HardwarePage) Content(config *state.InstallConfig, ctrl WizardController) fyne.CanvasObject {
+	// Probe once and pre-select; later visits keep the user's choices
+	if !p.probed {
+		p.probed = true
+		p.hw = data.GetHardware()
+		applyHardwareDefaults(config, p.hw)
+	}
+
+	cpu := p.hw.CPUModel
+	if cpu == "" {
+		cpu = "Unknown"
+	}
+
+	chassis := "Desktop"
+	if p.hw.Laptop() {
+		chassis = "Laptop"
+	}
+
+	detected := widget.NewForm(
+		widget.NewFormItem("CPU", widget.NewLabel(cpu)),
+		widget.NewFormItem("Graphics", widget.NewLabel(gpuSummary(p.hw))),
+		widget.NewFormItem("Wi-Fi", widget.NewLabel(yesNo(p.hw.WiFi))),
+		widget.NewFormItem("Bluetooth", widget.NewLabel(yesNo(p.hw.Bluetooth))),
+		widget.NewFormItem("Type", widget.NewLabel(chassis)),
+	)
+
+	ucodeSelect := widget.NewSelect([]string{"intel-ucode", "amd-ucode", "none"}, func(s string) {
+		config.Microcode = s
+	})
+	ucodeSelect.SetSelected(config.Microcode)
+
+	btCheck := widget.NewCheck("Install Bluetooth support (bluez)", func(b bool) {
+		config.InstallBluetooth = b
+	})
+	btCheck.Checked = config.InstallBluetooth
+
+	powerSelect := widget.NewSelect([]string{"none", "tlp", "power-profiles-daemon"}, func(s string) {
+		config.PowerProfile = s
+	})
+	powerSelect.SetSelected(config.PowerProfile)
+
+	driverHint := widget.NewLabel("Graphics drivers are pre-selected from the detected GPUs and can be changed on the Desktop page.")
+	driverHint.Wrapping = fyne.TextWrapWord
+
+	return container.NewVBox(
+		widget.NewLabelWithStyle("Detected Hardware", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		detected,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Hardware Support", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewForm(
+			widget.NewFormItem("CPU Microcode", ucodeSelect),
+			widget.NewFormItem("Bluetooth", btCheck),
+			widget.NewFormItem("Power Management", powerSelect),
+		),
+		driverHint,
+	)
+}
+
+// applyHardwareDefaults pre-selects packages based on what was detected
+func applyHardwareDefaults(config *state.InstallConfig, hw data.Hardware) {
+	config.Microcode = hw.Microcode()
+	if config.Microcode == "" {
+		config.Microcode = "none"
+	}
+	config.GPUIntel = hw.HasGPU(data.VendorIntel)
+	config.GPUAMD = hw.HasGPU(data.VendorAMD)
+	config.NvidiaDriver = ""
+	for _, g := range hw.GPUs {
+		if g.VendorID == data.VendorNvidia {
+			config.NvidiaDriver = data.RecommendNvidiaDriver(g.DeviceID, config.Kernel)
+			break
+		}
+	}
+	config.InstallBluetooth = hw.Bluetooth
+	if hw.Laptop() {
+		config.PowerProfile = "power-profiles-daemon"
+	} else {
+		config.PowerProfile = "none"
+	}
+}
+
+func gpuSummary(hw data.Hardware) string {
+	if len(hw.GPUs) == 0 {
+		return "None detected"
+	}
+	var names []string
+	for _, g := range hw.GPUs {
+		names = append(names, fmt.Sprintf("%s [%04x:%04x]", g.VendorName(), g.VendorID, g.DeviceID))
+	}
+	s := strings.Join(names, ", ")
+	if hw.Hybrid() {
+		s += " (hybrid)"
+	}
+	return s
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "Yes"
+	}
+	return "No"
+}
+
+func (p *HardwarePage) OnNext(config *state.InstallConfig) error {
+	return nil
+}
+
+func NewHardwarePage() *HardwarePage {
+	return &HardwarePage{}
+}
@@ -1,15 +1,19 @@
 package pages
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"fmt"
-	"os"
-	"os/exec"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"archgui/gui/internal/command"
+	"archgui/gui/internal/data"
+	"archgui/gui/internal/installer"
+	"archgui/gui/internal/mirrors"
+	"archgui/gui/internal/network"
 	"archgui/gui/internal/state"
 
 	"fyne.io/fyne/v2"
@@ -51,9 +55,7 @@ func (p *InstallPage) Content(config *state.InstallConfig, ctrl WizardController
 }
 
 func (p *InstallPage) RunInstall(config *state.InstallConfig, ctrl WizardController) {
-	// 1. Generate Config
 	// We need to disable Next/Back during install
-
 	fyne.Do(func() {
 		ctrl.SetNextButtonEnabled(false)
 	})
@@ -61,44 +63,28 @@ func (p *InstallPage) RunInstall(config *state.InstallConfig, ctrl WizardControl
 
 	time.Sleep(500 * time.Millisecond) // UI settle
 
-	cfgStr := generateConfigEnv(config)
-	configFile := "/tmp/install.env"
-	if err := os.WriteFile(configFile, []byte(cfgStr), 0600); err != nil {
-		p.AppendLog("Error writing config: " + err.Error())
-		return
-	}
-
-	cmd := exec.Command("bash", "backend/arch-install.sh", "--config", configFile)
+	settings := installer.NewSettings(configVars(config))
+	engine := pickEngine(config, settings, ctrl.Runner(), p.AppendLog)
+	p.AppendLog("Install engine: " + engine.Name())
 
-	// Determine Pipe
-	cmdPipe, err := cmd.StdoutPipe()
-	if err != nil {
-		p.AppendLog("Error creating pipe: " + err.Error())
-		return
-	}
-	cmd.Stderr = cmd.Stdout
-
-	if err := cmd.Start(); err != nil {
-		p.AppendLog("Error starting command: " + err.Error())
-		return
-	}
-
-	scanner := bufio.NewScanner(cmdPipe)
-	for scanner.Scan() {
-		p.AppendLog(scanner.Text())
-	}
-
-	err = cmd.Wait()
-	if err != nil {
+	if err := engine.Install(context.Background(), settings); err != nil {
 		p.AppendLog(fmt.Sprintf("\nInstallation FAILED: %v", err))
 	} else {
 		p.AppendLog("\nInstallation SUCCESS! You can reboot now.")
-		// Maybe enable a "Finish" button?
-		// ctrl.Next() to a "Done" page? Or just leave it here.
 	}
-	// We keep buttons disabled or maybe enable "Close"?
-	// For wizard pattern, usually "Next" becomes "Finish".
-	// But we are at the last page.
+}
+
+// pickEngine picks the configured install engine. Settings the native
+// engine does not handle yet fall back to the script, noted in log.
+func pickEngine(config *state.InstallConfig, settings installer.Settings, runner command.Runner, log func(string)) installer.Engine {
+	if config.Engine == state.EngineNative {
+		keys := installer.Unsupported(settings)
+		if len(keys) == 0 {
+			return &installer.Native{Runner: runner, Root: "/mnt", Live: "/", Log: log}
+		}
+		log("The native engine does not support " + strings.Join(keys, ", ") + " yet, using the script.")
+	}
+	return &installer.Script{Runner: runner, Path: "backend/arch-install.sh", EnvFile: "/tmp/install.env", Log: log}
 }
 
 func (p *InstallPage) AppendLog(msg string) {
@@ -157,18 +143,167 @@ func boolToString(b bool) string {
 	return "no"
 }
 
+// rootPassword resolves the root account choice to the password the backend sets
+func rootPassword(c *state.InstallConfig) string {
+	switch c.RootMode {
+	case "same":
+		if u := c.PrimaryUser(); u != nil {
+			return u.Password
+		}
+		return ""
+	case "locked":
+		return ""
+	}
+	return c.RootPassword
+}
+
+// aurPackages is empty without a helper, there is nothing to build them with
+func aurPackages(c *state.InstallConfig) string {
+	if c.AURHelper == "" || c.AURHelper == "none" {
+		return ""
+	}
+	return strings.Join(c.AURPackages, " ")
+}
+
+// displayManager is the unit to enable for a desktop, "" for none
+func displayManager(desktop string) string {
+	d, _ := data.GetDesktopCatalog().Find(desktop)
+	return d.DisplayManager
+}
+
+// sudoersDropIn is empty when wheel should not get sudo
+func sudoersDropIn(c *state.InstallConfig) string {
+	if !data.UsesSudo(c.PrivilegeTool) {
+		return ""
+	}
+	return data.SudoersDropIn(c.AdminNoPassword)
+}
+
+// doasConf is empty when opendoas is not installed
+func doasConf(c *state.InstallConfig) string {
+	if !data.UsesDoas(c.PrivilegeTool) {
+		return ""
+	}
+	return data.DoasConf(c.AdminNoPassword)
+}
+
+// mirrorlist is the ranked list from the Mirrors page, empty to keep the live one
+func mirrorlist(c *state.InstallConfig) string {
+	if len(c.Mirrors) == 0 {
+		return ""
+	}
+	return mirrors.Render(c.Mirrors)
+}
+
+// wifiProfile is the NetworkManager keyfile for the Wi-Fi joined on the
+// Network page, empty when it is not copied to the installed system
+func wifiProfile(c *state.InstallConfig) (name, content string) {
+	if !c.CopyWifiProfile || c.WifiSSID == "" {
+		return "", ""
+	}
+	content, err := network.WifiProfile(c.WifiSSID, c.WifiPassphrase, c.WifiSecurity)
+	if err != nil {
+		return "", ""
+	}
+	return network.ProfileFileName(c.WifiSSID), content
+}
+
+// userVars serializes the account list as USER_COUNT plus indexed USER_<i>_* keys
+func userVars(users []state.User) [][2]string {
+	vars := [][2]string{{"USER_COUNT", strconv.Itoa(len(users))}}
+	for i, u := range users {
+		prefix := fmt.Sprintf("USER_%d_", i)
+		vars = append(vars,
+			[2]string{prefix + "NAME", u.Username},
+			[2]string{prefix + "FULL_NAME", u.FullName},
+			[2]string{prefix + "PASSWORD", u.Password},
+			[2]string{prefix + "ADMIN", boolToString(u.Admin)},
+			[2]string{prefix + "GROUPS", strings.Join(u.Groups, ",")},
+			[2]string{prefix + "SHELL", u.Shell},
+			[2]string{prefix + "HOME", u.Home},
+			[2]string{prefix + "SSH_KEYS", strings.Join(u.SSHKeys, "\n")},
+		)
+	}
+	return vars
+}
+
+// localeNames lists every locale that has to be generated: LANG, extras and LC_* overrides
+func localeNames(c *state.InstallConfig) []string {
+	names := append([]string{c.Locale}, c.ExtraLocales...)
+	for _, cat := range data.LCCategories {
+		if v := c.LocaleOverrides[cat]; v != "" {
+			names = append(names, v)
+		}
+	}
+	return names
+}
+
 func generateConfigEnv(c *state.InstallConfig) string {
-	return fmt.Sprintf(
-		"DISK=%s\nMANUAL_PARTITIONING=%s\nTARGET_ROOT=%s\nTARGET_EFI=%s\nFORMAT_ROOT=%s\nFORMAT_EFI=%s\n"+
-			"HOSTNAME=%s\nFULL_NAME=%s\nUSERNAME=%s\nROOT_PASSWORD=%s\nUSER_PASSWORD=%s\n"+
-			"TIMEZONE=%s\nLOCALE=%s\nKEYMAP=%s\n"+
-			"FS_TYPE=%s\nUSE_LUKS=%s\nLUKS_PASSWORD=%s\n"+
-			"DESKTOP_ENV=%s\nSHELL_CHOICE=%s\nHAS_NVIDIA=%s\nNONINTERACTIVE=yes\n",
-		c.Disk, boolToString(c.ManualPartitioning), c.TargetRoot, c.TargetEFI, boolToString(c.FormatRoot), boolToString(c.FormatEFI),
-		c.Hostname, c.FullName, c.Username, c.RootPassword, c.UserPassword,
-		c.Timezone, c.Locale, c.Keymap,
-		c.Filesystem,
-		boolToString(c.Encrypt), c.LuksPassword,
-		c.Desktop, c.Shell, boolToString(c.InstallNvidia),
-	)
+	return installer.NewSettings(configVars(c)).Env()
+}
+
+// configVars resolves the configuration to the keys both install engines read
+func configVars(c *state.InstallConfig) [][2]string {
+	gpu := planGPU(c)
+	wifiName, wifiConf := wifiProfile(c)
+	// Empty on BIOS (and unknown width), the backend would log "0-bit"
+	uefiBits := ""
+	if c.BootMode == "uefi" && c.UEFIBits != 0 {
+		uefiBits = strconv.Itoa(c.UEFIBits)
+	}
+
+	vars := [][2]string{
+		{"BOOT_MODE", c.BootMode},
+		{"UEFI_BITS", uefiBits},
+		{"DISK", c.Disk},
+		{"MANUAL_PARTITIONING", boolToString(c.ManualPartitioning)},
+		{"TARGET_ROOT", c.TargetRoot},
+		{"TARGET_EFI", c.TargetEFI},
+		{"FORMAT_ROOT", boolToString(c.FormatRoot)},
+		{"FORMAT_EFI", boolToString(c.FormatEFI)},
+		{"SWAP_SIZE", strconv.Itoa(c.SwapSize)},
+		{"OFFLINE_REPO", packageSourceDir(c)},
+		{"MIRRORLIST", mirrorlist(c)},
+		{"CACHE_SERVER", c.CacheServer},
+		{"CACHE_DIR", c.CacheDir},
+		{"HOSTNAME", c.Hostname},
+		{"WIFI_PROFILE_NAME", wifiName},
+		{"WIFI_PROFILE", wifiConf},
+		{"ROOT_PASSWORD", rootPassword(c)},
+		{"ROOT_LOCKED", boolToString(c.RootMode == "locked")},
+		{"PRIVILEGE_TOOL", c.PrivilegeTool},
+		{"SUDOERS_DROPIN", sudoersDropIn(c)},
+		{"DOAS_CONF", doasConf(c)},
+		{"ENABLE_SSHD", boolToString(c.EnableSSHD)},
+		{"SSH_DISABLE_PASSWORDS", boolToString(c.EnableSSHD && c.SSHDisablePasswords)},
+		{"TIMEZONE", c.Timezone},
+		{"LOCALE", c.Locale},
+		{"LOCALE_GEN", data.LocaleGen(data.ResolveLocales(data.GetLocales(), localeNames(c)))},
+		{"LOCALE_CONF", data.LocaleConf(c.Locale, c.LocaleOverrides)},
+		{"KEYMAP", c.Keymap},
+		{"X11_KEYBOARD_CONF", data.X11KeyboardConf(c.XkbLayout, c.XkbModel, c.XkbVariant, c.XkbOptions)},
+		{"FS_TYPE", c.Filesystem},
+		{"USE_LUKS", boolToString(c.Encrypt)},
+		{"LUKS_PASSWORD", c.LuksPassword},
+		{"DESKTOP_ENV", c.Desktop},
+		{"DESKTOP_VARIANT", c.DesktopVariant},
+		{"DESKTOP_PACKAGES", strings.Join(data.GetDesktopCatalog().Packages(c.Desktop, c.DesktopVariant, c.DesktopExtras), " ")},
+		{"DISPLAY_MANAGER", displayManager(c.Desktop)},
+		{"EXTRA_PACKAGES", strings.Join(data.BundlePackages(c.Bundles, c.ExtraPackages), " ")},
+		{"EXTRA_SERVICES", strings.Join(data.BundleServices(c.Bundles), " ")},
+		{"KERNEL", c.Kernel},
+		{"MULTILIB", boolToString(needsMultilib(c))},
+		{"GPU_PACKAGES", strings.Join(gpu.Packages, " ")},
+		{"GPU_AUR_PACKAGES", strings.Join(gpu.AURPackages, " ")},
+		{"AUR_HELPER", c.AURHelper},
+		{"AUR_PACKAGES", aurPackages(c)},
+		{"KERNEL_PARAMS", strings.Join(gpu.KernelParams, " ")},
+		{"INITRAMFS_MODULES", strings.Join(gpu.Modules, " ")},
+		{"REMOVE_KMS_HOOK", boolToString(gpu.RemoveKMSHook)},
+		{"MICROCODE", c.Microcode},
+		{"BLUETOOTH", boolToString(c.InstallBluetooth)},
+		{"POWER_PROFILE", c.PowerProfile},
+		{"NONINTERACTIVE", "yes"},
+	}
+	return append(vars, userVars(c.Users)...)
 }
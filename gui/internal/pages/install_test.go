@@ -1,7 +1,12 @@
 package pages
 
 import (
+	"archgui/gui/internal/data"
+	"archgui/gui/internal/installer"
 	"archgui/gui/internal/state"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -9,42 +14,99 @@ import (
 func TestGenerateConfigEnv(t *testing.T) {
 	// Setup a sample config
 	config := state.NewInstallConfig()
+	config.BootMode = "uefi"
+	config.UEFIBits = 32
 	config.Disk = "/dev/sda"
 	config.Hostname = "myarch"
-	config.FullName = "Alice Smith"
-	config.Username = "alice"
+	config.Users = []state.User{
+		{Username: "alice", FullName: "Alice Smith", Password: "secretuser", Admin: true, Groups: []string{"audio", "video"}, Shell: "zsh",
+			SSHKeys: []string{"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHkhCFGOSHncrqPVXxxn3+TFaYdQcKe6ZZXkkUSTfJno alice@laptop"}},
+		{Username: "bob", Password: "bobpw", Groups: []string{"games"}, Shell: "bash", Home: "/srv/bob"},
+	}
 	config.RootPassword = "secretroot"
-	config.UserPassword = "secretuser"
+	config.PrivilegeTool = "doas"
+	config.AdminNoPassword = true
+	config.EnableSSHD = true
+	config.SSHDisablePasswords = true
 	config.Timezone = "UTC"
-	config.Locale = "en_GB"
+	config.Locale = "en_GB.UTF-8"
+	config.LocaleOverrides = map[string]string{"LC_TIME": "de_DE.UTF-8"}
 	config.Keymap = "uk"
+	config.XkbLayout = "gb"
+	config.XkbVariant = "extd"
 	config.Filesystem = "btrfs"
 	config.Encrypt = true
 	config.LuksPassword = "cryptpass"
 	config.Desktop = "kde"
-	config.Shell = "zsh"
-	config.InstallNvidia = true
+	config.Kernel = "linux-lts"
+	config.GPUIntel = true
+	config.NvidiaDriver = "nvidia-open-dkms"
+	config.Bundles = []string{"printing"}
+	config.ExtraPackages = []string{"htop"}
+	config.AURHelper = "paru"
+	config.AURPackages = []string{"visual-studio-code-bin", "google-chrome"}
+	config.Microcode = "amd-ucode"
+	config.InstallBluetooth = true
+	config.PowerProfile = "tlp"
 	config.ManualPartitioning = false
 
 	envStr := generateConfigEnv(config)
 
 	checks := map[string]string{
-		"DISK":           "/dev/sda",
-		"HOSTNAME":       "myarch",
-		"FULL_NAME":      "Alice Smith",
-		"USERNAME":       "alice",
-		"ROOT_PASSWORD":  "secretroot",
-		"USER_PASSWORD":  "secretuser",
-		"TIMEZONE":       "UTC",
-		"LOCALE":         "en_GB",
-		"KEYMAP":         "uk",
-		"FS_TYPE":        "btrfs",
-		"USE_LUKS":       "yes",
-		"LUKS_PASSWORD":  "cryptpass",
-		"DESKTOP_ENV":    "kde",
-		"SHELL_CHOICE":   "zsh",
-		"HAS_NVIDIA":     "yes",
-		"NONINTERACTIVE": "yes",
+		"BOOT_MODE":             "uefi",
+		"UEFI_BITS":             "32",
+		"DISK":                  "/dev/sda",
+		"HOSTNAME":              "myarch",
+		"USER_COUNT":            "2",
+		"USER_0_NAME":           "alice",
+		"USER_0_FULL_NAME":      "'Alice Smith'",
+		"USER_0_PASSWORD":       "secretuser",
+		"USER_0_ADMIN":          "yes",
+		"USER_0_GROUPS":         "audio,video",
+		"USER_0_SHELL":          "zsh",
+		"USER_0_SSH_KEYS":       "'ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHkhCFGOSHncrqPVXxxn3+TFaYdQcKe6ZZXkkUSTfJno alice@laptop'",
+		"USER_1_NAME":           "bob",
+		"USER_1_SSH_KEYS":       "\n",
+		"USER_1_ADMIN":          "no",
+		"USER_1_GROUPS":         "games",
+		"USER_1_HOME":           "/srv/bob",
+		"ROOT_PASSWORD":         "secretroot",
+		"PRIVILEGE_TOOL":        "doas",
+		"SUDOERS_DROPIN":        "\n",
+		"ENABLE_SSHD":           "yes",
+		"SSH_DISABLE_PASSWORDS": "yes",
+		"TIMEZONE":              "UTC",
+		"LOCALE":                "en_GB",
+		"KEYMAP":                "uk",
+		"FS_TYPE":               "btrfs",
+		"USE_LUKS":              "yes",
+		"LUKS_PASSWORD":         "cryptpass",
+		"DESKTOP_ENV":           "kde",
+		"KERNEL":                "linux-lts",
+		"GPU_PACKAGES":          "'mesa vulkan-intel intel-media-driver nvidia-open-dkms nvidia-utils nvidia-settings linux-lts-headers nvidia-prime'",
+		"KERNEL_PARAMS":         "'nvidia_drm.modeset=1 nvidia_drm.fbdev=1'",
+		"INITRAMFS_MODULES":     "'nvidia nvidia_modeset nvidia_uvm nvidia_drm'",
+		"REMOVE_KMS_HOOK":       "yes",
+		"EXTRA_PACKAGES":        "'cups cups-pdf gutenprint system-config-printer avahi nss-mdns htop'",
+		"EXTRA_SERVICES":        "'cups avahi-daemon'",
+		"AUR_HELPER":            "paru",
+		"AUR_PACKAGES":          "'visual-studio-code-bin google-chrome'",
+		"MICROCODE":             "amd-ucode",
+		"BLUETOOTH":             "yes",
+		"POWER_PROFILE":         "tlp",
+		"NONINTERACTIVE":        "yes",
+	}
+
+	if !strings.Contains(envStr, "permit nopass setenv { LANG LC_ALL } :wheel") {
+		t.Errorf("DOAS_CONF does not carry the wheel rule:\n%s", envStr)
+	}
+
+	if strings.Contains(envStr, "HAS_NVIDIA") {
+		t.Errorf("HAS_NVIDIA was replaced by GPU_PACKAGES, found it in:\n%s", envStr)
+	}
+
+	if !strings.Contains(envStr, `Option "XkbLayout" "gb"`) || !strings.Contains(envStr, `Option "XkbVariant" "extd"`) {
+		t.Errorf("X11_KEYBOARD_CONF does not carry the layout:\n%s", envStr)
 	}
 
 	for key, expected := range checks {
@@ -54,3 +116,131 @@ func TestGenerateConfigEnv(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigEnvSourcesInBash(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+
+	config := state.NewInstallConfig()
+	config.Users[0].FullName = `Bob "The Builder" O'Neil`
+	config.RootPassword = `p@ss word$HOME;rm -rf /`
+	config.Locale = "en_US.UTF-8"
+	config.LocaleOverrides = map[string]string{"LC_TIME": "de_DE.UTF-8", "LC_PAPER": "de_DE.UTF-8"}
+
+	envFile := filepath.Join(t.TempDir(), "install.env")
+	if err := os.WriteFile(envFile, []byte(generateConfigEnv(config)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command("bash", "-c", `set -e; source "$1"; printf '%s|%s|%s' "$USER_0_FULL_NAME" "$ROOT_PASSWORD" "$LOCALE_CONF"`, "bash", envFile).CombinedOutput()
+	if err != nil {
+		t.Fatalf("sourcing env failed: %v\n%s", err, out)
+	}
+	want := config.Users[0].FullName + "|" + config.RootPassword + "|LANG=en_US.UTF-8\nLC_PAPER=de_DE.UTF-8\nLC_TIME=de_DE.UTF-8\n"
+	if string(out) != want {
+		t.Errorf("values changed after sourcing:\ngot:  %q\nwant: %q", out, want)
+	}
+}
+
+func TestRootPasswordModes(t *testing.T) {
+	config := state.NewInstallConfig()
+	config.RootPassword = "rootpw"
+	config.Users[0].Password = "userpw"
+
+	config.RootMode = "same"
+	if env := generateConfigEnv(config); !strings.Contains(env, "ROOT_PASSWORD=userpw\n") || !strings.Contains(env, "ROOT_LOCKED=no\n") {
+		t.Errorf("root should reuse the user password:\n%s", env)
+	}
+
+	config.RootMode = "locked"
+	if env := generateConfigEnv(config); !strings.Contains(env, "ROOT_PASSWORD=\n") || !strings.Contains(env, "ROOT_LOCKED=yes\n") {
+		t.Errorf("root should be locked without a password:\n%s", env)
+	}
+}
+
+func TestDesktopEnvFromCatalog(t *testing.T) {
+	config := state.NewInstallConfig()
+	for _, d := range data.GetDesktopCatalog().Desktops {
+		for _, v := range d.Variants {
+			config.Desktop, config.DesktopVariant = d.ID, v.ID
+			env := generateConfigEnv(config)
+			if !strings.Contains(env, "DISPLAY_MANAGER="+d.DisplayManager+"\n") {
+				t.Errorf("%s/%s: display manager %s is not enabled", d.ID, v.ID, d.DisplayManager)
+			}
+			if !strings.Contains(env, "DESKTOP_PACKAGES="+installer.ShellQuote(strings.Join(data.GetDesktopCatalog().Packages(d.ID, v.ID, nil), " "))+"\n") {
+				t.Errorf("%s/%s: package list missing from env", d.ID, v.ID)
+			}
+		}
+	}
+
+	config.Desktop = "none"
+	if env := generateConfigEnv(config); !strings.Contains(env, "DESKTOP_PACKAGES=\n") || !strings.Contains(env, "DISPLAY_MANAGER=\n") {
+		t.Errorf("none should not install a desktop")
+	}
+}
+
+func TestUEFIBitsOnlyForUEFI(t *testing.T) {
+	config := state.NewInstallConfig()
+	config.BootMode, config.UEFIBits = "bios", 0
+	if env := generateConfigEnv(config); !strings.Contains(env, "UEFI_BITS=\n") {
+		t.Errorf("BIOS install should leave UEFI_BITS empty:\n%s", env)
+	}
+}
+
+func TestGamingBundleEnablesMultilib(t *testing.T) {
+	config := state.NewInstallConfig()
+	config.Bundles = []string{"gaming"}
+	config.NvidiaDriver = "nvidia-open"
+	if env := generateConfigEnv(config); !strings.Contains(env, "MULTILIB=yes\n") || !strings.Contains(env, "lib32-nvidia-utils") {
+		t.Errorf("gaming bundle should enable multilib:\n%s", env)
+	}
+
+	// Deselecting the bundle turns it off again
+	config.Bundles = nil
+	if env := generateConfigEnv(config); !strings.Contains(env, "MULTILIB=no\n") || strings.Contains(env, "lib32-") {
+		t.Errorf("multilib left on without the gaming bundle:\n%s", env)
+	}
+}
+
+func TestWifiProfileEnv(t *testing.T) {
+	config := state.NewInstallConfig()
+	config.WifiSSID, config.WifiSecurity, config.WifiPassphrase = "Home Net", "psk", "it's secret"
+	if env := generateConfigEnv(config); !strings.Contains(env, "WIFI_PROFILE=\n") {
+		t.Errorf("profile written without being asked for:\n%s", env)
+	}
+
+	config.CopyWifiProfile = true
+	env := generateConfigEnv(config)
+	if !strings.Contains(env, "WIFI_PROFILE_NAME=Home_Net.nmconnection\n") {
+		t.Errorf("unexpected profile name:\n%s", env)
+	}
+	if !strings.Contains(env, `psk=it'\''s secret`) {
+		t.Errorf("passphrase not quoted into the profile:\n%s", env)
+	}
+}
+
+func TestOfflineRepoEnv(t *testing.T) {
+	config := state.NewInstallConfig()
+	config.OfflineRepo = "/run/media/usb/repo"
+	if env := generateConfigEnv(config); !strings.Contains(env, "OFFLINE_REPO=\n") {
+		t.Errorf("online source should not pass a directory:\n%s", env)
+	}
+	config.PackageSource = "offline"
+	if env := generateConfigEnv(config); !strings.Contains(env, "OFFLINE_REPO=/run/media/usb/repo\n") {
+		t.Errorf("offline directory missing:\n%s", env)
+	}
+}
+
+func TestMirrorlistEnv(t *testing.T) {
+	config := state.NewInstallConfig()
+	if env := generateConfigEnv(config); !strings.Contains(env, "MIRRORLIST=\n") {
+		t.Errorf("live mirrorlist should be kept:\n%s", env)
+	}
+	config.Mirrors = []string{"https://fast.example/$repo/os/$arch", "https://slow.example/$repo/os/$arch"}
+	env := generateConfigEnv(config)
+	want := `Server = https://fast.example/$repo/os/$arch` + "\n" + `Server = https://slow.example/$repo/os/$arch`
+	if !strings.Contains(env, want) {
+		t.Errorf("ranked order not written:\n%s", env)
+	}
+}
@@ -1,6 +1,7 @@
 package pages
 
 import (
+	"archgui/gui/internal/command"
 	"archgui/gui/internal/state"
 
 	"fyne.io/fyne/v2"
@@ -11,6 +12,10 @@ type WizardController interface {
 	Back()
 	ShowLog(msg string)
 	SetNextButtonEnabled(bool)
+	// Window is the parent for dialogs opened by a page
+	Window() fyne.Window
+	// Runner runs the programs pages call (lsblk, cfdisk, the installer)
+	Runner() command.Runner
 }
 
 type Page interface {
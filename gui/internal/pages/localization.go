@@ -1,6 +1,14 @@
 package pages
 
 import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"archgui/gui/internal/command"
+	"archgui/gui/internal/data"
 	"archgui/gui/internal/state"
 
 	"fyne.io/fyne/v2"
@@ -8,39 +16,321 @@ import (
 	"fyne.io/fyne/v2/widget"
 )
 
-type LocalizationPage struct{}
+// Maximum number of search hits offered in the drop-down
+const maxTimezoneMatches = 40
+
+// Categories offered as separate "Formats" overrides; the rest follow LANG
+var formatCategories = []string{"LC_TIME", "LC_NUMERIC", "LC_MONETARY", "LC_PAPER", "LC_MEASUREMENT"}
+
+const sameAsLang = "(same as Language)"
+
+type LocalizationPage struct {
+	zones  []string
+	groups map[string][]string
+
+	locales     []data.Locale
+	localeNames []string
+
+	keymaps []string
+	xkb     data.XkbList
+}
 
 func (p *LocalizationPage) Title() string {
 	return "Localization"
 }
 
 func (p *LocalizationPage) Content(config *state.InstallConfig, ctrl WizardController) fyne.CanvasObject {
-	tzEntry := widget.NewEntry()
-	tzEntry.SetPlaceHolder("Europe/London")
-	tzEntry.Text = config.Timezone
-	tzEntry.OnChanged = func(s string) { config.Timezone = s }
+	if p.zones == nil {
+		p.zones = data.GetTimezones()
+		p.groups = data.GroupTimezones(p.zones)
+	}
+
+	// --- Timezone: region/city picker with search ---
+	var regions []string
+	for r := range p.groups {
+		regions = append(regions, r)
+	}
+	sort.Strings(regions)
+
+	currentLabel := widget.NewLabel("")
+	citySelect := widget.NewSelect(nil, nil)
+	regionSelect := widget.NewSelect(regions, nil)
+
+	setTimezone := func(tz string) {
+		region, city := data.SplitTimezone(tz)
+		if _, ok := p.groups[region]; !ok {
+			return
+		}
+		config.Timezone = tz
+		currentLabel.SetText("Selected: " + tz)
+		regionSelect.SetSelected(region)
+		citySelect.SetSelected(city)
+	}
+
+	regionSelect.OnChanged = func(region string) {
+		citySelect.Options = p.groups[region]
+		citySelect.ClearSelected()
+		citySelect.Refresh()
+	}
+	citySelect.OnChanged = func(city string) {
+		if city == "" || regionSelect.Selected == "" {
+			return
+		}
+		config.Timezone = data.JoinTimezone(regionSelect.Selected, city)
+		currentLabel.SetText("Selected: " + config.Timezone)
+	}
 
-	locEntry := widget.NewEntry()
-	locEntry.SetPlaceHolder("en_US")
-	locEntry.Text = config.Locale
-	locEntry.OnChanged = func(s string) { config.Locale = s }
+	tzSearch := widget.NewSelectEntry(nil)
+	tzSearch.SetPlaceHolder("Search, e.g. Berlin or New_York")
+	tzSearch.OnChanged = func(s string) {
+		if data.IsValidTimezone(s) {
+			setTimezone(s)
+			return
+		}
+		tzSearch.SetOptions(p.searchTimezones(s))
+	}
 
-	keyEntry := widget.NewEntry()
-	keyEntry.SetPlaceHolder("us")
-	keyEntry.Text = config.Keymap
-	keyEntry.OnChanged = func(s string) { config.Keymap = s }
+	suggestion := data.SuggestTimezone(config.Locale)
+	suggestBtn := widget.NewButton("Use suggestion: "+suggestion, func() {
+		setTimezone(suggestion)
+	})
+	if suggestion == config.Timezone {
+		suggestBtn.Hide()
+	}
+
+	setTimezone(config.Timezone)
+
+	// --- Locales from the SUPPORTED list ---
+	if p.locales == nil {
+		p.locales = data.GetLocales()
+		p.localeNames = data.LocaleNames(p.locales)
+	}
+
+	langSelect := widget.NewSelect(p.localeNames, func(s string) { config.Locale = s })
+	langSelect.SetSelected(config.Locale)
+
+	formatsForm := widget.NewForm()
+	for _, cat := range formatCategories {
+		sel := widget.NewSelect(append([]string{sameAsLang}, p.localeNames...), func(s string) {
+			if config.LocaleOverrides == nil {
+				config.LocaleOverrides = map[string]string{}
+			}
+			if s == sameAsLang {
+				delete(config.LocaleOverrides, cat)
+			} else {
+				config.LocaleOverrides[cat] = s
+			}
+		})
+		if v := config.LocaleOverrides[cat]; v != "" {
+			sel.SetSelected(v)
+		} else {
+			sel.SetSelected(sameAsLang)
+		}
+		formatsForm.Append(cat, sel)
+	}
+
+	extraLabel := widget.NewLabel("")
+	updateExtra := func() {
+		if len(config.ExtraLocales) == 0 {
+			extraLabel.SetText("None")
+		} else {
+			extraLabel.SetText(strings.Join(config.ExtraLocales, ", "))
+		}
+	}
+	updateExtra()
+
+	extraSelect := widget.NewSelect(p.localeNames, nil)
+	extraSelect.PlaceHolder = "(Add another locale)"
+	addExtraBtn := widget.NewButton("Add", func() {
+		name := extraSelect.Selected
+		if name == "" || name == config.Locale || slices.Contains(config.ExtraLocales, name) {
+			return
+		}
+		config.ExtraLocales = append(config.ExtraLocales, name)
+		updateExtra()
+	})
+	clearExtraBtn := widget.NewButton("Clear", func() {
+		config.ExtraLocales = nil
+		updateExtra()
+	})
+
+	keyboard := p.keyboardContent(config, ctrl.Runner())
 
 	return container.NewVBox(
 		widget.NewLabelWithStyle("Configure Localization", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		widget.NewForm(
-			widget.NewFormItem("Timezone", tzEntry),
-			widget.NewFormItem("Locale", locEntry),
-			widget.NewFormItem("Keymap", keyEntry),
+			widget.NewFormItem("Search Timezone", tzSearch),
+			widget.NewFormItem("Region", regionSelect),
+			widget.NewFormItem("City", citySelect),
+			widget.NewFormItem("", currentLabel),
+			widget.NewFormItem("", suggestBtn),
+			widget.NewFormItem("Language (LANG)", langSelect),
+			widget.NewFormItem("Additional Locales", extraLabel),
+			widget.NewFormItem("", container.NewBorder(nil, nil, nil, container.NewHBox(addExtraBtn, clearExtraBtn), extraSelect)),
+		),
+		widget.NewSeparator(),
+		keyboard,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Formats", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabel("Use a different locale for dates, numbers or paper size, e.g. English UI with German formats."),
+		formatsForm,
+	)
+}
+
+const defaultVariant = "(default)"
+
+// keyboardContent builds the console keymap and X11 layout pickers with a
+// live preview in the installer session
+func (p *LocalizationPage) keyboardContent(config *state.InstallConfig, runner command.Runner) fyne.CanvasObject {
+	if p.keymaps == nil {
+		p.keymaps = data.GetConsoleKeymaps()
+		p.xkb = data.GetXkbList()
+	}
+
+	statusLabel := widget.NewLabel("")
+	applyLive := func() {
+		if err := applyLiveKeyboard(runner, config); err != nil {
+			statusLabel.SetText("Live preview unavailable: " + err.Error())
+		} else {
+			statusLabel.SetText("Layout active in this session, try it below.")
+		}
+	}
+
+	consoleSelect := widget.NewSelect(p.keymaps, func(s string) { config.Keymap = s })
+	consoleSelect.SetSelected(config.Keymap)
+
+	modelLabels, modelNames := xkbLabels(p.xkb.Models)
+	modelSelect := widget.NewSelect(modelLabels, func(s string) {
+		config.XkbModel = modelNames[s]
+		applyLive()
+	})
+
+	variantSelect := widget.NewSelect(nil, nil)
+	var variantNames map[string]string
+	fillVariants := func() {
+		var labels []string
+		labels, variantNames = xkbLabels(p.xkb.VariantsFor(config.XkbLayout))
+		variantSelect.Options = append([]string{defaultVariant}, labels...)
+		variantSelect.Refresh()
+	}
+	variantSelect.OnChanged = func(s string) {
+		config.XkbVariant = variantNames[s] // "" for the default variant
+		if suggestion := data.SuggestConsoleKeymap(p.keymaps, config.XkbLayout, config.XkbVariant); suggestion != "" {
+			consoleSelect.SetSelected(suggestion)
+		}
+		applyLive()
+	}
+
+	layoutLabels, layoutNames := xkbLabels(p.xkb.Layouts)
+	layoutSelect := widget.NewSelect(layoutLabels, func(s string) {
+		if layoutNames[s] != config.XkbLayout {
+			config.XkbVariant = ""
+		}
+		config.XkbLayout = layoutNames[s]
+		fillVariants()
+		variantSelect.SetSelected(xkbLabelFor(p.xkb.VariantsFor(config.XkbLayout), config.XkbVariant, defaultVariant))
+	})
+
+	optionsEntry := widget.NewEntry()
+	optionsEntry.SetPlaceHolder("e.g. caps:escape,grp:alt_shift_toggle")
+	optionsEntry.Text = config.XkbOptions
+	optionsEntry.OnSubmitted = func(s string) {
+		config.XkbOptions = strings.ReplaceAll(s, " ", "")
+		applyLive()
+	}
+	optionsEntry.OnChanged = func(s string) { config.XkbOptions = strings.ReplaceAll(s, " ", "") }
+
+	// Selecting the stored values triggers the live preview once
+	modelSelect.SetSelected(xkbLabelFor(p.xkb.Models, config.XkbModel, ""))
+	layoutSelect.SetSelected(xkbLabelFor(p.xkb.Layouts, config.XkbLayout, ""))
+
+	testEntry := widget.NewEntry()
+	testEntry.SetPlaceHolder("Type here to test the layout (e.g. umlauts, @, |)")
+
+	return container.NewVBox(
+		widget.NewLabelWithStyle("Keyboard", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewForm(
+			widget.NewFormItem("Layout", layoutSelect),
+			widget.NewFormItem("Variant", variantSelect),
+			widget.NewFormItem("Model", modelSelect),
+			widget.NewFormItem("Options", optionsEntry),
+			widget.NewFormItem("Console Keymap", consoleSelect),
+			widget.NewFormItem("Test", testEntry),
 		),
+		statusLabel,
 	)
 }
 
+// xkbLabels returns "name - description" labels and a label -> name lookup
+func xkbLabels(items []data.XkbItem) ([]string, map[string]string) {
+	labels := make([]string, len(items))
+	names := make(map[string]string, len(items))
+	for i, it := range items {
+		labels[i] = it.Name + " - " + it.Description
+		names[labels[i]] = it.Name
+	}
+	return labels, names
+}
+
+func xkbLabelFor(items []data.XkbItem, name, fallback string) string {
+	for _, it := range items {
+		if it.Name == name {
+			return it.Name + " - " + it.Description
+		}
+	}
+	return fallback
+}
+
+// applyLiveKeyboard switches the installer's X session to the selected layout
+func applyLiveKeyboard(r command.Runner, c *state.InstallConfig) error {
+	args := []string{"-layout", c.XkbLayout}
+	if c.XkbVariant != "" {
+		args = append(args, "-variant", c.XkbVariant)
+	}
+	if c.XkbModel != "" {
+		args = append(args, "-model", c.XkbModel)
+	}
+	// An empty -option clears previously applied options first
+	args = append(args, "-option", "")
+	if c.XkbOptions != "" {
+		args = append(args, "-option", c.XkbOptions)
+	}
+	_, err := r.Run(context.Background(), command.Cmd{Name: "setxkbmap", Args: args})
+	return err
+}
+
+// searchTimezones returns zones containing the query, ignoring case and treating space as underscore
+func (p *LocalizationPage) searchTimezones(query string) []string {
+	q := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(query), " ", "_"))
+	if q == "" {
+		return nil
+	}
+	var matches []string
+	for _, z := range p.zones {
+		if strings.Contains(strings.ToLower(z), q) {
+			matches = append(matches, z)
+			if len(matches) == maxTimezoneMatches {
+				break
+			}
+		}
+	}
+	return matches
+}
+
 func (p *LocalizationPage) OnNext(config *state.InstallConfig) error {
+	if !data.IsValidTimezone(config.Timezone) {
+		return fmt.Errorf("unknown timezone %q, please pick one from the list", config.Timezone)
+	}
+	for _, opt := range strings.Split(config.XkbOptions, ",") {
+		if opt != "" && !p.xkb.HasOption(opt) {
+			return fmt.Errorf("unknown keyboard option %q", opt)
+		}
+	}
+	for _, name := range localeNames(config) {
+		if _, ok := data.FindLocale(p.locales, name); !ok {
+			return fmt.Errorf("unsupported locale %q, please pick one from the list", name)
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,319 @@
+package pages
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"archgui/gui/internal/data"
+	"archgui/gui/internal/mirrors"
+	"archgui/gui/internal/state"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Benchmark limits: a handful of parallel downloads, each cut off so a dead
+// mirror cannot stall the page
+const (
+	benchWorkers = 8
+	benchTimeout = 5 * time.Second
+	benchMax     = 50 // mirrors tested per run, the filtered list is cut here
+)
+
+var keepChoices = []string{"5", "10", "20"}
+
+type MirrorsPage struct {
+	all     []mirrors.Mirror // live list merged with the archlinux.org status
+	loaded  bool
+	ranked  []mirrors.Result
+	keep    string
+	running bool
+
+	countries []string
+	protocols []string
+}
+
+func (p *MirrorsPage) Title() string {
+	return "Mirrors"
+}
+
+func (p *MirrorsPage) Content(config *state.InstallConfig, ctrl WizardController) fyne.CanvasObject {
+	statusLabel := widget.NewLabel("Loading the mirror list...")
+	statusLabel.Wrapping = fyne.TextWrapWord
+
+	listLabel := widget.NewLabel("")
+	showList := func() {
+		listLabel.SetText(p.listText(config))
+	}
+
+	countryGroup := widget.NewCheckGroup(nil, func(s []string) {
+		p.countries = s
+	})
+	countryGroup.Selected = p.countries
+	protocolGroup := widget.NewCheckGroup([]string{"https", "http"}, func(s []string) {
+		p.protocols = s
+	})
+	protocolGroup.Horizontal = true
+	protocolGroup.Selected = p.protocols
+
+	keepSelect := widget.NewSelect(keepChoices, func(s string) {
+		p.keep = s
+		if p.ranked != nil {
+			config.Mirrors = p.chosen()
+			showList()
+		}
+	})
+	keepSelect.SetSelected(p.keep)
+
+	var benchBtn *widget.Button
+	benchBtn = widget.NewButton("Benchmark", func() {
+		candidates := mirrors.Filter(p.all, p.countries, p.protocols)
+		if len(candidates) == 0 {
+			statusLabel.SetText("No mirrors match the filters.")
+			return
+		}
+		if len(candidates) > benchMax {
+			candidates = candidates[:benchMax]
+		}
+		p.running = true
+		benchBtn.Disable()
+		statusLabel.SetText(fmt.Sprintf("Testing %d mirrors...", len(candidates)))
+		go func() {
+			results := mirrors.Benchmark(context.Background(), candidates, benchWorkers, benchTimeout, func(done, total int) {
+				fyne.Do(func() {
+					statusLabel.SetText(fmt.Sprintf("Testing mirrors: %d of %d done", done, total))
+				})
+			})
+			fyne.Do(func() {
+				p.running = false
+				p.ranked = mirrors.Rank(results)
+				config.Mirrors = p.chosen()
+				statusLabel.SetText(benchmarkSummary(p.ranked))
+				showList()
+				benchBtn.Enable()
+			})
+		}()
+	})
+	resetBtn := widget.NewButton("Keep Current List", func() {
+		p.ranked = nil
+		config.Mirrors = nil
+		statusLabel.SetText("The live system's mirrorlist is used as is.")
+		showList()
+	})
+
+	if !p.loaded {
+		benchBtn.Disable()
+		go func() {
+			live, err := mirrors.GetMirrorlist()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			status, statusErr := mirrors.FetchStatus(ctx, mirrors.StatusURL)
+			fyne.Do(func() {
+				p.all, p.loaded = mirrors.Merge(live, status), true
+				switch {
+				case err != nil && statusErr != nil:
+					statusLabel.SetText("No mirrors available: " + err.Error())
+				case statusErr != nil:
+					statusLabel.SetText("Country information unavailable (" + statusErr.Error() + "), showing the live mirrorlist.")
+				default:
+					statusLabel.SetText(fmt.Sprintf("%d mirrors known. Pick countries and protocols, then benchmark.", len(p.all)))
+				}
+				countryGroup.Options = mirrors.Countries(p.all)
+				countryGroup.Refresh()
+				showList()
+				if !p.running {
+					benchBtn.Enable()
+				}
+			})
+		}()
+	} else {
+		countryGroup.Options = mirrors.Countries(p.all)
+		statusLabel.SetText(benchmarkSummary(p.ranked))
+		if p.running {
+			benchBtn.Disable()
+		}
+	}
+	showList()
+
+	// --- Lab cache ---
+	cacheStatus := widget.NewLabel("")
+	cacheStatus.Wrapping = fyne.TextWrapWord
+	serverEntry := widget.NewEntry()
+	serverEntry.SetPlaceHolder("http://cache.lan:9129/repo/archlinux/$repo/os/$arch")
+	serverEntry.SetText(config.CacheServer)
+	serverEntry.Validator = func(s string) error {
+		if s == "" {
+			return nil
+		}
+		return mirrors.ValidateServer(s)
+	}
+	serverEntry.OnChanged = func(s string) {
+		config.CacheServer = strings.TrimSpace(s)
+	}
+	dirEntry := widget.NewEntry()
+	dirEntry.SetPlaceHolder("/mnt/pkgcache")
+	dirEntry.SetText(config.CacheDir)
+	dirEntry.OnChanged = func(s string) {
+		config.CacheDir = strings.TrimSpace(s)
+	}
+	var testBtn *widget.Button
+	testBtn = widget.NewButton("Test", func() {
+		testBtn.Disable()
+		cacheStatus.SetText("Checking the cache...")
+		server, dir := config.CacheServer, config.CacheDir
+		go func() {
+			msg := labCacheStatus(server, dir)
+			fyne.Do(func() {
+				cacheStatus.SetText(msg)
+				testBtn.Enable()
+			})
+		}()
+	})
+
+	countryScroll := container.NewVScroll(countryGroup)
+	countryScroll.SetMinSize(fyne.NewSize(0, 150))
+
+	return container.NewVBox(
+		widget.NewLabelWithStyle("Filters", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewForm(
+			widget.NewFormItem("Protocols", protocolGroup),
+			&widget.FormItem{Text: "Countries", Widget: countryScroll, HintText: "None selected means all countries"},
+			widget.NewFormItem("Keep", keepSelect),
+		),
+		container.NewHBox(benchBtn, resetBtn),
+		statusLabel,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Mirror Order", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		listLabel,
+		widget.NewLabel("The order is written to the live system before downloading and to the installed system."),
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Lab Cache", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewForm(
+			&widget.FormItem{Text: "Caching Proxy", Widget: serverEntry, HintText: "pacoloco or nginx cache, tried before the mirrors"},
+			&widget.FormItem{Text: "Shared Cache", Widget: container.NewBorder(nil, nil, nil, testBtn, dirEntry), HintText: "Mounted directory (NFS) used as pacman's package cache"},
+		),
+		cacheStatus,
+		widget.NewLabel("Both are checked again before partitioning. If unreachable, the installation uses the mirrors."),
+	)
+}
+
+// labCacheStatus probes the caching proxy and the shared cache directory
+func labCacheStatus(server, dir string) string {
+	if server == "" && dir == "" {
+		return "No lab cache configured."
+	}
+	var lines []string
+	if server != "" {
+		if err := mirrors.Probe(context.Background(), server, benchTimeout); err != nil {
+			lines = append(lines, "Proxy unreachable, the mirrors will be used: "+err.Error())
+		} else {
+			lines = append(lines, "Proxy reachable.")
+		}
+	}
+	if dir != "" {
+		if err := cacheDirUsable(dir); err != nil {
+			lines = append(lines, "Shared cache not usable, the target's own cache will be used: "+err.Error())
+		} else {
+			lines = append(lines, "Shared cache directory found.")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cacheDirUsable requires an existing absolute directory
+func cacheDirUsable(dir string) error {
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("%s is not an absolute path", dir)
+	}
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return nil
+}
+
+// chosen is the fastest working mirrors, as many as the Keep choice
+func (p *MirrorsPage) chosen() []string {
+	n, _ := strconv.Atoi(p.keep)
+	var servers []string
+	for _, r := range p.ranked {
+		if r.Err != nil || len(servers) >= n {
+			break
+		}
+		servers = append(servers, r.Mirror.Server)
+	}
+	return servers
+}
+
+// listText shows the order that will be written: the chosen mirrors with
+// their speed, or the live list when nothing was chosen
+func (p *MirrorsPage) listText(config *state.InstallConfig) string {
+	var s string
+	if len(config.Mirrors) > 0 {
+		for i, server := range config.Mirrors {
+			s += fmt.Sprintf("%d. %s%s\n", i+1, server, p.rateOf(server))
+		}
+		return s
+	}
+	n := 0
+	for _, m := range p.all {
+		if m.Enabled {
+			n++
+			s += fmt.Sprintf("%d. %s\n", n, m.Server)
+		}
+	}
+	if s == "" {
+		return "No active mirrors in the live mirrorlist."
+	}
+	return "Current (live) mirrorlist:\n" + s
+}
+
+func (p *MirrorsPage) rateOf(server string) string {
+	for _, r := range p.ranked {
+		if r.Mirror.Server == server && r.Err == nil {
+			return fmt.Sprintf(" (%s/s)", data.FormatSize(int64(r.Rate())))
+		}
+	}
+	return ""
+}
+
+func benchmarkSummary(ranked []mirrors.Result) string {
+	if ranked == nil {
+		return "The live system's mirrorlist is used as is."
+	}
+	failed := 0
+	for _, r := range ranked {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	return fmt.Sprintf("%d mirrors tested, %d did not answer within %s.", len(ranked), failed, benchTimeout)
+}
+
+func (p *MirrorsPage) OnNext(config *state.InstallConfig) error {
+	if config.CacheServer != "" {
+		if err := mirrors.ValidateServer(config.CacheServer); err != nil {
+			return fmt.Errorf("caching proxy: %w", err)
+		}
+	}
+	if config.CacheDir != "" && !filepath.IsAbs(config.CacheDir) {
+		return fmt.Errorf("shared cache: %s is not an absolute path", config.CacheDir)
+	}
+	if p.ranked != nil && len(config.Mirrors) == 0 {
+		return fmt.Errorf("no mirror answered, change the filters or keep the current list")
+	}
+	return nil
+}
+
+func NewMirrorsPage() *MirrorsPage {
+	return &MirrorsPage{keep: "10", protocols: []string{"https"}}
+}
@@ -0,0 +1,410 @@
+package pages
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"archgui/gui/internal/data"
+	"archgui/gui/internal/network"
+	"archgui/gui/internal/state"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Package source choices
+const (
+	sourceOnline       = "online"
+	sourceOffline      = "offline"
+	sourceOnlineLabel  = "Online mirrors"
+	sourceOfflineLabel = "Local repository or package cache (offline)"
+)
+
+type NetworkPage struct {
+	stations []network.Station
+	networks []network.WifiNetwork
+	station  int // index into stations
+}
+
+func (p *NetworkPage) Title() string {
+	return "Network"
+}
+
+func (p *NetworkPage) Content(config *state.InstallConfig, ctrl WizardController) fyne.CanvasObject {
+	ifaceBox := container.NewVBox()
+	refreshInterfaces := func() {
+		ifaceBox.RemoveAll()
+		ifaces, err := network.GetInterfaces()
+		if err != nil {
+			ifaceBox.Add(widget.NewLabel("Cannot list interfaces: " + err.Error()))
+			return
+		}
+		if len(ifaces) == 0 {
+			ifaceBox.Add(widget.NewLabel("No network interfaces found."))
+		}
+		for _, i := range ifaces {
+			ifaceBox.Add(widget.NewLabel(interfaceLabel(i)))
+		}
+	}
+	refreshInterfaces()
+
+	// --- Connectivity ---
+	onlineLabel := widget.NewLabel("Checking the internet connection...")
+	onlineLabel.Wrapping = fyne.TextWrapWord
+	var checkBtn *widget.Button
+	checkOnline := func() {
+		checkBtn.Disable()
+		onlineLabel.SetText("Checking the internet connection...")
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			err := network.CheckConnectivity(ctx, network.ConnectivityURL)
+			fyne.Do(func() {
+				if err != nil {
+					onlineLabel.SetText("Offline: " + err.Error())
+				} else {
+					onlineLabel.SetText("Online: archlinux.org is reachable.")
+				}
+				refreshInterfaces()
+				checkBtn.Enable()
+			})
+		}()
+	}
+	checkBtn = widget.NewButton("Check Again", checkOnline)
+
+	// --- Wi-Fi ---
+	wifiStatus := widget.NewLabel("")
+	wifiStatus.Wrapping = fyne.TextWrapWord
+
+	copyCheck := widget.NewCheck("Use this Wi-Fi network on the installed system (NetworkManager)", func(on bool) {
+		config.CopyWifiProfile = on
+	})
+	copyCheck.Checked = config.CopyWifiProfile
+	if !canCopyWifi(config) {
+		copyCheck.Disable()
+	}
+
+	networkSelect := widget.NewSelect(nil, nil)
+	networkSelect.PlaceHolder = "Scan to list networks"
+	passEntry := widget.NewPasswordEntry()
+	passEntry.SetPlaceHolder("Passphrase")
+
+	stationSelect := widget.NewSelect(nil, func(string) {})
+	var scanBtn, connectBtn *widget.Button
+	setBusy := func(b bool) {
+		if b || len(p.stations) == 0 {
+			scanBtn.Disable()
+			connectBtn.Disable()
+			return
+		}
+		scanBtn.Enable()
+		connectBtn.Enable()
+	}
+
+	showNetworks := func() {
+		labels := make([]string, len(p.networks))
+		for i, n := range p.networks {
+			labels[i] = wifiNetworkLabel(n)
+		}
+		networkSelect.SetOptions(labels)
+		networkSelect.ClearSelected()
+		for i, n := range p.networks {
+			if n.Connected || n.Name == config.WifiSSID {
+				networkSelect.SetSelectedIndex(i)
+				break
+			}
+		}
+	}
+
+	scan := func() {
+		if len(p.stations) == 0 {
+			return
+		}
+		station := p.stations[p.station]
+		setBusy(true)
+		wifiStatus.SetText("Scanning on " + station.Device + "...")
+		go func() {
+			nets, err := scanNetworks(station)
+			fyne.Do(func() {
+				if err != nil {
+					wifiStatus.SetText("Scan failed: " + err.Error())
+				} else {
+					p.networks = nets
+					wifiStatus.SetText(fmt.Sprintf("%d networks found.", len(nets)))
+					showNetworks()
+				}
+				setBusy(false)
+			})
+		}()
+	}
+	scanBtn = widget.NewButton("Scan", scan)
+
+	connectBtn = widget.NewButton("Connect", func() {
+		i := networkSelect.SelectedIndex()
+		if i < 0 || i >= len(p.networks) {
+			wifiStatus.SetText("Select a network first.")
+			return
+		}
+		n := p.networks[i]
+		if n.Security == network.Security8021X {
+			wifiStatus.SetText("Enterprise (802.1X) networks need a configuration file, use iwctl in a terminal.")
+			return
+		}
+		passphrase := passEntry.Text
+		setBusy(true)
+		wifiStatus.SetText("Connecting to " + n.Name + "...")
+		go func() {
+			err := connectNetwork(n, passphrase)
+			fyne.Do(func() {
+				setBusy(false)
+				if err != nil {
+					wifiStatus.SetText("Could not connect: " + err.Error())
+					return
+				}
+				wifiStatus.SetText("Connected to " + n.Name + ".")
+				config.WifiSSID, config.WifiSecurity, config.WifiPassphrase = n.Name, n.Security, passphrase
+				config.CopyWifiProfile = canCopyWifi(config)
+				copyCheck.SetChecked(config.CopyWifiProfile)
+				if config.CopyWifiProfile {
+					copyCheck.Enable()
+				} else {
+					copyCheck.Disable()
+				}
+				checkOnline()
+			})
+		}()
+	})
+
+	stationSelect.OnChanged = func(string) {
+		p.station = stationSelect.SelectedIndex()
+		p.networks = nil
+		showNetworks()
+		scan()
+	}
+
+	// --- Package source ---
+	repoStatus := widget.NewLabel("")
+	repoStatus.Wrapping = fyne.TextWrapWord
+	repoEntry := widget.NewEntry()
+	repoEntry.SetPlaceHolder("/run/media/usb/repo")
+	repoEntry.SetText(config.OfflineRepo)
+	checkRepo := func() {
+		if config.PackageSource != sourceOffline {
+			repoStatus.SetText("Packages are downloaded from the mirrors.")
+			return
+		}
+		if r, err := data.OpenLocalRepo(config.OfflineRepo); err != nil {
+			repoStatus.SetText("Not usable: " + err.Error())
+		} else {
+			repoStatus.SetText("Found a " + r.String() + ". Missing packages are reported before the disk is touched.")
+		}
+	}
+	repoEntry.OnChanged = func(s string) {
+		config.OfflineRepo = strings.TrimSpace(s)
+		checkRepo()
+	}
+	browseBtn := widget.NewButton("Browse...", func() {
+		pickDirectory(ctrl.Window(), repoEntry.SetText)
+	})
+	sourceRadio := widget.NewRadioGroup([]string{sourceOnlineLabel, sourceOfflineLabel}, func(s string) {
+		if s == sourceOfflineLabel {
+			config.PackageSource = sourceOffline
+			repoEntry.Enable()
+			browseBtn.Enable()
+		} else {
+			config.PackageSource = sourceOnline
+			repoEntry.Disable()
+			browseBtn.Disable()
+		}
+		checkRepo()
+	})
+	if config.PackageSource == sourceOffline {
+		sourceRadio.SetSelected(sourceOfflineLabel)
+	} else {
+		sourceRadio.SetSelected(sourceOnlineLabel)
+	}
+
+	setBusy(false)
+	if p.stations == nil {
+		wifiStatus.SetText("Looking for Wi-Fi devices...")
+		go func() {
+			stations, err := loadStations()
+			fyne.Do(func() {
+				p.stations = stations
+				switch {
+				case err != nil:
+					wifiStatus.SetText("Wi-Fi is not available: " + err.Error())
+				case len(stations) == 0:
+					wifiStatus.SetText("No Wi-Fi devices found.")
+				default:
+					stationSelect.SetOptions(stationNames(stations))
+					stationSelect.SetSelectedIndex(0) // scans
+				}
+				setBusy(false)
+			})
+		}()
+	} else if len(p.stations) > 0 {
+		stationSelect.Options = stationNames(p.stations)
+		stationSelect.SetSelectedIndex(p.station)
+	}
+	checkOnline()
+
+	return container.NewVBox(
+		widget.NewLabelWithStyle("Interfaces", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		ifaceBox,
+		container.NewHBox(onlineLabel, checkBtn),
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Wi-Fi", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewForm(
+			widget.NewFormItem("Device", container.NewBorder(nil, nil, nil, scanBtn, stationSelect)),
+			widget.NewFormItem("Network", networkSelect),
+			widget.NewFormItem("Passphrase", container.NewBorder(nil, nil, nil, connectBtn, passEntry)),
+		),
+		wifiStatus,
+		copyCheck,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Package Source", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		sourceRadio,
+		widget.NewForm(widget.NewFormItem("Directory", container.NewBorder(nil, nil, nil, browseBtn, repoEntry))),
+		repoStatus,
+		widget.NewLabel("A wired connection needs no setup. Online installs download packages, so stay connected until the installation finishes.\nOffline installs skip AUR packages and oh-my-zsh."),
+	)
+}
+
+// pickDirectory opens a folder dialog below /run/media, where removable
+// media is mounted
+func pickDirectory(win fyne.Window, set func(path string)) {
+	d := dialog.NewFolderOpen(func(dir fyne.ListableURI, err error) {
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		if dir != nil {
+			set(dir.Path())
+		}
+	}, win)
+	if dir, err := storage.ListerForURI(storage.NewFileURI("/run/media")); err == nil {
+		d.SetLocation(dir)
+	}
+	d.Show()
+}
+
+// offlinePreflight checks the packages chosen in the wizard against the
+// offline source. The backend repeats the check with its full package list
+// before partitioning. A bare cache that cannot be checked yields an error
+// wrapping data.ErrNoPackageDB, which is a warning rather than a blocker.
+func offlinePreflight(config *state.InstallConfig) error {
+	dir := packageSourceDir(config)
+	if dir == "" {
+		return nil
+	}
+	r, err := data.OpenLocalRepo(dir)
+	if err != nil {
+		return fmt.Errorf("offline package source: %w", err)
+	}
+	names := []string{config.Kernel}
+	names = append(names, planGPU(config).Packages...)
+	names = append(names, data.GetDesktopCatalog().Packages(config.Desktop, config.DesktopVariant, config.DesktopExtras)...)
+	names = append(names, data.BundlePackages(config.Bundles, config.ExtraPackages)...)
+	live, _ := data.GetSyncDB()
+	missing, err := r.Missing(names, live)
+	if err != nil {
+		return fmt.Errorf("offline package source: %w", err)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("not available offline: %s", strings.Join(missing, " "))
+	}
+	return nil
+}
+
+// canCopyWifi reports whether the joined network can be written as a profile:
+// iwd may have connected with stored credentials the installer never saw
+func canCopyWifi(config *state.InstallConfig) bool {
+	switch config.WifiSecurity {
+	case network.SecurityOpen:
+		return config.WifiSSID != ""
+	case network.SecurityPSK:
+		return config.WifiSSID != "" && config.WifiPassphrase != ""
+	}
+	return false
+}
+
+func loadStations() ([]network.Station, error) {
+	iwd, err := network.ConnectIWD()
+	if err != nil {
+		return nil, err
+	}
+	defer iwd.Close()
+	return iwd.Stations()
+}
+
+func scanNetworks(s network.Station) ([]network.WifiNetwork, error) {
+	iwd, err := network.ConnectIWD()
+	if err != nil {
+		return nil, err
+	}
+	defer iwd.Close()
+	if err := iwd.Scan(s.Path); err != nil {
+		return nil, err
+	}
+	return iwd.Networks(s.Path)
+}
+
+func connectNetwork(n network.WifiNetwork, passphrase string) error {
+	iwd, err := network.ConnectIWD()
+	if err != nil {
+		return err
+	}
+	defer iwd.Close()
+	return iwd.Connect(n.Path, passphrase)
+}
+
+func stationNames(stations []network.Station) []string {
+	names := make([]string, len(stations))
+	for i, s := range stations {
+		names[i] = s.Device
+	}
+	return names
+}
+
+func interfaceLabel(i network.Interface) string {
+	kind := "Wired"
+	if i.Wireless {
+		kind = "Wireless"
+	}
+	return fmt.Sprintf("%s: %s, %s (%s)", i.Name, kind, i.State, i.MAC)
+}
+
+func wifiNetworkLabel(n network.WifiNetwork) string {
+	parts := []string{fmt.Sprintf("%d dBm", n.Signal), n.Security}
+	if n.Connected {
+		parts = append(parts, "connected")
+	}
+	return fmt.Sprintf("%s (%s)", n.Name, strings.Join(parts, ", "))
+}
+
+// OnNext does not require a connection, a wired link needs no setup here.
+// Only a profile that cannot be written or an unusable offline source are
+// rejected.
+func (p *NetworkPage) OnNext(config *state.InstallConfig) error {
+	if config.PackageSource == sourceOffline {
+		if _, err := data.OpenLocalRepo(config.OfflineRepo); err != nil {
+			return fmt.Errorf("offline package source: %w", err)
+		}
+	}
+	if config.CopyWifiProfile {
+		if _, err := network.WifiProfile(config.WifiSSID, config.WifiPassphrase, config.WifiSecurity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func NewNetworkPage() *NetworkPage {
+	return &NetworkPage{}
+}
@@ -0,0 +1,208 @@
+package pages
+
+import (
+	"archgui/gui/internal/data"
+	"archgui/gui/internal/state"
+	"archgui/gui/internal/validation"
+	"fmt"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+type PackagesPage struct {
+	// Package database of the chosen source, loaded in the background
+	db       *data.SyncDB
+	dbErr    error
+	loaded   bool
+	dbSource string // OfflineRepo the database was loaded for
+
+	customErr error // parse error of the free-form list, blocks Next
+	aurErr    error // same for the AUR list
+}
+
+var aurHelpers = []string{"none", "yay", "paru"}
+
+func (p *PackagesPage) Title() string {
+	return "Additional Software"
+}
+
+func (p *PackagesPage) Content(config *state.InstallConfig, ctrl WizardController) fyne.CanvasObject {
+	statusLabel := widget.NewLabel("Checking the package database...")
+	statusLabel.Wrapping = fyne.TextWrapWord
+	update := func() {
+		if p.loaded {
+			statusLabel.SetText(p.status(config))
+		}
+	}
+
+	// --- Bundles ---
+	bundleBox := container.NewVBox()
+	for _, b := range data.GetBundles() {
+		check := widget.NewCheck(b.Name, func(on bool) {
+			config.Bundles = slices.DeleteFunc(config.Bundles, func(id string) bool { return id == b.ID })
+			// [multilib] follows the selection, see needsMultilib
+			if on {
+				config.Bundles = append(config.Bundles, b.ID)
+			}
+			update()
+		})
+		check.Checked = slices.Contains(config.Bundles, b.ID)
+		desc := widget.NewLabel(b.Description)
+		desc.Importance = widget.LowImportance
+		bundleBox.Add(container.NewVBox(check, desc))
+	}
+
+	// --- Custom packages ---
+	customEntry := widget.NewMultiLineEntry()
+	customEntry.Text = strings.Join(config.ExtraPackages, " ")
+	customEntry.SetPlaceHolder("htop neovim firefox")
+	customEntry.SetMinRowsVisible(3)
+	customEntry.Validator = func(s string) error {
+		_, err := validation.ParsePackageList(s)
+		return err
+	}
+	customEntry.OnChanged = func(s string) {
+		pkgs, err := validation.ParsePackageList(s)
+		p.customErr = err
+		if err == nil {
+			config.ExtraPackages = pkgs
+		}
+		update()
+	}
+
+	// --- AUR ---
+	aurEntry := widget.NewMultiLineEntry()
+	aurEntry.Text = strings.Join(config.AURPackages, " ")
+	aurEntry.SetPlaceHolder("visual-studio-code-bin google-chrome")
+	aurEntry.SetMinRowsVisible(2)
+	aurEntry.Validator = customEntry.Validator
+	aurEntry.OnChanged = func(s string) {
+		pkgs, err := validation.ParsePackageList(s)
+		p.aurErr = err
+		if err == nil {
+			config.AURPackages = pkgs
+		}
+	}
+
+	aurNote := widget.NewLabel("")
+	aurNote.Wrapping = fyne.TextWrapWord
+	if gpu := planGPU(config); len(gpu.AURPackages) > 0 {
+		aurNote.SetText("The selected NVIDIA driver (" + strings.Join(gpu.AURPackages, " ") + ") is only in the AUR. Pick a helper to have it installed.")
+	}
+
+	helperSelect := widget.NewSelect(aurHelpers, func(s string) {
+		config.AURHelper = s
+		if s == "none" {
+			aurEntry.Disable()
+		} else {
+			aurEntry.Enable()
+		}
+	})
+	helperSelect.SetSelected(config.AURHelper)
+
+	if source := packageSourceDir(config); !p.loaded || p.dbSource != source {
+		p.loaded, p.dbSource = false, source
+		go func() {
+			db, err := packageDB(config)
+			fyne.Do(func() {
+				p.db, p.dbErr, p.loaded = db, err, true
+				update()
+			})
+		}()
+	} else {
+		update()
+	}
+
+	return container.NewVBox(
+		widget.NewLabelWithStyle("Software Bundles", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		bundleBox,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Extra Packages", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewForm(&widget.FormItem{Text: "Packages", Widget: customEntry, HintText: "Package or group names separated by spaces"}),
+		statusLabel,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("AUR", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabel("AUR packages are user-maintained and built from source as the first user.\nBuild failures are reported as warnings and do not stop the installation."),
+		widget.NewForm(
+			widget.NewFormItem("Helper", helperSelect),
+			&widget.FormItem{Text: "AUR Packages", Widget: aurEntry, HintText: "Package names separated by spaces"},
+		),
+		aurNote,
+	)
+}
+
+// status describes the selection: size estimate and names the database does not know
+func (p *PackagesPage) status(config *state.InstallConfig) string {
+	names := data.BundlePackages(config.Bundles, config.ExtraPackages)
+	if len(names) == 0 {
+		return "No additional packages selected."
+	}
+	if p.db == nil {
+		return fmt.Sprintf("%d packages selected. Package database not available (%v), names are checked during the installation.", len(names), p.dbErr)
+	}
+	pkgs, missing := p.db.Resolve(names)
+	s := fmt.Sprintf("%d packages, about %s to download (dependencies not included).", len(pkgs), data.FormatSize(data.DownloadSize(pkgs)))
+	if len(missing) > 0 {
+		s += "\nNot in the package database: " + strings.Join(missing, " ")
+	}
+	return s
+}
+
+// packageSourceDir is the offline directory, "" when installing from the mirrors
+func packageSourceDir(config *state.InstallConfig) string {
+	if config.PackageSource != sourceOffline {
+		return ""
+	}
+	return config.OfflineRepo
+}
+
+// packageDB is the database packages are resolved against: an offline
+// repository's own, otherwise the live system's (also for a bare cache)
+func packageDB(config *state.InstallConfig) (*data.SyncDB, error) {
+	if dir := packageSourceDir(config); dir != "" {
+		r, err := data.OpenLocalRepo(dir)
+		if err != nil {
+			return nil, err
+		}
+		if r.DB != nil {
+			return r.DB, nil
+		}
+	}
+	return data.GetSyncDB()
+}
+
+// packagesEstimate is the summary line for the extra software download size
+func packagesEstimate(config *state.InstallConfig) string {
+	names := data.BundlePackages(config.Bundles, config.ExtraPackages)
+	db, err := packageDB(config)
+	if err != nil {
+		return "unknown"
+	}
+	pkgs, _ := db.Resolve(names)
+	return data.FormatSize(data.DownloadSize(pkgs))
+}
+
+func (p *PackagesPage) OnNext(config *state.InstallConfig) error {
+	if p.customErr != nil {
+		return p.customErr
+	}
+	if config.AURHelper != "none" && p.aurErr != nil {
+		return fmt.Errorf("AUR packages: %w", p.aurErr)
+	}
+	// Bundles may list multilib packages the live system has not synced, so
+	// only the user's own names are enforced
+	if p.db != nil {
+		if _, missing := p.db.Resolve(config.ExtraPackages); len(missing) > 0 {
+			return fmt.Errorf("packages not found: %s", strings.Join(missing, " "))
+		}
+	}
+	return nil
+}
+
+func NewPackagesPage() *PackagesPage {
+	return &PackagesPage{}
+}
@@ -0,0 +1,231 @@
+package pages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"archgui/gui/internal/command"
+	"archgui/gui/internal/data"
+	"archgui/gui/internal/mirrors"
+	"archgui/gui/internal/network"
+	"archgui/gui/internal/preflight"
+	"archgui/gui/internal/state"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+const preflightTimeout = 15 * time.Second
+
+type PreflightPage struct {
+	results []preflight.Result
+	running bool
+}
+
+func (p *PreflightPage) Title() string {
+	return "Preflight Checks"
+}
+
+func (p *PreflightPage) Content(config *state.InstallConfig, ctrl WizardController) fyne.CanvasObject {
+	statusLabel := widget.NewLabel("")
+	statusLabel.Wrapping = fyne.TextWrapWord
+	rows := container.NewVBox()
+
+	var runBtn *widget.Button
+	var run func()
+	show := func() {
+		rows.RemoveAll()
+		for _, r := range p.results {
+			rows.Add(p.resultRow(r, statusLabel, run))
+		}
+		switch {
+		case p.running:
+			statusLabel.SetText("Running checks...")
+		case preflight.Blocking(p.results):
+			statusLabel.SetText("Some checks failed. Fix them before installing.")
+		default:
+			statusLabel.SetText("Ready to install.")
+		}
+	}
+	run = func() {
+		p.running = true
+		runBtn.Disable()
+		show()
+		checks := preflightChecks(config, ctrl.Runner())
+		go func() {
+			results := preflight.Run(context.Background(), checks, preflightTimeout)
+			fyne.Do(func() {
+				p.results, p.running = results, false
+				runBtn.Enable()
+				show()
+			})
+		}()
+	}
+	runBtn = widget.NewButton("Run Checks Again", func() { run() })
+
+	// Settings may have changed since the last visit
+	if !p.running {
+		run()
+	} else {
+		runBtn.Disable()
+		show()
+	}
+
+	return container.NewVBox(
+		widget.NewLabelWithStyle("Preflight Checks", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabel("Problems found here would otherwise stop the installation halfway."),
+		statusLabel,
+		widget.NewSeparator(),
+		rows,
+		runBtn,
+	)
+}
+
+// resultRow shows one result with its hint and, if available, a fix button
+// that re-runs all checks afterwards
+func (p *PreflightPage) resultRow(r preflight.Result, statusLabel *widget.Label, rerun func()) fyne.CanvasObject {
+	status := widget.NewLabelWithStyle(r.Status.String(), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	switch r.Status {
+	case preflight.Fail:
+		status.Importance = widget.DangerImportance
+	case preflight.Warn:
+		status.Importance = widget.WarningImportance
+	default:
+		status.Importance = widget.SuccessImportance
+	}
+	msg := widget.NewLabel(r.Name + ": " + r.Message)
+	msg.Wrapping = fyne.TextWrapWord
+	row := container.NewVBox(container.NewBorder(nil, nil, status, nil, msg))
+	if r.Hint != "" {
+		hint := widget.NewLabel(r.Hint)
+		hint.Importance = widget.LowImportance
+		row.Add(hint)
+	}
+	if r.Fix != nil && r.Status != preflight.Pass {
+		var fixBtn *widget.Button
+		fixBtn = widget.NewButton("Fix", func() {
+			fixBtn.Disable()
+			statusLabel.SetText("Fixing " + r.Name + "...")
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+				defer cancel()
+				err := r.Fix(ctx)
+				fyne.Do(func() {
+					if err != nil {
+						statusLabel.SetText("Fix failed: " + err.Error())
+						fixBtn.Enable()
+						return
+					}
+					rerun()
+				})
+			}()
+		})
+		row.Add(container.NewHBox(fixBtn))
+	}
+	return row
+}
+
+// preflightChecks builds the checks for the current settings
+func preflightChecks(config *state.InstallConfig, r command.Runner) []preflight.Check {
+	offline := packageSourceDir(config) != ""
+
+	target := config.Disk
+	if config.ManualPartitioning {
+		target = config.TargetRoot
+	}
+	// DiskSize reads the whole disk, which includes the EFI partition
+	estimate := installEstimate(config)
+	minSize, recommended := estimate.Required(), estimate.Recommended()
+	if !config.ManualPartitioning && config.BootMode == data.BootUEFI {
+		minSize, recommended = minSize+espSize, recommended+espSize
+	}
+
+	required := []string{"pacstrap", "arch-chroot", "genfstab", "mkfs." + config.Filesystem}
+	if !config.ManualPartitioning {
+		required = append(required, "parted", "wipefs")
+	}
+	if config.BootMode == data.BootUEFI {
+		required = append(required, "mkfs.fat")
+	}
+	if config.Encrypt {
+		required = append(required, "cryptsetup")
+	}
+	if offline {
+		required = append(required, "repo-add")
+	}
+
+	sync, _ := data.GetSyncDB()
+	checks := []preflight.Check{
+		{Name: "Internet connection", Run: func(ctx context.Context) preflight.Result {
+			err := network.CheckConnectivity(ctx, network.ConnectivityURL)
+			switch {
+			case err == nil:
+				return preflight.Result{Status: preflight.Pass, Message: "Online."}
+			case offline:
+				return preflight.Result{Status: preflight.Pass, Message: "Offline install, no connection needed."}
+			}
+			return preflight.Result{Status: preflight.Fail, Message: err.Error(), Hint: "Connect on the Network page or choose an offline package source"}
+		}},
+		preflight.Clock(r, "https://archlinux.org", time.Minute, 10*time.Minute),
+		preflight.DiskSize("/sys/class/block", target, minSize, recommended),
+		preflight.Tools(r, required, []string{"xterm", "cfdisk"}),
+		preflight.Keyring(r, "/var/lib/pacman/local", sync),
+	}
+	if offline {
+		checks = append(checks, preflight.Check{Name: "Offline packages", Run: func(ctx context.Context) preflight.Result {
+			err := offlinePreflight(config)
+			switch {
+			case errors.Is(err, data.ErrNoPackageDB):
+				return preflight.Result{Status: preflight.Warn, Message: "Could not check the package cache: " + err.Error() + ".", Hint: "Sync the package databases once online, or use a repository made with repo-add"}
+			case err != nil:
+				return preflight.Result{Status: preflight.Fail, Message: err.Error(), Hint: "Add the packages to the repository or deselect them"}
+			}
+			return preflight.Result{Status: preflight.Pass, Message: "All selected packages are available."}
+		}})
+	}
+	if config.CacheServer != "" || config.CacheDir != "" {
+		server, dir := config.CacheServer, config.CacheDir
+		checks = append(checks, preflight.Check{Name: "Lab cache", Run: func(ctx context.Context) preflight.Result {
+			var problems []string
+			if server != "" {
+				if err := mirrors.Probe(ctx, server, preflightTimeout); err != nil {
+					problems = append(problems, "proxy: "+err.Error())
+				}
+			}
+			if dir != "" {
+				if err := cacheDirUsable(dir); err != nil {
+					problems = append(problems, "shared cache: "+err.Error())
+				}
+			}
+			if len(problems) > 0 {
+				return preflight.Result{Status: preflight.Warn, Message: strings.Join(problems, "; "), Hint: "The installation falls back to the mirrors"}
+			}
+			return preflight.Result{Status: preflight.Pass, Message: "Reachable."}
+		}})
+	}
+	return checks
+}
+
+func (p *PreflightPage) OnNext(config *state.InstallConfig) error {
+	if p.running {
+		return fmt.Errorf("the checks are still running")
+	}
+	if preflight.Blocking(p.results) {
+		var failed []string
+		for _, r := range p.results {
+			if r.Status == preflight.Fail {
+				failed = append(failed, r.Name)
+			}
+		}
+		return fmt.Errorf("preflight checks failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func NewPreflightPage() *PreflightPage {
+	return &PreflightPage{}
+}
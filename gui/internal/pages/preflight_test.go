@@ -0,0 +1,34 @@
+package pages
+
+import (
+	"slices"
+	"testing"
+
+	"archgui/gui/internal/command"
+	"archgui/gui/internal/state"
+)
+
+func TestPreflightChecksForSettings(t *testing.T) {
+	names := func(c *state.InstallConfig) []string {
+		var out []string
+		for _, check := range preflightChecks(c, &command.Fake{}) {
+			out = append(out, check.Name)
+		}
+		return out
+	}
+
+	config := state.NewInstallConfig()
+	base := names(config)
+	if slices.Contains(base, "Offline packages") || slices.Contains(base, "Lab cache") {
+		t.Errorf("optional checks without their settings: %v", base)
+	}
+
+	config.PackageSource, config.OfflineRepo = "offline", "/run/media/usb/repo"
+	config.CacheServer = "http://cache.lan:9129/repo/archlinux/$repo/os/$arch"
+	got := names(config)
+	for _, want := range []string{"Internet connection", "System clock", "Target size", "Offline packages", "Lab cache"} {
+		if !slices.Contains(got, want) {
+			t.Errorf("%s missing from %v", want, got)
+		}
+	}
+}
@@ -1,10 +1,11 @@
 package pages
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
 	"strings"
 
+	"archgui/gui/internal/command"
 	"archgui/gui/internal/data"
 	"archgui/gui/internal/state"
 
@@ -28,6 +29,10 @@ type StoragePage struct {
 	efiSelect     *widget.Select
 	formatEfi     *widget.Check
 
+	// Shared
+	swapSelect *widget.Select
+	sizeLabel  *widget.Label
+
 	// Logic
 	modeSelect *widget.RadioGroup
 
@@ -39,14 +44,53 @@ func (p *StoragePage) Title() string {
 }
 
 func (p *StoragePage) Content(config *state.InstallConfig, ctrl WizardController) fyne.CanvasObject {
+	// --- Size estimate (desktop and packages are picked later, the
+	// Summary repeats the check with the final selection) ---
+	p.sizeLabel = widget.NewLabel("Estimating the installation size...")
+	p.sizeLabel.Wrapping = fyne.TextWrapWord
+	var estimate *data.SizeEstimate
+	updateSize := func() {
+		if estimate != nil {
+			text, importance := sizeStatus(config, *estimate)
+			p.sizeLabel.SetText(text)
+			p.sizeLabel.Importance = importance
+			p.sizeLabel.Refresh()
+		}
+	}
+	go func() {
+		e := installEstimate(config)
+		fyne.Do(func() {
+			estimate = &e
+			updateSize()
+		})
+	}()
+
+	var swapOptions []string
+	for _, gib := range swapSizes {
+		swapOptions = append(swapOptions, swapLabel(gib))
+	}
+	p.swapSelect = widget.NewSelect(swapOptions, func(val string) {
+		for _, gib := range swapSizes {
+			if swapLabel(gib) == val {
+				config.SwapSize = gib
+			}
+		}
+		if estimate != nil {
+			estimate.Swap = int64(config.SwapSize) << 30
+		}
+		updateSize()
+	})
+	p.swapSelect.SetSelected(swapLabel(config.SwapSize))
+
 	// --- Auto Partitioning Widgets ---
-	p.diskSelect = widget.NewSelect(data.GetDisks(), func(val string) {
+	p.diskSelect = widget.NewSelect(data.GetDisks(ctrl.Runner()), func(val string) {
 		// Parse: /dev/sda (...)
 		if len(val) > 0 {
 			var name string
 			_, _ = fmt.Sscanf(val, "/dev/%s", &name)
 			config.Disk = "/dev/" + strings.TrimSpace(strings.Split(name, " ")[0]) // simple parse
 		}
+		updateSize()
 	})
 	if len(p.diskSelect.Options) > 0 {
 		p.diskSelect.SetSelected(p.diskSelect.Options[0])
@@ -71,9 +115,15 @@ func (p *StoragePage) Content(config *state.InstallConfig, ctrl WizardController
 	})
 	p.encCheck.Checked = config.Encrypt
 
+	layoutHint := "Layout: GPT with a 512 MiB EFI System Partition and root."
+	if config.BootMode == data.BootBIOS {
+		layoutHint = "Layout: MBR (msdos) with a single bootable root partition."
+	}
+
 	autoContent := container.NewVBox(
 		widget.NewLabelWithStyle("Automatic Partitioning", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		widget.NewLabel("Warning: Selected disk will be WIPED."),
+		widget.NewLabel(layoutHint),
 		widget.NewForm(
 			widget.NewFormItem("Target Disk", p.diskSelect),
 			widget.NewFormItem("Filesystem", p.fsSelect),
@@ -84,33 +134,51 @@ func (p *StoragePage) Content(config *state.InstallConfig, ctrl WizardController
 
 	// --- Manual Partitioning Widgets ---
 	p.openCfdiskBtn = widget.NewButton("Open Partition Manager (cfdisk)", func() {
-		// Launch cfdisk in xterm
-		cmd := exec.Command("xterm", "-e", "cfdisk")
-		if err := cmd.Start(); err != nil {
-			// Show error (ctrl needed? or log)
-			ctrl.ShowLog(fmt.Sprintf("Failed to launch cfdisk: %v", err))
-		}
+		// Launch cfdisk in xterm, the partition lists are refreshed when it closes
+		p.openCfdiskBtn.Disable()
+		go func() {
+			_, err := ctrl.Runner().Run(context.Background(), command.Cmd{Name: "xterm", Args: []string{"-e", "cfdisk"}})
+			fyne.Do(func() {
+				p.openCfdiskBtn.Enable()
+				if err != nil {
+					ctrl.ShowLog(fmt.Sprintf("Failed to launch cfdisk: %v", err))
+					return
+				}
+				p.refreshBtn.OnTapped()
+			})
+		}()
 	})
 
-	p.rootSelect = widget.NewSelect(data.GetPartitions(), func(val string) {
+	p.rootSelect = widget.NewSelect(data.GetPartitions(ctrl.Runner()), func(val string) {
 		config.TargetRoot = parseDevPath(val)
+		updateSize()
 	})
 	p.formatRoot = widget.NewCheck("Format Root?", func(b bool) { config.FormatRoot = b })
 	p.formatRoot.Checked = true
 
-	p.efiSelect = widget.NewSelect(data.GetPartitions(), func(val string) {
+	p.efiSelect = widget.NewSelect(data.GetPartitions(ctrl.Runner()), func(val string) {
 		config.TargetEFI = parseDevPath(val)
 	})
 	p.formatEfi = widget.NewCheck("Format EFI?", func(b bool) { config.FormatEFI = b })
 
 	p.refreshBtn = widget.NewButton("Refresh Partitions", func() {
-		parts := data.GetPartitions()
+		parts := data.GetPartitions(ctrl.Runner())
 		p.rootSelect.Options = parts
 		p.efiSelect.Options = parts
 		p.rootSelect.Refresh()
 		p.efiSelect.Refresh()
 	})
 
+	// EFI partition is only meaningful (and then required) for UEFI installs
+	efiForm := widget.NewForm(
+		widget.NewFormItem("EFI Partition (/boot)", p.efiSelect),
+		widget.NewFormItem("", p.formatEfi),
+	)
+	if config.BootMode == data.BootBIOS {
+		config.TargetEFI = ""
+		efiForm.Hide()
+	}
+
 	manualContent := container.NewVBox(
 		widget.NewLabelWithStyle("Manual Partitioning", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		widget.NewLabel("1. Use cfdisk to create partitions."),
@@ -120,9 +188,8 @@ func (p *StoragePage) Content(config *state.InstallConfig, ctrl WizardController
 		widget.NewForm(
 			widget.NewFormItem("Root Partition (/)", p.rootSelect),
 			widget.NewFormItem("", p.formatRoot),
-			widget.NewFormItem("EFI Partition (/boot)", p.efiSelect),
-			widget.NewFormItem("", p.formatEfi),
 		),
+		efiForm,
 	)
 
 	// --- Mode Switching ---
@@ -140,6 +207,7 @@ func (p *StoragePage) Content(config *state.InstallConfig, ctrl WizardController
 			autoContent.Hide()
 			manualContent.Show()
 		}
+		updateSize()
 	})
 	p.modeSelect.Horizontal = true
 	p.modeSelect.Selected = "Automatic"
@@ -149,9 +217,109 @@ func (p *StoragePage) Content(config *state.InstallConfig, ctrl WizardController
 		p.modeSelect,
 		widget.NewSeparator(),
 		p.contentContainer,
+		widget.NewSeparator(),
+		widget.NewForm(&widget.FormItem{Text: "Swapfile", Widget: p.swapSelect, HintText: "Created on the root filesystem"}),
+		p.sizeLabel,
 	)
 }
 
+// swapSizes are the swapfile choices in GiB, 0 for none
+var swapSizes = []int{0, 2, 4, 8, 16, 32}
+
+func swapLabel(gib int) string {
+	if gib == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d GiB", gib)
+}
+
+// espSize is the EFI System Partition automatic partitioning carves off
+const espSize = 513 << 20
+
+// installPackageNames lists what the backend installs from the repos, in
+// step with base_packages and all_packages in arch-install.sh
+func installPackageNames(config *state.InstallConfig) []string {
+	names := []string{"base", "base-devel", config.Kernel, "linux-firmware", "networkmanager", "grub", "sudo", "nano", "vim", "git", "btop"}
+	if config.Microcode != "" && config.Microcode != "none" {
+		names = append(names, config.Microcode)
+	}
+	if config.InstallBluetooth {
+		names = append(names, "bluez", "bluez-utils")
+	}
+	if config.PowerProfile != "" && config.PowerProfile != "none" {
+		names = append(names, config.PowerProfile)
+	}
+	if config.Filesystem == "btrfs" {
+		names = append(names, "btrfs-progs")
+	}
+	if config.BootMode == data.BootUEFI {
+		names = append(names, "efibootmgr")
+	}
+	names = append(names, planGPU(config).Packages...)
+	names = append(names, data.BundlePackages(config.Bundles, config.ExtraPackages)...)
+	names = append(names, data.GetDesktopCatalog().Packages(config.Desktop, config.DesktopVariant, config.DesktopExtras)...)
+	for _, u := range config.Users {
+		if strings.HasPrefix(u.Shell, "zsh") {
+			names = append(names, "zsh", "zsh-completions")
+			break
+		}
+	}
+	if data.UsesDoas(config.PrivilegeTool) {
+		names = append(names, "opendoas")
+	}
+	if config.EnableSSHD {
+		names = append(names, "openssh")
+	}
+	return names
+}
+
+// installEstimate is the space the current selection needs on the root filesystem
+func installEstimate(config *state.InstallConfig) data.SizeEstimate {
+	db, _ := packageDB(config)
+	return data.EstimateSize(db, installPackageNames(config), int64(config.SwapSize)<<30)
+}
+
+// targetSize is the space the root filesystem gets on the selected target
+func targetSize(config *state.InstallConfig) (int64, error) {
+	if config.ManualPartitioning {
+		return data.BlockDeviceSize(config.TargetRoot)
+	}
+	size, err := data.BlockDeviceSize(config.Disk)
+	if err == nil && config.BootMode == data.BootUEFI {
+		size -= espSize
+	}
+	return size, err
+}
+
+// checkInstallSize fails when the installation cannot finish on size bytes
+func checkInstallSize(e data.SizeEstimate, size int64) error {
+	if e.Required() > size {
+		return fmt.Errorf("the installation needs about %s but the target has %s: choose a larger target, a smaller swapfile or fewer packages",
+			data.FormatSize(e.Required()), data.FormatSize(size))
+	}
+	return nil
+}
+
+// sizeStatus compares the estimate with the selected target
+func sizeStatus(config *state.InstallConfig, e data.SizeEstimate) (string, widget.Importance) {
+	text := fmt.Sprintf("Estimated space needed: %s (packages %s, package cache %s, swap %s), %s with room for updates and data.",
+		data.FormatSize(e.Required()), data.FormatSize(e.Installed), data.FormatSize(e.Cache), data.FormatSize(e.Swap), data.FormatSize(e.Recommended()))
+	if !e.Exact {
+		text += " Package database not available, sizes are rough."
+	}
+	size, err := targetSize(config)
+	if err != nil {
+		return text, widget.MediumImportance
+	}
+	if err := checkInstallSize(e, size); err != nil {
+		return text + "\nThe target is too small: " + data.FormatSize(size) + " available.", widget.DangerImportance
+	}
+	if size < e.Recommended() {
+		return text + "\nThe target has only " + data.FormatSize(size) + ", little room is left after the installation.", widget.WarningImportance
+	}
+	return text, widget.MediumImportance
+}
+
 func parseDevPath(val string) string {
 	// "/dev/sda1 (10G)" -> "/dev/sda1"
 	if len(val) > 0 {
@@ -168,6 +336,14 @@ func (p *StoragePage) OnNext(config *state.InstallConfig) error {
 		if config.TargetRoot == "" {
 			return fmt.Errorf("please select a Root partition")
 		}
+		if config.BootMode == data.BootUEFI {
+			if config.TargetEFI == "" {
+				return fmt.Errorf("please select an EFI partition (required for UEFI boot)")
+			}
+			if config.TargetEFI == config.TargetRoot {
+				return fmt.Errorf("EFI and Root partitions must be different")
+			}
+		}
 	} else {
 		if config.Disk == "" {
 			return fmt.Errorf("please select a Target Disk")
@@ -176,6 +352,9 @@ func (p *StoragePage) OnNext(config *state.InstallConfig) error {
 			return fmt.Errorf("LUKS Password is required")
 		}
 	}
+	if size, err := targetSize(config); err == nil {
+		return checkInstallSize(installEstimate(config), size)
+	}
 	return nil
 }
 
@@ -0,0 +1,41 @@
+package pages
+
+import (
+	"slices"
+	"testing"
+
+	"archgui/gui/internal/data"
+	"archgui/gui/internal/state"
+)
+
+func TestInstallPackageNames(t *testing.T) {
+	config := state.NewInstallConfig()
+	config.Filesystem = "btrfs"
+	config.PrivilegeTool = data.PrivBoth
+	config.EnableSSHD = true
+	config.Users[0].Shell = "zsh"
+	config.Kernel = "linux-lts"
+
+	got := installPackageNames(config)
+	for _, want := range []string{"base", "linux-lts", "btrfs-progs", "efibootmgr", "opendoas", "openssh", "zsh"} {
+		if !slices.Contains(got, want) {
+			t.Errorf("%s missing from %v", want, got)
+		}
+	}
+
+	config.BootMode = data.BootBIOS
+	config.Filesystem = "ext4"
+	if got := installPackageNames(config); slices.Contains(got, "efibootmgr") || slices.Contains(got, "btrfs-progs") {
+		t.Errorf("unexpected packages for a BIOS ext4 install: %v", got)
+	}
+}
+
+func TestCheckInstallSize(t *testing.T) {
+	e := data.SizeEstimate{Installed: 6 << 30, Cache: 2 << 30, Swap: 8 << 30, Reserve: 2 << 30}
+	if err := checkInstallSize(e, 16<<30); err != nil {
+		t.Errorf("exact fit: %v", err)
+	}
+	if err := checkInstallSize(e, 12<<30); err == nil {
+		t.Error("expected an error for a too small target")
+	}
+}
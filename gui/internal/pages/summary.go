@@ -1,11 +1,19 @@
 package pages
 
 import (
+	"archgui/gui/internal/command"
+	"archgui/gui/internal/data"
+	"archgui/gui/internal/installer"
 	"archgui/gui/internal/state"
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 )
 
@@ -17,43 +25,349 @@ func (p *SummaryPage) Title() string {
 
 func (p *SummaryPage) Content(config *state.InstallConfig, ctrl WizardController) fyne.CanvasObject {
 	// Build summary text
-	summary := fmt.Sprintf(`Target Disk: %s
+	summary := fmt.Sprintf(`Boot Mode: %s
+Target Disk: %s
 Manual Partitioning: %v
 Filesystem: %s
 Encrypt: %v
+Swapfile: %s
+Disk Space: %s
 
+Package Source: %s
+Mirrors: %s
+Lab Cache: %s
 Hostname: %s
-User: %s (Full: %s)
-Shell: %s
+Wi-Fi Profile: %s
+Users:
+%sRoot: %s
+Admin Access: %s
+SSH: %s
 
 Timezone: %s
 Locale: %s
 Keymap: %s
+Keyboard: %s
 
 Desktop: %s
-Nvidia: %v
+Extra Software: %s
+AUR: %s
+Kernel: %s
+Graphics: %s
+
+Microcode: %s
+Bluetooth: %v
+Power Management: %s
 `,
-		config.Disk, config.ManualPartitioning, config.Filesystem, config.Encrypt,
-		config.Hostname, config.Username, config.FullName, config.Shell,
-		config.Timezone, config.Locale, config.Keymap,
-		config.Desktop, config.InstallNvidia)
+		bootModeLabel(config), config.Disk, config.ManualPartitioning, config.Filesystem, config.Encrypt, swapLabel(config.SwapSize), diskSpaceLabel(config),
+		packageSourceLabel(config), mirrorsLabel(config), labCacheLabel(config), config.Hostname, wifiLabel(config), usersLabel(config.Users), rootModeLabel(config), privilegeLabel(config), sshLabel(config),
+		config.Timezone, config.Locale, config.Keymap, keyboardLabel(config),
+		desktopLabel(config), extraSoftwareLabel(config), aurLabel(config), config.Kernel, graphicsLabel(config),
+		config.Microcode, config.InstallBluetooth, config.PowerProfile)
+
+	if len(config.ExtraLocales) > 0 {
+		summary += "\nAdditional Locales: " + strings.Join(config.ExtraLocales, ", ")
+	}
+	for _, cat := range data.LCCategories {
+		if v := config.LocaleOverrides[cat]; v != "" {
+			summary += fmt.Sprintf("\n%s: %s", cat, v)
+		}
+	}
 
 	if config.ManualPartitioning {
-		summary += fmt.Sprintf("\nManual Targets:\nRoot: %s (Format: %v)\nEFI: %s (Format: %v)",
-			config.TargetRoot, config.FormatRoot, config.TargetEFI, config.FormatEFI)
+		summary += fmt.Sprintf("\nManual Targets:\nRoot: %s (Format: %v)", config.TargetRoot, config.FormatRoot)
+		if config.BootMode == data.BootUEFI {
+			summary += fmt.Sprintf("\nEFI: %s (Format: %v)", config.TargetEFI, config.FormatEFI)
+		}
+	}
+
+	preflight := widget.NewLabel("")
+	preflight.Wrapping = fyne.TextWrapWord
+	if err := offlinePreflight(config); errors.Is(err, data.ErrNoPackageDB) {
+		preflight.SetText("Offline packages not checked: " + err.Error())
+		preflight.Importance = widget.WarningImportance
+	} else if err != nil {
+		preflight.SetText("Cannot install offline: " + err.Error())
+		preflight.Importance = widget.DangerImportance
+	} else {
+		preflight.Hide()
+	}
+
+	if size, err := targetSize(config); err == nil {
+		if err := checkInstallSize(installEstimate(config), size); err != nil {
+			preflight.SetText(strings.TrimSpace(preflight.Text + "\nNot enough space: " + err.Error()))
+			preflight.Importance = widget.DangerImportance
+			preflight.Show()
+		}
 	}
 
+	var simulateBtn *widget.Button
+	simulateBtn = widget.NewButton("Simulate", func() {
+		simulateBtn.Disable()
+		settings := installer.NewSettings(configVars(config))
+		var notes []string
+		engine := pickEngine(config, settings, ctrl.Runner(), func(line string) { notes = append(notes, line) })
+		go func() {
+			cmds, err := engine.Simulate(context.Background(), settings)
+			fyne.Do(func() {
+				simulateBtn.Enable()
+				showSimulation(ctrl.Window(), engine.Name(), notes, cmds, err)
+			})
+		}()
+	})
+
 	return container.NewVBox(
 		widget.NewLabelWithStyle("Ready to Install", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		widget.NewLabel("Please review your settings below."),
 		widget.NewSeparator(),
 		widget.NewLabel(summary),
+		preflight,
 		widget.NewSeparator(),
+		container.NewHBox(simulateBtn, widget.NewLabel("Lists every command the installation would run, without running any.")),
 		widget.NewLabel("Click 'Install' to begin. This operation cannot be undone."),
 	)
 }
 
+// showSimulation lists the simulated commands in a dialog
+func showSimulation(parent fyne.Window, engine string, notes []string, cmds []command.Cmd, err error) {
+	if err != nil && len(cmds) == 0 {
+		dialog.ShowError(fmt.Errorf("simulation failed: %w", err), parent)
+		return
+	}
+	header := strings.Join(append(notes, "Install engine: "+engine), "\n") + "\n" + simulationHeader(cmds)
+	if err != nil {
+		header += "\nThe simulation stopped early: " + err.Error()
+	}
+	list := widget.NewLabel(simulationText(cmds))
+	list.TextStyle = fyne.TextStyle{Monospace: true}
+	scroll := container.NewScroll(list)
+	scroll.SetMinSize(fyne.NewSize(900, 500))
+	d := dialog.NewCustom("Simulated Installation", "Close", container.NewBorder(widget.NewLabel(header), nil, nil, nil, scroll), parent)
+	d.Show()
+}
+
+func simulationHeader(cmds []command.Cmd) string {
+	destructive := 0
+	for _, c := range cmds {
+		if installer.Destructive(c) {
+			destructive++
+		}
+	}
+	return fmt.Sprintf("%d commands, %d of them (marked !) erase or rewrite disks. Passwords are passed on stdin (marked < stdin) and not shown.", len(cmds), destructive)
+}
+
+// simulationText is one command per line in execution order
+func simulationText(cmds []command.Cmd) string {
+	var b strings.Builder
+	for _, c := range cmds {
+		if installer.Destructive(c) {
+			b.WriteString("! ")
+		} else {
+			b.WriteString("  ")
+		}
+		b.WriteString(c.String())
+		if c.Stdin != "" {
+			b.WriteString(" < stdin")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func graphicsLabel(config *state.InstallConfig) string {
+	var parts []string
+	if config.GPUIntel {
+		parts = append(parts, "Intel")
+	}
+	if config.GPUAMD {
+		parts = append(parts, "AMD")
+	}
+	if config.NvidiaDriver != "" {
+		parts = append(parts, "NVIDIA ("+config.NvidiaDriver+")")
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	s := strings.Join(parts, " + ")
+	if needsMultilib(config) {
+		s += ", multilib"
+	}
+	return s
+}
+
+func keyboardLabel(config *state.InstallConfig) string {
+	s := config.XkbLayout
+	if config.XkbVariant != "" {
+		s += " (" + config.XkbVariant + ")"
+	}
+	if config.XkbOptions != "" {
+		s += ", options: " + config.XkbOptions
+	}
+	return s
+}
+
+// usersLabel lists one account per line
+func usersLabel(users []state.User) string {
+	var b strings.Builder
+	for _, u := range users {
+		fmt.Fprintf(&b, "  %s", u.Username)
+		if u.FullName != "" {
+			fmt.Fprintf(&b, " (%s)", u.FullName)
+		}
+		groups := u.Groups
+		if u.Admin {
+			groups = append([]string{"wheel"}, groups...)
+		}
+		fmt.Fprintf(&b, ", shell %s, groups %s", u.Shell, strings.Join(groups, ","))
+		if len(u.SSHKeys) > 0 {
+			fmt.Fprintf(&b, ", %d SSH key(s)", len(u.SSHKeys))
+		}
+		if u.Home != "" {
+			fmt.Fprintf(&b, ", home %s", u.Home)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func desktopLabel(config *state.InstallConfig) string {
+	d, ok := data.GetDesktopCatalog().Find(config.Desktop)
+	if !ok {
+		return "none"
+	}
+	label := d.Name
+	if v, ok := d.Variant(config.DesktopVariant); ok {
+		label += " " + strings.ToLower(v.Name)
+	}
+	var extras []string
+	for _, e := range d.Extras {
+		if slices.Contains(config.DesktopExtras, e.ID) {
+			extras = append(extras, e.Name)
+		}
+	}
+	if len(extras) > 0 {
+		label += " (+ " + strings.Join(extras, ", ") + ")"
+	}
+	return label
+}
+
+func extraSoftwareLabel(config *state.InstallConfig) string {
+	var parts []string
+	for _, b := range data.SelectedBundles(config.Bundles) {
+		parts = append(parts, b.Name)
+	}
+	if len(config.ExtraPackages) > 0 {
+		parts = append(parts, strings.Join(config.ExtraPackages, " "))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ") + " (about " + packagesEstimate(config) + " to download)"
+}
+
+func aurLabel(config *state.InstallConfig) string {
+	if config.AURHelper == "none" {
+		return "no helper"
+	}
+	if pkgs := aurPackages(config); pkgs != "" {
+		return config.AURHelper + ": " + pkgs
+	}
+	return config.AURHelper
+}
+
+func privilegeLabel(config *state.InstallConfig) string {
+	label := config.PrivilegeTool
+	if config.PrivilegeTool == data.PrivBoth {
+		label = "sudo and doas"
+	}
+	if config.AdminNoPassword {
+		label += ", no password"
+	}
+	return label
+}
+
+func sshLabel(config *state.InstallConfig) string {
+	switch {
+	case !config.EnableSSHD:
+		return "disabled"
+	case config.SSHDisablePasswords:
+		return "enabled, keys only"
+	}
+	return "enabled"
+}
+
+func packageSourceLabel(config *state.InstallConfig) string {
+	if dir := packageSourceDir(config); dir != "" {
+		return "offline (" + dir + ")"
+	}
+	return "online mirrors"
+}
+
+func mirrorsLabel(config *state.InstallConfig) string {
+	if len(config.Mirrors) == 0 {
+		return "live mirrorlist"
+	}
+	return fmt.Sprintf("%d ranked, fastest %s", len(config.Mirrors), config.Mirrors[0])
+}
+
+func labCacheLabel(config *state.InstallConfig) string {
+	var parts []string
+	if config.CacheServer != "" {
+		parts = append(parts, "proxy "+config.CacheServer)
+	}
+	if config.CacheDir != "" {
+		parts = append(parts, "shared cache "+config.CacheDir)
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ") + " (mirrors as fallback)"
+}
+
+// diskSpaceLabel compares the estimate for the final selection with the target
+func diskSpaceLabel(config *state.InstallConfig) string {
+	e := installEstimate(config)
+	label := "about " + data.FormatSize(e.Required()) + " needed"
+	if size, err := targetSize(config); err == nil {
+		label += ", " + data.FormatSize(size) + " available"
+		if size < e.Recommended() {
+			label += " (little room left)"
+		}
+	}
+	return label
+}
+
+func wifiLabel(config *state.InstallConfig) string {
+	if !config.CopyWifiProfile || config.WifiSSID == "" {
+		return "none"
+	}
+	return config.WifiSSID
+}
+
+func rootModeLabel(config *state.InstallConfig) string {
+	switch config.RootMode {
+	case "same":
+		return "same password as the first user"
+	case "locked":
+		return "locked"
+	}
+	return "own password"
+}
+
+func bootModeLabel(config *state.InstallConfig) string {
+	if config.BootMode == data.BootBIOS {
+		return "BIOS (Legacy)"
+	}
+	return fmt.Sprintf("UEFI (%d-bit)", config.UEFIBits)
+}
+
 func (p *SummaryPage) OnNext(config *state.InstallConfig) error {
+	// An unchecked cache is left to the backend's own check
+	if err := offlinePreflight(config); err != nil && !errors.Is(err, data.ErrNoPackageDB) {
+		return err
+	}
+	if size, err := targetSize(config); err == nil {
+		return checkInstallSize(installEstimate(config), size)
+	}
 	return nil
 }
 
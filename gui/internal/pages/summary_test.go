@@ -0,0 +1,26 @@
+package pages
+
+import (
+	"strings"
+	"testing"
+
+	"archgui/gui/internal/command"
+)
+
+func TestSimulationText(t *testing.T) {
+	cmds := []command.Cmd{
+		{Name: "wipefs", Args: []string{"-af", "/dev/sda"}},
+		{Name: "cryptsetup", Args: []string{"open", "/dev/sda2", "cryptroot", "-"}, Stdin: "secret"},
+	}
+	got := simulationText(cmds)
+	want := "! wipefs -af /dev/sda\n  cryptsetup open /dev/sda2 cryptroot - < stdin\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if strings.Contains(got, "secret") {
+		t.Error("stdin content leaked into the preview")
+	}
+	if h := simulationHeader(cmds); !strings.HasPrefix(h, "2 commands, 1 of them") {
+		t.Errorf("header %q", h)
+	}
+}
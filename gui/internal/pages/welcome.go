@@ -1,6 +1,7 @@
 package pages
 
 import (
+	"archgui/gui/internal/data"
 	"archgui/gui/internal/state"
 
 	"fyne.io/fyne/v2"
@@ -8,24 +9,78 @@ import (
 	"fyne.io/fyne/v2/widget"
 )
 
-type WelcomePage struct{}
+// Boot mode override choices
+const (
+	bootDetected = "Detected"
+	bootUEFI64   = "UEFI (64-bit)"
+	bootUEFI32   = "UEFI (32-bit)"
+	bootBIOS     = "BIOS (Legacy)"
+)
+
+type WelcomePage struct {
+	boot     data.BootInfo
+	probed   bool
+	override string
+}
 
 func (p *WelcomePage) Title() string {
 	return "Welcome"
 }
 
 func (p *WelcomePage) Content(config *state.InstallConfig, ctrl WizardController) fyne.CanvasObject {
+	if !p.probed {
+		p.probed = true
+		p.boot = data.GetBootInfo()
+		p.override = bootDetected
+		p.applyBootMode(config)
+	}
+
+	bootLabel := widget.NewLabel("Firmware: " + p.boot.String())
+
+	secureBootHint := widget.NewLabel("Secure Boot is enabled. The installed GRUB is not signed, disable Secure Boot before rebooting.")
+	secureBootHint.Wrapping = fyne.TextWrapWord
+	if !p.boot.SecureBoot {
+		secureBootHint.Hide()
+	}
+
+	bootSelect := widget.NewSelect([]string{bootDetected, bootUEFI64, bootUEFI32, bootBIOS}, func(s string) {
+		p.override = s
+		p.applyBootMode(config)
+	})
+	bootSelect.SetSelected(p.override)
+
 	return container.NewCenter(
 		container.NewVBox(
 			widget.NewLabelWithStyle("Welcome to Arch Linux Installer", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 			widget.NewLabel("This wizard will guide you through the installation process."),
 			widget.NewLabel("You can choose between Automatic and Manual partitioning."),
 			widget.NewLabel(""),
+			bootLabel,
+			secureBootHint,
+			widget.NewForm(
+				widget.NewFormItem("Install for", bootSelect),
+			),
+			widget.NewLabel("Override the boot mode only when preparing a disk for another machine."),
+			widget.NewLabel(""),
 			widget.NewLabel("Click 'Next' to begin."),
 		),
 	)
 }
 
+// applyBootMode stores the detected or overridden boot mode in the config
+func (p *WelcomePage) applyBootMode(config *state.InstallConfig) {
+	switch p.override {
+	case bootUEFI64:
+		config.BootMode, config.UEFIBits = data.BootUEFI, 64
+	case bootUEFI32:
+		config.BootMode, config.UEFIBits = data.BootUEFI, 32
+	case bootBIOS:
+		config.BootMode, config.UEFIBits = data.BootBIOS, 0
+	default:
+		config.BootMode, config.UEFIBits = p.boot.Mode, p.boot.FirmwareBits
+	}
+}
+
 func (p *WelcomePage) OnNext(config *state.InstallConfig) error {
 	return nil
 }
@@ -0,0 +1,221 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"archgui/gui/internal/command"
+	"archgui/gui/internal/data"
+)
+
+// runFix runs a remedy through r, the output explains failures
+func runFix(ctx context.Context, r command.Runner, name string, args ...string) error {
+	var out []string
+	_, err := r.Run(ctx, command.Cmd{Name: name, Args: args, Output: func(l string) { out = append(out, l) }})
+	if msg := strings.TrimSpace(strings.Join(out, "\n")); err != nil && msg != "" {
+		return fmt.Errorf("%w: %s", err, msg)
+	}
+	return err
+}
+
+// toolPackages maps commands to the package shipping them, for the fix
+var toolPackages = map[string]string{
+	"arch-chroot": "arch-install-scripts",
+	"genfstab":    "arch-install-scripts",
+	"pacstrap":    "arch-install-scripts",
+	"cfdisk":      "util-linux",
+	"cryptsetup":  "cryptsetup",
+	"mkfs.btrfs":  "btrfs-progs",
+	"mkfs.ext4":   "e2fsprogs",
+	"mkfs.fat":    "dosfstools",
+	"parted":      "parted",
+	"xterm":       "xterm",
+}
+
+// Tools fails when a required command is missing from PATH and warns for
+// optional ones. The fix installs the missing packages on the live system.
+func Tools(r command.Runner, required, optional []string) Check {
+	return Check{Name: "Live system tools", Run: func(ctx context.Context) Result {
+		missing := func(names []string) []string {
+			var out []string
+			for _, n := range names {
+				if _, err := exec.LookPath(n); err != nil {
+					out = append(out, n)
+				}
+			}
+			return out
+		}
+		req, opt := missing(required), missing(optional)
+		if len(req)+len(opt) == 0 {
+			return Result{Status: Pass, Message: "All needed commands are available."}
+		}
+		res := Result{Status: Warn, Message: "Missing: " + strings.Join(append(req, opt...), ", ")}
+		if len(req) > 0 {
+			res.Status = Fail
+		}
+		var pkgs []string
+		for _, n := range append(req, opt...) {
+			if p := toolPackages[n]; p != "" {
+				pkgs = append(pkgs, p)
+			}
+		}
+		if len(pkgs) > 0 {
+			res.Hint = "Install " + strings.Join(pkgs, " ") + " on the live system"
+			res.Fix = func(ctx context.Context) error {
+				return runFix(ctx, r, "pacman", append([]string{"-Sy", "--noconfirm", "--needed"}, pkgs...)...)
+			}
+		}
+		return res
+	}}
+}
+
+// Clock compares the system clock with the Date header of url. A clock far
+// off makes pacman reject signatures as not yet valid or expired.
+func Clock(r command.Runner, url string, warn, fail time.Duration) Check {
+	return Check{Name: "System clock", Run: func(ctx context.Context) Result {
+		enableNTP := func(ctx context.Context) error {
+			return runFix(ctx, r, "timedatectl", "set-ntp", "true")
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			return Result{Status: Warn, Message: err.Error()}
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return Result{Status: Warn, Message: "Cannot compare with a time server: " + err.Error(), Hint: "Enable NTP once online", Fix: enableNTP}
+		}
+		resp.Body.Close()
+		remote, err := http.ParseTime(resp.Header.Get("Date"))
+		if err != nil {
+			return Result{Status: Warn, Message: "No usable Date header from " + url}
+		}
+		skew := time.Since(remote).Round(time.Second)
+		if skew < 0 {
+			skew = -skew
+		}
+		switch {
+		case skew >= fail:
+			return Result{Status: Fail, Message: fmt.Sprintf("The clock is off by %s, package signatures would be rejected.", skew), Hint: "Enable NTP time synchronization", Fix: enableNTP}
+		case skew >= warn:
+			return Result{Status: Warn, Message: fmt.Sprintf("The clock is off by %s.", skew), Hint: "Enable NTP time synchronization", Fix: enableNTP}
+		}
+		return Result{Status: Pass, Message: "The clock is correct."}
+	}}
+}
+
+// DiskSize checks the size of a block device (disk or partition) in a
+// /sys/class/block style directory
+func DiskSize(sysBlock, dev string, min, recommended int64) Check {
+	return Check{Name: "Target size", Run: func(ctx context.Context) Result {
+		if dev == "" {
+			return Result{Status: Fail, Message: "No target selected.", Hint: "Choose a disk on the Storage page"}
+		}
+		size, err := data.ReadBlockDeviceSize(sysBlock, dev)
+		if err != nil {
+			return Result{Status: Fail, Message: err.Error() + ".", Hint: "Choose a disk on the Storage page"}
+		}
+		switch {
+		case size < min:
+			return Result{Status: Fail, Message: fmt.Sprintf("%s has %s, at least %s are needed.", dev, data.FormatSize(size), data.FormatSize(min)), Hint: "Choose a larger disk or fewer packages"}
+		case size < recommended:
+			return Result{Status: Warn, Message: fmt.Sprintf("%s has %s, %s are recommended for this selection.", dev, data.FormatSize(size), data.FormatSize(recommended))}
+		}
+		return Result{Status: Pass, Message: fmt.Sprintf("%s has %s.", dev, data.FormatSize(size))}
+	}}
+}
+
+// Keyring warns when the installed archlinux-keyring is older than the one
+// in the sync database: new packager keys would be unknown to pacstrap
+func Keyring(r command.Runner, localDB string, sync *data.SyncDB) Check {
+	return Check{Name: "Arch Linux keyring", Run: func(ctx context.Context) Result {
+		update := func(ctx context.Context) error {
+			return runFix(ctx, r, "pacman", "-Sy", "--noconfirm", "archlinux-keyring")
+		}
+		installed := localVersion(localDB, "archlinux-keyring")
+		if installed == "" {
+			return Result{Status: Warn, Message: "archlinux-keyring is not installed on the live system.", Hint: "Install the keyring on the live system", Fix: update}
+		}
+		// A fresh ISO has no synced databases, so an old keyring looks current
+		if sync == nil {
+			return Result{Status: Warn, Message: "Installed " + installed + ", the package databases are not synced to compare.", Hint: "Update the keyring on the live system", Fix: update}
+		}
+		latest := sync.Packages["archlinux-keyring"].Version
+		if latest == "" {
+			return Result{Status: Pass, Message: "Installed " + installed + ", no newer version known."}
+		}
+		if vercmp(installed, latest) >= 0 {
+			return Result{Status: Pass, Message: "Up to date (" + installed + ")."}
+		}
+		return Result{
+			Status:  Warn,
+			Message: fmt.Sprintf("Installed %s, %s is available. Signatures by newer packagers would fail.", installed, latest),
+			Hint:    "Update the keyring on the live system",
+			Fix:     update,
+		}
+	}}
+}
+
+// localVersion reads %VERSION% of an installed package from pacman's local
+// database (<dir>/<name>-<version>/desc)
+func localVersion(dir, name string) string {
+	matches, _ := filepath.Glob(filepath.Join(dir, name+"-*", "desc"))
+	for _, m := range matches {
+		raw, err := os.ReadFile(m)
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(string(raw), "\n")
+		var gotName, version string
+		for i := 0; i+1 < len(lines); i++ {
+			switch lines[i] {
+			case "%NAME%":
+				gotName = lines[i+1]
+			case "%VERSION%":
+				version = lines[i+1]
+			}
+		}
+		// archlinux-keyring-* also matches longer names
+		if gotName == name {
+			return version
+		}
+	}
+	return ""
+}
+
+// vercmp orders versions by their numeric and alphabetic segments, enough
+// for pkgver-pkgrel strings like 20250123-1
+func vercmp(a, b string) int {
+	split := func(s string) []string {
+		return strings.FieldsFunc(s, func(r rune) bool {
+			return !('0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z')
+		})
+	}
+	as, bs := split(a), split(b)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aerr := strconv.ParseInt(as[i], 10, 64)
+		bn, berr := strconv.ParseInt(bs[i], 10, 64)
+		switch {
+		case aerr == nil && berr == nil && an != bn:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case (aerr != nil || berr != nil) && as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,189 @@
+package preflight
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"archgui/gui/internal/command"
+	"archgui/gui/internal/data"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClock(t *testing.T) {
+	offset := time.Duration(0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(offset).UTC().Format(http.TimeFormat))
+	}))
+	defer srv.Close()
+
+	r := &command.Fake{}
+	check := Clock(r, srv.URL, time.Minute, 10*time.Minute)
+	for _, tt := range []struct {
+		offset time.Duration
+		want   Status
+	}{
+		{0, Pass},
+		{-3 * time.Minute, Warn},
+		{2 * time.Hour, Fail},
+	} {
+		offset = tt.offset
+		res := check.Run(context.Background())
+		if res.Status != tt.want {
+			t.Errorf("offset %s: got %s (%s), want %s", tt.offset, res.Status, res.Message, tt.want)
+		}
+		if tt.want != Pass && res.Fix == nil {
+			t.Errorf("offset %s: no NTP fix offered", tt.offset)
+		}
+	}
+	if res := check.Run(context.Background()); res.Fix == nil || res.Fix(context.Background()) != nil {
+		t.Fatal("NTP fix failed")
+	}
+	if got := r.Lines(); !slices.Equal(got, []string{"timedatectl set-ntp true"}) {
+		t.Errorf("NTP fix ran %q", got)
+	}
+
+	srv.Close()
+	if res := check.Run(context.Background()); res.Status != Warn {
+		t.Errorf("unreachable time server: got %s", res.Status)
+	}
+}
+
+func TestDiskSize(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "sda", "size"), "41943040\n") // 20 GiB
+	const gib = 1 << 30
+
+	tests := []struct {
+		dev              string
+		min, recommended int64
+		want             Status
+	}{
+		{"/dev/sda", 8 * gib, 16 * gib, Pass},
+		{"/dev/sda", 8 * gib, 32 * gib, Warn},
+		{"/dev/sda", 30 * gib, 32 * gib, Fail},
+		{"/dev/sdz", 8 * gib, 16 * gib, Fail},
+		{"", 8 * gib, 16 * gib, Fail},
+	}
+	for _, tt := range tests {
+		r := DiskSize(dir, tt.dev, tt.min, tt.recommended).Run(context.Background())
+		if r.Status != tt.want {
+			t.Errorf("%q min %d: got %s (%s), want %s", tt.dev, tt.min/gib, r.Status, r.Message, tt.want)
+		}
+	}
+}
+
+func TestTools(t *testing.T) {
+	bin := t.TempDir()
+	writeFile(t, filepath.Join(bin, "parted"), "#!/bin/sh\n")
+	os.Chmod(filepath.Join(bin, "parted"), 0o755)
+	t.Setenv("PATH", bin)
+	r := &command.Fake{Replies: []command.Reply{{Prefix: "pacman -Sy --noconfirm --needed arch-install-scripts", Err: errors.New("pacman: exit status 1")}}}
+
+	if res := Tools(r, []string{"parted"}, nil).Run(context.Background()); res.Status != Pass {
+		t.Errorf("present tool: got %s (%s)", res.Status, res.Message)
+	}
+	res := Tools(r, []string{"parted"}, []string{"xterm"}).Run(context.Background())
+	if res.Status != Warn || res.Fix == nil {
+		t.Fatalf("missing optional tool: got %s, fix %v", res.Status, res.Fix != nil)
+	}
+	if err := res.Fix(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if got := r.Lines(); !slices.Equal(got, []string{"pacman -Sy --noconfirm --needed xterm"}) {
+		t.Errorf("fix ran %q", got)
+	}
+	res = Tools(r, []string{"pacstrap"}, nil).Run(context.Background())
+	if res.Status != Fail || res.Hint != "Install arch-install-scripts on the live system" {
+		t.Errorf("missing required tool: got %s, hint %q", res.Status, res.Hint)
+	}
+	if res.Fix == nil || res.Fix(context.Background()) == nil {
+		t.Error("a failing pacman should fail the fix")
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	local := t.TempDir()
+	writeFile(t, filepath.Join(local, "archlinux-keyring-20240520-1", "desc"), "%NAME%\narchlinux-keyring\n\n%VERSION%\n20240520-1\n\n")
+	writeFile(t, filepath.Join(local, "archlinux-keyring-extra-1-1", "desc"), "%NAME%\narchlinux-keyring-extra\n\n%VERSION%\n99999999-1\n\n")
+
+	syncDir := t.TempDir()
+	writeSyncDB(t, filepath.Join(syncDir, "core.db"), "archlinux-keyring-20250123-1", "%NAME%\narchlinux-keyring\n\n%VERSION%\n20250123-1\n\n")
+	sync, err := data.LoadSyncDB(syncDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &command.Fake{}
+	res := Keyring(r, local, sync).Run(context.Background())
+	if res.Status != Warn || res.Fix == nil {
+		t.Fatalf("outdated keyring: got %s (%s)", res.Status, res.Message)
+	}
+	if err := res.Fix(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if got := r.Lines(); !slices.Equal(got, []string{"pacman -Sy --noconfirm archlinux-keyring"}) {
+		t.Errorf("fix ran %q", got)
+	}
+	// Fresh ISO: nothing synced yet, so the age is unknown
+	if res := Keyring(r, local, nil).Run(context.Background()); res.Status != Warn || res.Fix == nil {
+		t.Errorf("without a sync database: got %s", res.Status)
+	}
+	if res := Keyring(r, local, &data.SyncDB{Packages: map[string]data.SyncPackage{}}).Run(context.Background()); res.Status != Pass {
+		t.Errorf("keyring missing from the sync database: got %s", res.Status)
+	}
+	if res := Keyring(r, t.TempDir(), sync).Run(context.Background()); res.Status != Warn {
+		t.Errorf("keyring not installed: got %s", res.Status)
+	}
+}
+
+func TestVercmp(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"20250123-1", "20250123-1", 0},
+		{"20240520-1", "20250123-1", -1},
+		{"20250123-2", "20250123-1", 1},
+		{"1.10-1", "1.9-1", 1},
+		{"1.0", "1.0.1", -1},
+	}
+	for _, tt := range tests {
+		if got := vercmp(tt.a, tt.b); got != tt.want {
+			t.Errorf("vercmp(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// writeSyncDB creates a one-package repo database
+func writeSyncDB(t *testing.T, path, dir, desc string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: dir + "/desc", Mode: 0o644, Size: int64(len(desc))})
+	tw.Write([]byte(desc))
+	tw.Close()
+	gz.Close()
+}
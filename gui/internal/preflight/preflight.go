@@ -0,0 +1,93 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Status of a check, ordered by severity
+type Status int
+
+const (
+	Pass Status = iota
+	Warn        // the install can continue, something may go wrong
+	Fail        // the install would fail, Install stays blocked
+)
+
+func (s Status) String() string {
+	switch s {
+	case Pass:
+		return "OK"
+	case Warn:
+		return "Warning"
+	}
+	return "Failed"
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name    string
+	Status  Status
+	Message string
+	Hint    string                          // what the user can do about it
+	Fix     func(ctx context.Context) error // automatic remedy, nil when there is none
+}
+
+// Check produces a Result. It should honour ctx, Run gives up on it when
+// the context ends.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) Result
+}
+
+// Run executes all checks concurrently, each limited to timeout. Results are
+// in the order of checks. A check that panics or overruns fails.
+func Run(ctx context.Context, checks []Check, timeout time.Duration) []Result {
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runOne(ctx, c, timeout)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func runOne(ctx context.Context, c Check, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan Result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- Result{Status: Fail, Message: fmt.Sprintf("check crashed: %v", r)}
+			}
+		}()
+		done <- c.Run(ctx)
+	}()
+
+	var r Result
+	select {
+	case r = <-done:
+	case <-ctx.Done():
+		r = Result{Status: Fail, Message: "did not finish in time", Hint: "Run the checks again"}
+	}
+	r.Name = c.Name
+	return r
+}
+
+// Blocking reports whether any result is a hard failure
+func Blocking(results []Result) bool {
+	for _, r := range results {
+		if r.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
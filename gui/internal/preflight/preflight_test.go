@@ -0,0 +1,44 @@
+package preflight
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	sleep := func(d time.Duration, s Status) func(ctx context.Context) Result {
+		return func(ctx context.Context) Result {
+			select {
+			case <-time.After(d):
+			case <-ctx.Done():
+			}
+			return Result{Status: s}
+		}
+	}
+	checks := []Check{
+		{Name: "slow", Run: sleep(100*time.Millisecond, Pass)},
+		{Name: "warn", Run: sleep(100*time.Millisecond, Warn)},
+		{Name: "stuck", Run: func(context.Context) Result { select {} }},
+		{Name: "panics", Run: func(context.Context) Result { panic("boom") }},
+	}
+
+	start := time.Now()
+	results := Run(context.Background(), checks, 300*time.Millisecond)
+	// Concurrent: the two sleeps overlap and the stuck check is cut off
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("checks ran serially or the timeout was ignored: %s", elapsed)
+	}
+	want := []Status{Pass, Warn, Fail, Fail}
+	for i, r := range results {
+		if r.Name != checks[i].Name || r.Status != want[i] {
+			t.Errorf("result %d: got %s/%s, want %s/%s", i, r.Name, r.Status, checks[i].Name, want[i])
+		}
+	}
+	if !Blocking(results) {
+		t.Error("failures should block")
+	}
+	if Blocking(results[:2]) {
+		t.Error("warnings should not block")
+	}
+}
@@ -0,0 +1,36 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// DefaultProfilePath is read at startup when no -profile flag is given
+const DefaultProfilePath = "/etc/archgui/profile.json"
+
+// LoadProfile returns the defaults overlaid with a JSON profile, so lab
+// installs can preset any setting. Keys are the InstallConfig field names:
+//
+//	{"CacheServer": "http://cache.lan:9129/repo/archlinux/$repo/os/$arch", "Timezone": "Europe/Oslo"}
+//
+// A missing file is not an error when missingOK is set.
+func LoadProfile(path string, missingOK bool) (*InstallConfig, error) {
+	c := NewInstallConfig()
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && missingOK {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", path, err)
+	}
+	return c, nil
+}
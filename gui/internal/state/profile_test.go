@@ -0,0 +1,40 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadProfile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "profile.json")
+	if err := os.WriteFile(path, []byte(`{"CacheServer": "http://cache.lan:9129/repo/archlinux/$repo/os/$arch", "Timezone": "Europe/Oslo"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadProfile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.CacheServer != "http://cache.lan:9129/repo/archlinux/$repo/os/$arch" || c.Timezone != "Europe/Oslo" {
+		t.Errorf("profile not applied: %q %q", c.CacheServer, c.Timezone)
+	}
+	// Settings the profile does not mention keep their defaults
+	if c.Kernel != "linux" || len(c.Users) != 1 {
+		t.Errorf("defaults lost: kernel %q, %d users", c.Kernel, len(c.Users))
+	}
+
+	if _, err := LoadProfile(filepath.Join(dir, "absent.json"), true); err != nil {
+		t.Errorf("missing optional profile: %v", err)
+	}
+	if _, err := LoadProfile(filepath.Join(dir, "absent.json"), false); err == nil {
+		t.Error("missing explicit profile should fail")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"CachServer": "typo"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadProfile(path, false); err == nil {
+		t.Error("unknown keys should be rejected")
+	}
+}
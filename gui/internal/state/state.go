@@ -2,6 +2,30 @@ package state
 
 // InstallConfig holds the configuration for the installation
 type InstallConfig struct {
+	// Boot (detected on the Welcome page, can be overridden)
+	BootMode string // uefi, bios
+	UEFIBits int    // 64, 32 (UEFI only)
+
+	// Hardware (pre-selected from detection on the Hardware page)
+	Microcode        string // intel-ucode, amd-ucode, none
+	InstallBluetooth bool
+	PowerProfile     string // none, tlp, power-profiles-daemon
+
+	// Network: the Wi-Fi joined on the Network page, if any
+	WifiSSID        string
+	WifiSecurity    string // open, psk (network.Security*)
+	WifiPassphrase  string
+	CopyWifiProfile bool // write a NetworkManager profile to the installed system
+
+	// Package source
+	PackageSource string   // online, offline
+	OfflineRepo   string   // local repository or package cache directory (offline)
+	Mirrors       []string // Server values in order, empty keeps the live mirrorlist
+
+	// Lab cache, usually preset by a profile; unreachable ones fall back to the mirrors
+	CacheServer string // caching proxy Server value (pacoloco, nginx), tried before the mirrors
+	CacheDir    string // shared package cache directory, e.g. an NFS mount
+
 	// Storage
 	Disk               string
 	ManualPartitioning bool
@@ -9,6 +33,7 @@ type InstallConfig struct {
 	TargetEFI          string // For manual (UEFI)
 	FormatRoot         bool
 	FormatEFI          bool
+	SwapSize           int // swapfile in GiB, 0 for none
 
 	// Encryption
 	Encrypt      bool
@@ -17,37 +42,110 @@ type InstallConfig struct {
 	// Filesystem
 	Filesystem string // ext4, btrfs
 
-	// Account
+	// Accounts
 	Hostname     string
-	FullName     string
-	Username     string
+	Users        []User // the first entry is the primary account
 	RootPassword string
-	UserPassword string
+	RootMode     string // password, same (use the primary user's password), locked
+
+	// Privilege escalation for administrators (wheel)
+	PrivilegeTool   string // sudo, doas, both
+	AdminNoPassword bool   // NOPASSWD / nopass
+
+	// SSH (keys are per user)
+	EnableSSHD          bool
+	SSHDisablePasswords bool // key-only login
 
 	// Localization
-	Timezone string
-	Locale   string
-	Keymap   string
+	Timezone        string
+	Locale          string            // LANG, e.g. en_US.UTF-8
+	ExtraLocales    []string          // generated in addition to LANG
+	LocaleOverrides map[string]string // LC_* category -> locale
+	Keymap          string            // console keymap (vconsole.conf)
+	XkbLayout       string            // X11 keyboard (00-keyboard.conf)
+	XkbVariant      string
+	XkbModel        string
+	XkbOptions      string // comma separated, e.g. caps:escape,grp:alt_shift_toggle
+
+	// Desktop (ids from data.GetDesktopCatalog)
+	Desktop        string   // xfce, gnome, etc., none
+	DesktopVariant string   // minimal, full
+	DesktopExtras  []string // ids of the desktop's optional package sets
+
+	// Additional software
+	Bundles       []string // ids from data.GetBundles
+	ExtraPackages []string // free-form package or group names
+	AURHelper     string   // none, yay, paru
+	AURPackages   []string // built with AURHelper as the first user
 
-	// Desktop
-	Desktop       string // xfce, gnome, etc.
-	Graphics      string // nvidia, etc. (bool in backend, but keeping flexible)
-	InstallNvidia bool
+	// Kernel & Graphics (drivers pre-selected from detected PCI IDs)
+	Kernel       string // linux, linux-lts, linux-zen, linux-hardened
+	GPUIntel     bool
+	GPUAMD       bool
+	NvidiaDriver string // "" (no NVIDIA GPU), nouveau, nvidia-open, nvidia-dkms, ...
+	Multilib     bool   // enable [multilib] and install lib32 variants
 
-	// Shell
-	Shell string
+	// Install engine
+	Engine string // native, script (backend/arch-install.sh)
+}
+
+// Install engines
+const (
+	EngineNative = "native"
+	EngineScript = "script"
+)
+
+// User is one account created on the installed system
+type User struct {
+	Username string
+	FullName string
+	Password string
+	Admin    bool     // member of wheel, may use sudo
+	Groups   []string // supplementary groups besides wheel
+	Shell    string   // bash, zsh, zsh-ohmyzsh
+	Home     string   // "" for /home/<username>
+	SSHKeys  []string // authorized_keys lines, checked by validation.ParseAuthorizedKeys
+}
+
+// NewUser returns an account with the defaults the installer always used
+func NewUser(username string) User {
+	return User{
+		Username: username,
+		Admin:    true,
+		Groups:   []string{"audio", "video", "storage"},
+		Shell:    "bash",
+	}
+}
+
+// PrimaryUser returns the first account, or nil when there is none
+func (c *InstallConfig) PrimaryUser() *User {
+	if len(c.Users) == 0 {
+		return nil
+	}
+	return &c.Users[0]
 }
 
 func NewInstallConfig() *InstallConfig {
 	return &InstallConfig{
-		Hostname:   "archlinux",
-		Username:   "user",
-		Filesystem: "ext4",
-		Desktop:    "xfce",
-		Shell:      "bash",
-		Timezone:   "UTC",
-		Locale:     "en_US",
-		Keymap:     "us",
-		FormatRoot: true, // Default to format even in manual unless unchecked
+		BootMode:       "uefi",
+		UEFIBits:       64,
+		PowerProfile:   "none",
+		PackageSource:  "online",
+		Hostname:       "archlinux",
+		Users:          []User{NewUser("user")},
+		RootMode:       "password",
+		PrivilegeTool:  "sudo",
+		Filesystem:     "ext4",
+		Desktop:        "xfce",
+		DesktopVariant: "full",
+		AURHelper:      "none",
+		Kernel:         "linux",
+		Timezone:       "UTC",
+		Locale:         "en_US.UTF-8",
+		Keymap:         "us",
+		XkbLayout:      "us",
+		XkbModel:       "pc105",
+		FormatRoot:     true, // Default to format even in manual unless unchecked
+		Engine:         EngineNative,
 	}
 }
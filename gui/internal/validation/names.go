@@ -0,0 +1,94 @@
+package validation
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Same pattern useradd accepts by default (shadow-utils NAME_REGEX)
+var usernameRe = regexp.MustCompile(`^[a-z_][a-z0-9_-]*[$]?$`)
+
+// One RFC 1123 hostname label: letters, digits and inner hyphens
+var hostLabelRe = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?$`)
+
+const (
+	maxUsernameLen = 32
+	maxHostnameLen = 253
+	maxLabelLen    = 63
+)
+
+// System accounts that exist on a fresh Arch install (filesystem and systemd
+// packages) plus names that would shadow them
+var reservedUsernames = map[string]bool{
+	"root": true, "bin": true, "daemon": true, "sys": true, "adm": true,
+	"mail": true, "ftp": true, "http": true, "nobody": true, "dbus": true,
+	"polkitd": true, "uuidd": true, "git": true, "avahi": true,
+	"colord": true, "rtkit": true, "sddm": true, "gdm": true, "lightdm": true,
+	"wheel": true, "users": true, "tty": true, "disk": true, "audio": true,
+	"video": true, "input": true, "kvm": true, "render": true, "utmp": true,
+}
+
+// Username checks a login name against the rules useradd enforces on Arch
+func Username(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("username is required")
+	case len(name) > maxUsernameLen:
+		return fmt.Errorf("username must be at most %d characters", maxUsernameLen)
+	case strings.ToLower(name) != name:
+		return fmt.Errorf("username must be lower case")
+	case strings.ContainsAny(name, " \t"):
+		return fmt.Errorf("username must not contain spaces")
+	case !usernameRe.MatchString(name):
+		return fmt.Errorf("username must start with a letter or _ and contain only a-z, 0-9, _ and -")
+	case reservedUsernames[name] || strings.HasPrefix(name, "systemd-"):
+		return fmt.Errorf("%q is reserved for a system account", name)
+	}
+	return nil
+}
+
+// Hostname checks a host name against RFC 1123. A trailing dot is not allowed
+// since /etc/hostname holds a plain name, not an FQDN in DNS notation.
+func Hostname(name string) error {
+	if name == "" {
+		return fmt.Errorf("hostname is required")
+	}
+	if len(name) > maxHostnameLen {
+		return fmt.Errorf("hostname must be at most %d characters", maxHostnameLen)
+	}
+	for _, label := range strings.Split(name, ".") {
+		switch {
+		case label == "":
+			return fmt.Errorf("hostname must not contain empty parts")
+		case len(label) > maxLabelLen:
+			return fmt.Errorf("each part of the hostname must be at most %d characters", maxLabelLen)
+		case strings.ContainsAny(label, " \t"):
+			return fmt.Errorf("hostname must not contain spaces")
+		case !hostLabelRe.MatchString(label):
+			return fmt.Errorf("hostname may only contain letters, digits and hyphens, and must not start or end with a hyphen")
+		}
+	}
+	return nil
+}
+
+// HomeDir checks a custom home directory. Empty means the useradd default.
+func HomeDir(path string) error {
+	switch {
+	case path == "":
+		return nil
+	case !strings.HasPrefix(path, "/"):
+		return fmt.Errorf("home directory must be an absolute path")
+	case path == "/" || filepath.Clean(path) != path:
+		return fmt.Errorf("home directory must be a clean path below /")
+	case strings.ContainsAny(path, ": \t\n"):
+		return fmt.Errorf("home directory must not contain spaces or colons")
+	}
+	for _, sys := range []string{"/bin", "/boot", "/dev", "/etc", "/proc", "/run", "/sys", "/usr"} {
+		if path == sys || strings.HasPrefix(path, sys+"/") {
+			return fmt.Errorf("home directory must not be inside %s", sys)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUsername(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"alice", true},
+		{"_build", true},
+		{"bob-smith", true},
+		{"user_01", true},
+		{"samba$", true},
+		{"", false},
+		{"Alice", false},
+		{"alice smith", false},
+		{"1alice", false},
+		{"-alice", false},
+		{"al$ice", false},
+		{"alice.smith", false},
+		{"root", false},
+		{"nobody", false},
+		{"systemd-network", false},
+		{strings.Repeat("a", 32), true},
+		{strings.Repeat("a", 33), false},
+	}
+	for _, tt := range tests {
+		if err := Username(tt.name); (err == nil) != tt.ok {
+			t.Errorf("Username(%q) = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestHostname(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"archlinux", true},
+		{"my-laptop", true},
+		{"Desktop01", true},
+		{"host.example.com", true},
+		{"1host", true},
+		{"", false},
+		{"My Laptop", false},
+		{"-laptop", false},
+		{"laptop-", false},
+		{"lap_top", false},
+		{"host..example", false},
+		{"host.", false},
+		{strings.Repeat("a", 63), true},
+		{strings.Repeat("a", 64), false},
+		{strings.Repeat("a.", 127) + "a", false},
+	}
+	for _, tt := range tests {
+		if err := Hostname(tt.name); (err == nil) != tt.ok {
+			t.Errorf("Hostname(%q) = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestHomeDir(t *testing.T) {
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"", true},
+		{"/home/alice", true},
+		{"/srv/users/bob", true},
+		{"home/alice", false},
+		{"/", false},
+		{"/home/alice/", false},
+		{"/home/../etc", false},
+		{"/home/alice smith", false},
+		{"/home/a:b", false},
+		{"/etc/alice", false},
+		{"/usr", false},
+		{"/usrdata/alice", true},
+	}
+	for _, tt := range tests {
+		if err := HomeDir(tt.path); (err == nil) != tt.ok {
+			t.Errorf("HomeDir(%q) = %v, want ok=%v", tt.path, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Characters makepkg allows in pkgname; names may not start with - or .
+var packageNameRe = regexp.MustCompile(`^[a-z0-9@_+][a-z0-9@._+-]*$`)
+
+// ParsePackageList splits a free-form list on whitespace and commas and
+// checks every name. Duplicates are dropped, the order is kept.
+func ParsePackageList(text string) ([]string, error) {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	var names []string
+	seen := map[string]bool{}
+	for _, f := range fields {
+		if !packageNameRe.MatchString(f) {
+			return nil, fmt.Errorf("%q is not a valid package name", f)
+		}
+		if !seen[f] {
+			seen[f] = true
+			names = append(names, f)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,26 @@
+package validation
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParsePackageList(t *testing.T) {
+	got, err := ParsePackageList("htop, neovim\n  ripgrep\tlib32-mesa htop gtk2+ python3.12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"htop", "neovim", "ripgrep", "lib32-mesa", "gtk2+", "python3.12"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"Firefox", "-rf", ".hidden", "foo;rm", "a/b", "$(x)"} {
+		if _, err := ParsePackageList("htop " + bad); err == nil {
+			t.Errorf("%q should be rejected", bad)
+		}
+	}
+	if got, err := ParsePackageList("  \n"); err != nil || len(got) != 0 {
+		t.Errorf("empty input: %v %v", got, err)
+	}
+}
@@ -0,0 +1,148 @@
+package validation
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Most used passwords from public breach corpora, one per line (lower case)
+//
+//go:embed common-passwords.txt
+var commonPasswordsRaw string
+
+var (
+	commonOnce      sync.Once
+	commonPasswords map[string]bool
+)
+
+func isCommonPassword(pw string) bool {
+	commonOnce.Do(func() {
+		commonPasswords = map[string]bool{}
+		for _, line := range strings.Split(commonPasswordsRaw, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				commonPasswords[line] = true
+			}
+		}
+	})
+	return commonPasswords[strings.ToLower(pw)]
+}
+
+// Strength is a coarse rating derived from the entropy estimate
+type Strength int
+
+const (
+	VeryWeak Strength = iota
+	Weak
+	Fair
+	Strong
+	VeryStrong
+)
+
+func (s Strength) String() string {
+	switch s {
+	case VeryWeak:
+		return "Very weak"
+	case Weak:
+		return "Weak"
+	case Fair:
+		return "Fair"
+	case Strong:
+		return "Strong"
+	}
+	return "Very strong"
+}
+
+// PasswordReport is the result of CheckPassword
+type PasswordReport struct {
+	Entropy  float64 // estimated bits
+	Strength Strength
+	Warnings []string
+}
+
+// CheckPassword estimates how hard a password is to guess. The estimate is
+// character pool size times effective length, where repeats and simple
+// sequences ("aaaa", "1234", "abcd") do not count. Passwords from the common
+// list or equal to the username are always rated very weak.
+func CheckPassword(password, username string) PasswordReport {
+	var r PasswordReport
+	if password == "" {
+		return r
+	}
+
+	if username != "" && strings.EqualFold(password, username) {
+		r.Warnings = append(r.Warnings, "Password is the same as the username.")
+		return r
+	}
+	if isCommonPassword(password) {
+		r.Warnings = append(r.Warnings, "This is one of the most commonly used passwords.")
+		return r
+	}
+	if username != "" && len(username) >= 3 && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		r.Warnings = append(r.Warnings, "Password contains the username.")
+	}
+
+	r.Entropy = float64(effectiveLength(password)) * math.Log2(float64(poolSize(password)))
+	switch {
+	case r.Entropy < 28:
+		r.Strength = VeryWeak
+	case r.Entropy < 36:
+		r.Strength = Weak
+	case r.Entropy < 60:
+		r.Strength = Fair
+	case r.Entropy < 80:
+		r.Strength = Strong
+	default:
+		r.Strength = VeryStrong
+	}
+	if len([]rune(password)) < 8 {
+		r.Warnings = append(r.Warnings, "Use at least 8 characters.")
+	}
+	return r
+}
+
+// poolSize returns the size of the alphabet the password draws from
+func poolSize(pw string) int {
+	var lower, upper, digit, symbol, other bool
+	for _, c := range pw {
+		switch {
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= '0' && c <= '9':
+			digit = true
+		case c < unicode.MaxASCII && unicode.IsPrint(c):
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	size := 0
+	for _, class := range []struct {
+		present bool
+		n       int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.present {
+			size += class.n
+		}
+	}
+	return max(size, 2)
+}
+
+// effectiveLength counts characters that are neither a repeat nor the next
+// step of a +1/-1 sequence relative to the previous character
+func effectiveLength(pw string) int {
+	runes := []rune(pw)
+	n := 1
+	for i := 1; i < len(runes); i++ {
+		d := runes[i] - runes[i-1]
+		if d == 0 || d == 1 || d == -1 {
+			continue
+		}
+		n++
+	}
+	return n
+}
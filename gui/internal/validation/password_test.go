@@ -0,0 +1,48 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckPassword(t *testing.T) {
+	tests := []struct {
+		password, username string
+		want               Strength
+		warning            string // substring of one warning, "" for none expected
+	}{
+		{"", "alice", VeryWeak, ""},
+		{"password", "alice", VeryWeak, "commonly used"},
+		{"Passw0rd", "alice", VeryWeak, "commonly used"},
+		{"archlinux", "alice", VeryWeak, "commonly used"},
+		{"Alice", "alice", VeryWeak, "same as the username"},
+		{"aaaaaaaaaaaaaaaa", "alice", VeryWeak, ""},
+		{"abcdefghijklmnop", "alice", VeryWeak, ""},
+		{"x7#", "alice", VeryWeak, "at least 8"},
+		{"alice1987!", "alice", Fair, "contains the username"},
+		{"Tr0ub4dor&3", "alice", Strong, ""},
+		{"correct horse battery staple", "alice", VeryStrong, ""},
+	}
+	for _, tt := range tests {
+		r := CheckPassword(tt.password, tt.username)
+		if r.Strength != tt.want {
+			t.Errorf("%q: strength %v (%.1f bits), want %v", tt.password, r.Strength, r.Entropy, tt.want)
+		}
+		joined := strings.Join(r.Warnings, " ")
+		if tt.warning != "" && !strings.Contains(joined, tt.warning) {
+			t.Errorf("%q: expected warning containing %q, got %v", tt.password, tt.warning, r.Warnings)
+		}
+		if tt.warning == "" && len(r.Warnings) > 0 {
+			t.Errorf("%q: unexpected warnings %v", tt.password, r.Warnings)
+		}
+	}
+}
+
+func TestCommonPasswordsEmbedded(t *testing.T) {
+	if !isCommonPassword("123456") || !isCommonPassword("QWERTY") {
+		t.Errorf("embedded common password list not loaded")
+	}
+	if isCommonPassword("") || isCommonPassword("correct horse battery staple") {
+		t.Errorf("unexpected common password match")
+	}
+}
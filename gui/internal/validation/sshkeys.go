@@ -0,0 +1,64 @@
+package validation
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Public key types sshd accepts in authorized_keys (ssh-dss is disabled since OpenSSH 9.8)
+var sshKeyTypes = map[string]bool{
+	"ssh-ed25519":                        true,
+	"ssh-rsa":                            true,
+	"ecdsa-sha2-nistp256":                true,
+	"ecdsa-sha2-nistp384":                true,
+	"ecdsa-sha2-nistp521":                true,
+	"sk-ssh-ed25519@openssh.com":         true,
+	"sk-ecdsa-sha2-nistp256@openssh.com": true,
+}
+
+// ParseAuthorizedKeys checks pasted or loaded public keys and returns them one
+// per entry with surrounding whitespace removed. Blank lines and # comments are
+// dropped. Options in front of the key type (from="...", etc.) are not supported.
+func ParseAuthorizedKeys(text string) ([]string, error) {
+	var keys []string
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := SSHPublicKey(line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		keys = append(keys, line)
+	}
+	return keys, nil
+}
+
+// SSHPublicKey checks one "type base64 [comment]" line. The key blob starts
+// with its own type name, which has to match the first field.
+func SSHPublicKey(line string) error {
+	if strings.Contains(line, "PRIVATE KEY") {
+		return fmt.Errorf("this is a private key, use the .pub file instead")
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return fmt.Errorf("expected \"<type> <key> [comment]\"")
+	}
+	if !sshKeyTypes[fields[0]] {
+		return fmt.Errorf("unsupported key type %q", fields[0])
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return fmt.Errorf("key data is not valid base64")
+	}
+	if len(blob) < 4 {
+		return fmt.Errorf("key data is truncated")
+	}
+	n := binary.BigEndian.Uint32(blob)
+	if uint64(n) > uint64(len(blob)-4) || string(blob[4:4+n]) != fields[0] {
+		return fmt.Errorf("key data does not match type %s", fields[0])
+	}
+	return nil
+}